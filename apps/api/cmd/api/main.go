@@ -7,13 +7,19 @@ import (
 	"os"
 	"os/signal"
 	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/ekkolyth/ekko-playlist/api/internal/api/httpserver"
+	"github.com/ekkolyth/ekko-playlist/api/internal/availability"
 	"github.com/ekkolyth/ekko-playlist/api/internal/db"
+	"github.com/ekkolyth/ekko-playlist/api/internal/ingest"
 	"github.com/ekkolyth/ekko-playlist/api/internal/lua"
 	"github.com/ekkolyth/ekko-playlist/api/internal/logging"
+	"github.com/ekkolyth/ekko-playlist/api/internal/metadata"
+	"github.com/ekkolyth/ekko-playlist/api/internal/scripts"
+	"github.com/ekkolyth/ekko-playlist/api/internal/thumbnails"
 	"github.com/joho/godotenv"
 )
 
@@ -44,6 +50,21 @@ func main() {
 		log.Fatal("Invalid API_PORT value:", port)
 	}
 
+	ingestWorkers, err := strconv.Atoi(getenvDefault("INGEST_WORKERS", "2"))
+	if err != nil || ingestWorkers < 1 {
+		log.Fatal("Invalid INGEST_WORKERS value:", os.Getenv("INGEST_WORKERS"))
+	}
+
+	luaReloadInterval, err := time.ParseDuration(getenvDefault("LUA_SCRIPTS_POLL_INTERVAL", "2s"))
+	if err != nil || luaReloadInterval <= 0 {
+		log.Fatal("Invalid LUA_SCRIPTS_POLL_INTERVAL value:", os.Getenv("LUA_SCRIPTS_POLL_INTERVAL"))
+	}
+
+	availabilityInterval, err := time.ParseDuration(getenvDefault("AVAILABILITY_CHECK_INTERVAL", "168h"))
+	if err != nil || availabilityInterval < time.Hour || availabilityInterval > 365*24*time.Hour {
+		log.Fatal("Invalid AVAILABILITY_CHECK_INTERVAL value:", os.Getenv("AVAILABILITY_CHECK_INTERVAL"))
+	}
+
 	ctx := context.Background()
 
 	// DB init
@@ -62,7 +83,58 @@ func main() {
 	defer luaService.Close()
 	logging.Info("Lua service initialized")
 
-	router := httpserver.NewRouter(dbService, luaService)
+	// PeerTube is federated, so videos are only accepted from the instances listed here
+	if instances := os.Getenv("PEERTUBE_INSTANCES"); instances != "" {
+		luaService.SetInstances("peertube", strings.Split(instances, ","))
+	}
+
+	// Optional script overrides, reloaded when they change
+	if scriptsDir := os.Getenv("LUA_SCRIPTS_DIR"); scriptsDir != "" {
+		if err := luaService.WatchDir(scriptsDir, luaReloadInterval); err != nil {
+			log.Fatal("Failed to watch LUA_SCRIPTS_DIR:", err)
+		}
+		logging.Info("Watching %s for Lua script changes", scriptsDir)
+	}
+
+	// Scripts managed through the admin API
+	scriptsService := scripts.NewService(dbService, luaService)
+	if err := scriptsService.Load(ctx); err != nil {
+		logging.Info("Failed to load database scripts, using built-in scripts: %s", err.Error())
+	}
+	scriptsService.Watch(luaReloadInterval)
+	defer scriptsService.Stop()
+
+	// Ingestion workers
+	ingestService := ingest.NewService(luaService, dbService)
+	jobs := ingest.NewWorkerPool(ingestService, ingestWorkers)
+	jobs.Start(ctx)
+
+	// YouTube playlist URLs are expanded through the Data API, or the playlist feed without a key
+	playlistFetcher := ingest.NewYouTubePlaylistFetcher(os.Getenv("YOUTUBE_API_KEY"))
+
+	// Metadata enrichment, through the Data API for YouTube when a key is set and oEmbed otherwise
+	var providers metadata.Chain
+	if apiKey := os.Getenv("YOUTUBE_API_KEY"); apiKey != "" {
+		providers = append(providers, metadata.NewYouTubeProvider(apiKey))
+	}
+	providers = append(providers, metadata.NewOEmbedProvider())
+	enricher := metadata.NewEnricher(dbService, providers)
+	enricher.Start(ctx)
+
+	// Dead-link checks, through the Data API for YouTube when a key is set and oEmbed otherwise
+	var checkers availability.Chain
+	if apiKey := os.Getenv("YOUTUBE_API_KEY"); apiKey != "" {
+		checkers = append(checkers, availability.NewYouTubeChecker(apiKey))
+	}
+	checkers = append(checkers, availability.NewHTTPChecker())
+	monitor := availability.NewMonitor(dbService, checkers, availabilityInterval)
+	monitor.Start(ctx)
+
+	// Thumbnails are cached in the upload directory and served from /api/uploads/thumbnails
+	thumbnailCacher := thumbnails.NewCacher(dbService, thumbnails.NewStore(thumbnails.Dir()))
+	thumbnailCacher.Start(ctx)
+
+	router := httpserver.NewRouter(dbService, luaService, ingestService, jobs, scriptsService, playlistFetcher)
 	server := &http.Server{
 		Addr:         ":" + port,
 		Handler:      router,
@@ -89,6 +161,18 @@ func main() {
 	if err := server.Shutdown(ctx); err != nil {
 		logging.Fatal("Server forced to shutdown:", err)
 	}
+	if err := jobs.Stop(ctx); err != nil {
+		log.Println("Ingestion workers did not stop cleanly:", err)
+	}
+	if err := enricher.Stop(ctx); err != nil {
+		log.Println("Metadata enricher did not stop cleanly:", err)
+	}
+	if err := monitor.Stop(ctx); err != nil {
+		log.Println("Availability monitor did not stop cleanly:", err)
+	}
+	if err := thumbnailCacher.Stop(ctx); err != nil {
+		log.Println("Thumbnail cacher did not stop cleanly:", err)
+	}
 	log.Println("Server exited")
 }
 
@@ -8,6 +8,7 @@ require (
 	github.com/jackc/pgx/v5 v5.7.5
 	github.com/joho/godotenv v1.5.1
 	github.com/yuin/gopher-lua v1.1.1
+	golang.org/x/image v0.25.0
 )
 
 require (
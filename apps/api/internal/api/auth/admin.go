@@ -0,0 +1,25 @@
+package auth
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/ekkolyth/ekko-playlist/api/internal/logging"
+)
+
+// AdminMiddleware creates a middleware that only lets the users in adminUserIDs through
+// It must run after AuthMiddleware. Everyone else gets 403, and with no admins configured
+// nobody does, so admin routes are closed by default.
+func AdminMiddleware(adminUserIDs []string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID, ok := GetUserID(r.Context())
+			if !ok || userID == "" || !slices.Contains(adminUserIDs, userID) {
+				logging.Info("Auth: User %s is not an admin, denying %s %s", userID, r.Method, r.URL.Path)
+				http.Error(w, "Admin access required", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAdminMiddleware(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	tests := []struct {
+		name   string
+		admins []string
+		ctx    context.Context
+		want   int
+	}{
+		{"admin", []string{"other", "admin"}, WithUser(context.Background(), "admin", "admin@example.com"), http.StatusNoContent},
+		{"not an admin", []string{"admin"}, WithUser(context.Background(), "user", "user@example.com"), http.StatusForbidden},
+		{"no admins configured", nil, WithUser(context.Background(), "admin", "admin@example.com"), http.StatusForbidden},
+		{"empty entry", []string{""}, WithUser(context.Background(), "", ""), http.StatusForbidden},
+		{"unauthenticated", []string{"admin"}, context.Background(), http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/admin/scripts", nil).WithContext(tt.ctx)
+			w := httptest.NewRecorder()
+			AdminMiddleware(tt.admins)(ok).ServeHTTP(w, r)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
@@ -107,6 +107,12 @@ func AuthMiddleware(dbService *db.Service) func(http.Handler) http.Handler {
 	}
 }
 
+// WithUser returns a copy of ctx carrying an authenticated user, as AuthMiddleware stores it
+func WithUser(ctx context.Context, userID, email string) context.Context {
+	ctx = context.WithValue(ctx, userIDKey, userID)
+	return context.WithValue(ctx, userEmailKey, email)
+}
+
 // GetUserID extracts the user ID from the request context
 func GetUserID(ctx context.Context) (string, bool) {
 	userID, ok := ctx.Value(userIDKey).(string)
@@ -0,0 +1,355 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/ekkolyth/ekko-playlist/api/internal/api/auth"
+	"github.com/ekkolyth/ekko-playlist/api/internal/api/httpx"
+	"github.com/ekkolyth/ekko-playlist/api/internal/api/pagination"
+	"github.com/ekkolyth/ekko-playlist/api/internal/db"
+	"github.com/ekkolyth/ekko-playlist/api/internal/logging"
+)
+
+var (
+	errChannelNotFound         = errors.New("channel not found")
+	errChannelPlatformMismatch = errors.New("channels are on different platforms")
+)
+
+type ChannelsHandler struct {
+	dbService *db.Service
+}
+
+func NewChannelsHandler(dbService *db.Service) *ChannelsHandler {
+	return &ChannelsHandler{
+		dbService: dbService,
+	}
+}
+
+type ChannelResponse struct {
+	ID         int64   `json:"id"`
+	Platform   string  `json:"platform"`
+	ExternalID *string `json:"externalId"`
+	Handle     *string `json:"handle"`
+	Name       string  `json:"name"`
+	AvatarURL  *string `json:"avatarUrl"`
+	VideoCount int64   `json:"videoCount"`
+	CreatedAt  string  `json:"createdAt"`
+}
+
+type ListChannelsResponse struct {
+	Channels []ChannelResponse `json:"channels"`
+}
+
+type ChannelVideosResponse struct {
+	Channel ChannelResponse `json:"channel"`
+	Videos  []VideoResponse `json:"videos"`
+	// Cursor of the next page, or null on the last page
+	NextCursor *string `json:"nextCursor"`
+}
+
+type MergeChannelsRequest struct {
+	ChannelIDs []int64 `json:"channelIds"`
+}
+
+// List handles GET /api/channels
+// Returns the authenticated user's channels that have saved videos, with their video counts
+func (h *ChannelsHandler) List(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	userID, ok := auth.GetUserID(ctx)
+	if !ok {
+		httpx.RespondError(w, http.StatusUnauthorized, "User ID not found in context")
+		return
+	}
+
+	channels, err := h.dbService.Queries.ListChannelsWithCounts(ctx, userID)
+	if err != nil {
+		logging.Info("Error listing channels: %s", err.Error())
+		httpx.RespondError(w, http.StatusInternalServerError, "Failed to fetch channels")
+		return
+	}
+
+	response := ListChannelsResponse{
+		Channels: make([]ChannelResponse, 0, len(channels)),
+	}
+	for _, channel := range channels {
+		response.Channels = append(response.Channels, newChannelResponse(&db.Channel{
+			ID:         channel.ID,
+			Platform:   channel.Platform,
+			ExternalID: channel.ExternalID,
+			Handle:     channel.Handle,
+			Name:       channel.Name,
+			AvatarUrl:  channel.AvatarUrl,
+			CreatedAt:  channel.CreatedAt,
+		}, channel.VideoCount))
+	}
+
+	httpx.RespondJSON(w, http.StatusOK, response)
+}
+
+// Videos handles GET /api/channels/{id}/videos
+// Returns a channel and its videos, newest first
+// Supports "limit" (1-200) and "cursor" (nextCursor of the previous page) query parameters for pagination;
+// without either every video is returned, like GET /api/videos
+func (h *ChannelsHandler) Videos(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	userID, ok := auth.GetUserID(ctx)
+	if !ok {
+		httpx.RespondError(w, http.StatusUnauthorized, "User ID not found in context")
+		return
+	}
+
+	limit, err := pageLimit(r)
+	if err != nil {
+		httpx.RespondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	sorting := pagination.Sort{Field: sortCreatedAt, Desc: true}
+	after, err := pagination.DecodeCursor(r.URL.Query().Get("cursor"), sorting)
+	if err != nil {
+		httpx.RespondError(w, http.StatusBadRequest, "Invalid cursor")
+		return
+	}
+
+	channel, ok := h.channel(ctx, w, r, userID)
+	if !ok {
+		return
+	}
+
+	query := &db.VideoQuery{
+		UserID:    userID,
+		ChannelID: channel.ID,
+		Sort:      db.VideoSortCreatedAt,
+		SortDesc:  true,
+		After:     videoKey(after),
+	}
+	if limit > 0 {
+		// One more than the page, to know whether there is a next page
+		query.Limit = limit + 1
+	}
+	rows, err := h.dbService.Queries.QueryVideos(ctx, query)
+	if err != nil {
+		logging.Info("Error listing channel videos: %s", err.Error())
+		httpx.RespondError(w, http.StatusInternalServerError, "Failed to fetch channel videos")
+		return
+	}
+	videoCount, err := h.dbService.Queries.GetChannelVideoCount(ctx, &channel.ID)
+	if err != nil {
+		logging.Info("Error counting channel videos: %s", err.Error())
+		httpx.RespondError(w, http.StatusInternalServerError, "Failed to fetch channel videos")
+		return
+	}
+
+	var nextCursor string
+	if limit > 0 && len(rows) > limit {
+		rows = rows[:limit]
+		nextCursor = pagination.EncodeCursor(sorting, videoSortKey(sorting.Field)(rows[limit-1]))
+	}
+	videos := make([]*db.Video, 0, len(rows))
+	for _, row := range rows {
+		videos = append(videos, row.Video)
+	}
+
+	httpx.RespondJSON(w, http.StatusOK, ChannelVideosResponse{
+		Channel:    newChannelResponse(channel, videoCount),
+		Videos:     videoResponses(ctx, h.dbService.Queries, videos),
+		NextCursor: optionalCursor(nextCursor),
+	})
+}
+
+// Merge handles POST /api/channels/{id}/merge
+// Moves the videos of the channels in channelIds to this channel and deletes them. The videos
+// keep the channel name they were saved with, and this channel takes their handle, external ID
+// and avatar if it has none. Their names and handles are kept as aliases of this channel, so videos saved under
+// them later join it.
+func (h *ChannelsHandler) Merge(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	userID, ok := auth.GetUserID(ctx)
+	if !ok {
+		httpx.RespondError(w, http.StatusUnauthorized, "User ID not found in context")
+		return
+	}
+
+	target, ok := h.channel(ctx, w, r, userID)
+	if !ok {
+		return
+	}
+
+	var req MergeChannelsRequest
+	if err := httpx.DecodeJSON(w, r, &req, 1<<20); err != nil {
+		httpx.RespondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	sourceIDs := mergeSourceIDs(target.ID, req.ChannelIDs)
+	if len(sourceIDs) == 0 {
+		httpx.RespondError(w, http.StatusBadRequest, "channelIds must contain at least one other channel")
+		return
+	}
+
+	var merged *db.Channel
+	var videoCount int64
+	err := h.dbService.DB.WithTx(ctx, func(q *db.Queries) error {
+		var err error
+		merged, videoCount, err = mergeChannels(ctx, q, userID, target, sourceIDs)
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, errChannelNotFound) {
+			httpx.RespondError(w, http.StatusNotFound, "Channel not found")
+			return
+		}
+		if errors.Is(err, errChannelPlatformMismatch) {
+			httpx.RespondError(w, http.StatusBadRequest, "Channels on different platforms can't be merged")
+			return
+		}
+		logging.Info("Error merging channels: %s", err.Error())
+		httpx.RespondError(w, http.StatusInternalServerError, "Failed to merge channels")
+		return
+	}
+
+	httpx.RespondJSON(w, http.StatusOK, newChannelResponse(merged, videoCount))
+}
+
+// channelMergeStore runs the queries of a merge; *db.Queries implements it
+type channelMergeStore interface {
+	ListChannelsByIDs(ctx context.Context, arg *db.ListChannelsByIDsParams) ([]*db.Channel, error)
+	MoveChannelVideos(ctx context.Context, arg *db.MoveChannelVideosParams) error
+	CreateChannelAliases(ctx context.Context, arg *db.CreateChannelAliasesParams) error
+	DeleteChannels(ctx context.Context, arg *db.DeleteChannelsParams) error
+	FillChannelDetails(ctx context.Context, arg *db.FillChannelDetailsParams) (*db.Channel, error)
+	GetChannelVideoCount(ctx context.Context, channelID *int64) (int64, error)
+}
+
+// mergeSourceIDs returns the channel IDs to merge into target, without target or duplicates
+func mergeSourceIDs(target int64, channelIDs []int64) []int64 {
+	sourceIDs := make([]int64, 0, len(channelIDs))
+	seen := make(map[int64]bool, len(channelIDs))
+	for _, id := range channelIDs {
+		if id != target && !seen[id] {
+			seen[id] = true
+			sourceIDs = append(sourceIDs, id)
+		}
+	}
+	return sourceIDs
+}
+
+// mergeChannels merges the user's sourceIDs channels into target, returning the merged channel
+// and its video count. It must run in a transaction.
+func mergeChannels(ctx context.Context, q channelMergeStore, userID string, target *db.Channel, sourceIDs []int64) (*db.Channel, int64, error) {
+	sources, err := q.ListChannelsByIDs(ctx, &db.ListChannelsByIDsParams{
+		UserID: userID,
+		Ids:    sourceIDs,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(sources) != len(sourceIDs) {
+		return nil, 0, errChannelNotFound
+	}
+	for _, source := range sources {
+		if source.Platform != target.Platform {
+			return nil, 0, errChannelPlatformMismatch
+		}
+	}
+
+	if err := q.MoveChannelVideos(ctx, &db.MoveChannelVideosParams{
+		UserID:    userID,
+		SourceIds: sourceIDs,
+		ChannelID: &target.ID,
+	}); err != nil {
+		return nil, 0, err
+	}
+	// The sources' names and handles, and the aliases they had, become aliases of the target
+	if err := q.CreateChannelAliases(ctx, &db.CreateChannelAliasesParams{
+		ChannelID: target.ID,
+		UserID:    userID,
+		SourceIds: sourceIDs,
+	}); err != nil {
+		return nil, 0, err
+	}
+	// Delete the sources first, their handle and external ID are unique
+	if err := q.DeleteChannels(ctx, &db.DeleteChannelsParams{
+		UserID: userID,
+		Ids:    sourceIDs,
+	}); err != nil {
+		return nil, 0, err
+	}
+
+	fill := &db.FillChannelDetailsParams{ID: target.ID}
+	for _, source := range sources {
+		if fill.ExternalID == nil {
+			fill.ExternalID = source.ExternalID
+		}
+		if fill.Handle == nil {
+			fill.Handle = source.Handle
+		}
+		if fill.AvatarUrl == nil {
+			fill.AvatarUrl = source.AvatarUrl
+		}
+	}
+	merged, err := q.FillChannelDetails(ctx, fill)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	videoCount, err := q.GetChannelVideoCount(ctx, &target.ID)
+	if err != nil {
+		return nil, 0, err
+	}
+	return merged, videoCount, nil
+}
+
+// channel looks up the {id} channel of the user, responding with an error if there is none
+func (h *ChannelsHandler) channel(ctx context.Context, w http.ResponseWriter, r *http.Request, userID string) (*db.Channel, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		httpx.RespondError(w, http.StatusBadRequest, "Invalid channel ID")
+		return nil, false
+	}
+
+	channel, err := h.dbService.Queries.GetChannelForUser(ctx, &db.GetChannelForUserParams{
+		ID:     id,
+		UserID: userID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			httpx.RespondError(w, http.StatusNotFound, "Channel not found")
+			return nil, false
+		}
+		logging.Info("Error getting channel: %s", err.Error())
+		httpx.RespondError(w, http.StatusInternalServerError, "Failed to fetch channel")
+		return nil, false
+	}
+	return channel, true
+}
+
+func newChannelResponse(channel *db.Channel, videoCount int64) ChannelResponse {
+	createdAt := ""
+	if channel.CreatedAt.Valid {
+		createdAt = channel.CreatedAt.Time.Format(time.RFC3339)
+	}
+
+	return ChannelResponse{
+		ID:         channel.ID,
+		Platform:   channel.Platform,
+		ExternalID: channel.ExternalID,
+		Handle:     channel.Handle,
+		Name:       channel.Name,
+		AvatarURL:  channel.AvatarUrl,
+		VideoCount: videoCount,
+		CreatedAt:  createdAt,
+	}
+}
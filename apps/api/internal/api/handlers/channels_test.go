@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/ekkolyth/ekko-playlist/api/internal/db"
+)
+
+// memoryChannels is a channelMergeStore over one user's channels, recording the queries run
+type memoryChannels struct {
+	channels map[int64]*db.Channel
+	aliases  map[int64][]string
+	videos   map[int64]int64
+	calls    []string
+}
+
+func (s *memoryChannels) ListChannelsByIDs(ctx context.Context, arg *db.ListChannelsByIDsParams) ([]*db.Channel, error) {
+	s.calls = append(s.calls, "ListChannelsByIDs")
+	var channels []*db.Channel
+	for _, id := range arg.Ids {
+		if channel, ok := s.channels[id]; ok {
+			channels = append(channels, channel)
+		}
+	}
+	return channels, nil
+}
+
+func (s *memoryChannels) MoveChannelVideos(ctx context.Context, arg *db.MoveChannelVideosParams) error {
+	s.calls = append(s.calls, "MoveChannelVideos")
+	for _, id := range arg.SourceIds {
+		s.videos[*arg.ChannelID] += s.videos[id]
+		delete(s.videos, id)
+	}
+	return nil
+}
+
+func (s *memoryChannels) CreateChannelAliases(ctx context.Context, arg *db.CreateChannelAliasesParams) error {
+	s.calls = append(s.calls, "CreateChannelAliases")
+	for _, id := range arg.SourceIds {
+		s.aliases[arg.ChannelID] = append(s.aliases[arg.ChannelID], s.channels[id].Name)
+		s.aliases[arg.ChannelID] = append(s.aliases[arg.ChannelID], s.aliases[id]...)
+	}
+	return nil
+}
+
+func (s *memoryChannels) DeleteChannels(ctx context.Context, arg *db.DeleteChannelsParams) error {
+	s.calls = append(s.calls, "DeleteChannels")
+	for _, id := range arg.Ids {
+		delete(s.channels, id)
+		delete(s.aliases, id)
+	}
+	return nil
+}
+
+func (s *memoryChannels) FillChannelDetails(ctx context.Context, arg *db.FillChannelDetailsParams) (*db.Channel, error) {
+	s.calls = append(s.calls, "FillChannelDetails")
+	channel := *s.channels[arg.ID]
+	if channel.ExternalID == nil {
+		channel.ExternalID = arg.ExternalID
+	}
+	if channel.Handle == nil {
+		channel.Handle = arg.Handle
+	}
+	if channel.AvatarUrl == nil {
+		channel.AvatarUrl = arg.AvatarUrl
+	}
+	s.channels[arg.ID] = &channel
+	return &channel, nil
+}
+
+func (s *memoryChannels) GetChannelVideoCount(ctx context.Context, channelID *int64) (int64, error) {
+	s.calls = append(s.calls, "GetChannelVideoCount")
+	return s.videos[*channelID], nil
+}
+
+func ptr(s string) *string {
+	return &s
+}
+
+func newMemoryChannels() *memoryChannels {
+	return &memoryChannels{
+		channels: map[int64]*db.Channel{
+			1: {ID: 1, Platform: "youtube", Name: "Rick Astley"},
+			2: {ID: 2, Platform: "youtube", Name: "RickAstleyVEVO", Handle: ptr("@RickAstleyVEVO"), AvatarUrl: ptr("https://example.com/vevo.jpg")},
+			3: {ID: 3, Platform: "youtube", Name: "Rick Astley - Topic", ExternalID: ptr("UC1"), Handle: ptr("@rick-topic")},
+			4: {ID: 4, Platform: "vimeo", Name: "Rick Astley"},
+		},
+		aliases: map[int64][]string{3: {"Rick Astley Official"}},
+		videos:  map[int64]int64{1: 2, 2: 3, 3: 1, 4: 1},
+	}
+}
+
+func TestMergeChannels(t *testing.T) {
+	store := newMemoryChannels()
+	target := store.channels[1]
+
+	merged, videoCount, err := mergeChannels(context.Background(), store, "user", target, []int64{2, 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if videoCount != 6 {
+		t.Errorf("video count = %d, want 6", videoCount)
+	}
+
+	// The target keeps its name and takes the first handle, external ID and avatar of the sources
+	want := &db.Channel{ID: 1, Platform: "youtube", Name: "Rick Astley", ExternalID: ptr("UC1"),
+		Handle: ptr("@RickAstleyVEVO"), AvatarUrl: ptr("https://example.com/vevo.jpg")}
+	if !reflect.DeepEqual(merged, want) {
+		t.Errorf("merged = %+v, want %+v", merged, want)
+	}
+
+	// The sources' names and their own aliases are kept, and recorded before the sources go
+	wantAliases := []string{"RickAstleyVEVO", "Rick Astley - Topic", "Rick Astley Official"}
+	if !reflect.DeepEqual(store.aliases[1], wantAliases) {
+		t.Errorf("aliases = %v, want %v", store.aliases[1], wantAliases)
+	}
+	if slices.Index(store.calls, "CreateChannelAliases") > slices.Index(store.calls, "DeleteChannels") {
+		t.Errorf("aliases were created after the sources were deleted: %v", store.calls)
+	}
+	if _, ok := store.channels[2]; ok {
+		t.Errorf("source channel 2 was not deleted")
+	}
+}
+
+func TestMergeChannelsErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		sources []int64
+		want    error
+	}{
+		{"missing channel", []int64{2, 99}, errChannelNotFound},
+		{"other platform", []int64{2, 4}, errChannelPlatformMismatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newMemoryChannels()
+			_, _, err := mergeChannels(context.Background(), store, "user", store.channels[1], tt.sources)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("error = %v, want %v", err, tt.want)
+			}
+			// Nothing is changed
+			if !reflect.DeepEqual(store.calls, []string{"ListChannelsByIDs"}) {
+				t.Errorf("queries run = %v, want only the lookup", store.calls)
+			}
+		})
+	}
+}
+
+func TestMergeSourceIDs(t *testing.T) {
+	if got := mergeSourceIDs(1, []int64{3, 1, 2, 3}); !reflect.DeepEqual(got, []int64{3, 2}) {
+		t.Errorf("mergeSourceIDs = %v, want [3 2]", got)
+	}
+	if got := mergeSourceIDs(1, []int64{1}); len(got) != 0 {
+		t.Errorf("mergeSourceIDs(only the target) = %v, want none", got)
+	}
+}
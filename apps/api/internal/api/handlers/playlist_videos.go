@@ -56,18 +56,16 @@ func (h *PlaylistVideosHandler) AddVideo(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Verify video exists and belongs to user
-	video, err := h.dbService.Queries.GetVideoByID(ctx, req.VideoID)
+	_, err := h.dbService.Queries.GetVideoForUser(ctx, &db.GetVideoForUserParams{
+		ID:     req.VideoID,
+		UserID: userID,
+	})
 	if err != nil {
 		logging.Info("Error getting video: %s", err.Error())
 		httpx.RespondError(w, http.StatusNotFound, "Video not found")
 		return
 	}
 
-	if video.UserID != userID {
-		httpx.RespondError(w, http.StatusForbidden, "You don't have permission to add this video")
-		return
-	}
-
 	// Get current max position to append at the end
 	videoCount, _ := h.dbService.Queries.GetPlaylistVideoCount(ctx, &db.GetPlaylistVideoCountParams{
 		UserID: userID,
@@ -168,19 +166,16 @@ func (h *PlaylistVideosHandler) BulkAddVideos(w http.ResponseWriter, r *http.Req
 	// Add each video
 	for _, videoID := range req.VideoIDs {
 		// Verify video exists and belongs to user
-		video, err := h.dbService.Queries.GetVideoByID(ctx, videoID)
+		_, err := h.dbService.Queries.GetVideoForUser(ctx, &db.GetVideoForUserParams{
+			ID:     videoID,
+			UserID: userID,
+		})
 		if err != nil {
 			logging.Info("Error getting video %d: %s", videoID, err.Error())
 			failedCount++
 			continue
 		}
 
-		if video.UserID != userID {
-			logging.Info("Video %d does not belong to user", videoID)
-			failedCount++
-			continue
-		}
-
 		err = h.dbService.Queries.AddVideoToPlaylistByName(ctx, &db.AddVideoToPlaylistByNameParams{
 			UserID:   userID,
 			VideoID:  videoID,
@@ -9,6 +9,7 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/ekkolyth/ekko-playlist/api/internal/api/auth"
 	"github.com/ekkolyth/ekko-playlist/api/internal/api/httpx"
+	"github.com/ekkolyth/ekko-playlist/api/internal/api/pagination"
 	"github.com/ekkolyth/ekko-playlist/api/internal/db"
 	"github.com/ekkolyth/ekko-playlist/api/internal/logging"
 )
@@ -45,6 +46,11 @@ type PlaylistDetailResponse struct {
 	Videos    []VideoResponse `json:"videos"`
 	CreatedAt string          `json:"createdAt"`
 	UpdatedAt string          `json:"updatedAt"`
+
+	// Cursor of the next page, or null on the last page
+	NextCursor *string `json:"nextCursor"`
+	// Number of videos in the playlist matching the search, across all pages; only on the first page
+	Total *int `json:"total,omitempty"`
 }
 
 type ListPlaylistsResponse struct {
@@ -159,6 +165,13 @@ func (h *PlaylistsHandler) List(w http.ResponseWriter, r *http.Request) {
 
 // Get handles GET /api/playlists/:id
 // Returns a playlist with its videos
+// Supports "limit" (1-200) and "cursor" (nextCursor of the previous page) query parameters for pagination;
+// without either every video is returned, as before lists were paged
+// Supports optional "search" query parameter, matched like GET /api/videos; results have a match with their
+// rank and highlights
+// Supports optional "sort" query parameter: position, created_at, title, channel or, when searching, relevance;
+// prefixed with - for descending (default position)
+// The search, the sort and the page are applied in a single db.VideoQuery
 func (h *PlaylistsHandler) Get(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 	defer cancel()
@@ -185,73 +198,83 @@ func (h *PlaylistsHandler) Get(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Parse search query parameter
+	// Parse search query parameter (web search syntax, matched against title, channel and tags)
 	searchTerm := strings.TrimSpace(r.URL.Query().Get("search"))
-	searchPattern := ""
+
+	// Parse pagination query parameters; playlists are in their own order by default
+	limit, err := pageLimit(r)
+	if err != nil {
+		httpx.RespondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	sortFields := []string{sortPosition, sortCreatedAt, sortTitle, sortChannel}
 	if searchTerm != "" {
-		// Format search term for ILIKE pattern matching
-		searchPattern = "%" + searchTerm + "%"
+		sortFields = append(sortFields, sortRelevance)
+	}
+	sorting, err := pagination.ParseSort(r.URL.Query().Get("sort"), sortFields, pagination.Sort{Field: sortPosition})
+	if err != nil {
+		httpx.RespondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	after, err := pagination.DecodeCursor(r.URL.Query().Get("cursor"), sorting)
+	if err != nil {
+		httpx.RespondError(w, http.StatusBadRequest, "Invalid cursor")
+		return
 	}
 
-	// Get videos
-	var videos []VideoResponse
-	if searchPattern != "" {
-		videoRows, err := h.dbService.Queries.GetPlaylistVideosWithSearch(ctx, &db.GetPlaylistVideosWithSearchParams{
-			UserID: userID,
-			Name:   playlistName,
-			Title:  searchPattern,
-		})
-		if err != nil {
-			logging.Info("Error getting playlist videos: %s", err.Error())
-			httpx.RespondError(w, http.StatusInternalServerError, "Failed to fetch playlist videos")
-			return
-		}
-		videos = make([]VideoResponse, 0, len(videoRows))
-		for _, videoRow := range videoRows {
-			createdAt := ""
-			if videoRow.CreatedAt.Valid {
-				createdAt = videoRow.CreatedAt.Time.Format(time.RFC3339)
-			}
+	query := &db.VideoQuery{
+		UserID:          userID,
+		PlaylistID:      playlist.ID,
+		Search:          searchTerm,
+		HeadlineOptions: headlineOptions,
+		Sort:            db.VideoSort(sorting.Field),
+		SortDesc:        sorting.Desc,
+		After:           videoKey(after),
+	}
+	if limit > 0 {
+		// One more than the page, to know whether there is a next page
+		query.Limit = limit + 1
+	}
+	rows, err := h.dbService.Queries.QueryVideos(ctx, query)
+	if err != nil {
+		logging.Info("Error getting playlist videos: %s", err.Error())
+		httpx.RespondError(w, http.StatusInternalServerError, "Failed to fetch playlist videos")
+		return
+	}
 
-			videos = append(videos, VideoResponse{
-				ID:            videoRow.ID,
-				VideoID:       videoRow.VideoID,
-				NormalizedURL: videoRow.NormalizedUrl,
-				OriginalURL:   videoRow.OriginalUrl,
-				Title:         videoRow.Title,
-				Channel:       videoRow.Channel,
-				UserID:        videoRow.UserID,
-				CreatedAt:     createdAt,
-			})
-		}
-	} else {
-		videoRows, err := h.dbService.Queries.GetPlaylistVideos(ctx, &db.GetPlaylistVideosParams{
-			UserID: userID,
-			Name:   playlistName,
-		})
-		if err != nil {
-			logging.Info("Error getting playlist videos: %s", err.Error())
-			httpx.RespondError(w, http.StatusInternalServerError, "Failed to fetch playlist videos")
-			return
-		}
-		videos = make([]VideoResponse, 0, len(videoRows))
-		for _, videoRow := range videoRows {
-			createdAt := ""
-			if videoRow.CreatedAt.Valid {
-				createdAt = videoRow.CreatedAt.Time.Format(time.RFC3339)
+	var nextCursor string
+	if limit > 0 && len(rows) > limit {
+		rows = rows[:limit]
+		nextCursor = pagination.EncodeCursor(sorting, videoSortKey(sorting.Field)(rows[limit-1]))
+	}
+
+	videos := make([]VideoResponse, 0, len(rows))
+	for _, row := range rows {
+		video := newPlaylistVideoResponse(row)
+		if searchTerm != "" {
+			video.Match = &SearchMatch{
+				Rank:    row.Rank,
+				Title:   highlightHTML(row.TitleHighlight),
+				Channel: highlightHTML(row.ChannelHighlight),
 			}
+		}
+		videos = append(videos, video)
+	}
 
-			videos = append(videos, VideoResponse{
-				ID:            videoRow.ID,
-				VideoID:       videoRow.VideoID,
-				NormalizedURL: videoRow.NormalizedUrl,
-				OriginalURL:   videoRow.OriginalUrl,
-				Title:         videoRow.Title,
-				Channel:       videoRow.Channel,
-				UserID:        videoRow.UserID,
-				CreatedAt:     createdAt,
-			})
+	// Like video lists, the total counts every page and is only given on the first
+	var total *int
+	if after == nil {
+		count := len(videos)
+		if nextCursor != "" {
+			n, err := h.dbService.Queries.CountVideos(ctx, query)
+			if err != nil {
+				logging.Info("Error counting playlist videos: %s", err.Error())
+				httpx.RespondError(w, http.StatusInternalServerError, "Failed to fetch playlist videos")
+				return
+			}
+			count = int(n)
 		}
+		total = &count
 	}
 
 	createdAt := ""
@@ -269,9 +292,20 @@ func (h *PlaylistsHandler) Get(w http.ResponseWriter, r *http.Request) {
 		Videos:    videos,
 		CreatedAt: createdAt,
 		UpdatedAt: updatedAt,
+
+		NextCursor: optionalCursor(nextCursor),
+		Total:      total,
 	})
 }
 
+// newPlaylistVideoResponse returns a playlist video with its position
+func newPlaylistVideoResponse(row *db.VideoQueryRow) VideoResponse {
+	response := newVideoResponse(row.Video, nil)
+	position := row.Position
+	response.Position = &position
+	return response
+}
+
 // Update handles PUT /api/playlists/:id
 // Updates a playlist's name
 func (h *PlaylistsHandler) Update(w http.ResponseWriter, r *http.Request) {
@@ -1,83 +1,149 @@
 package handlers
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
-	"fmt"
+	"mime"
 	"net/http"
-	"strings"
 	"time"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 
 	"github.com/ekkolyth/ekko-playlist/api/internal/api/auth"
 	"github.com/ekkolyth/ekko-playlist/api/internal/api/httpx"
 	"github.com/ekkolyth/ekko-playlist/api/internal/db"
+	"github.com/ekkolyth/ekko-playlist/api/internal/ingest"
 	"github.com/ekkolyth/ekko-playlist/api/internal/logging"
-	"github.com/ekkolyth/ekko-playlist/api/internal/lua"
+)
+
+const (
+	// ndjsonContentType is the media type of streamed ingestion requests and responses
+	ndjsonContentType = "application/x-ndjson"
+	// streamBatchSize caps how many streamed lines are normalized and saved together
+	streamBatchSize = 100
+	// streamBatchTimeout bounds the work on a single batch of streamed lines
+	streamBatchTimeout = 30 * time.Second
+	// streamIdleTimeout is how long a stream may go without reading a line or writing results
+	streamIdleTimeout = time.Minute
+	// maxStreamLineSize caps a single streamed line
+	maxStreamLineSize = 1 << 20
+	// playlistFetchTimeout bounds reading a playlist's members from YouTube
+	playlistFetchTimeout = 10 * time.Second
 )
 
 type ProcessHandler struct {
-	luaService *lua.Service
-	dbService  *db.Service
+	ingestService *ingest.Service
+	jobs          *ingest.WorkerPool
+	dbService     *db.Service
+	playlists     ingest.PlaylistFetcher
 }
 
-func NewProcessHandler(luaService *lua.Service, dbService *db.Service) *ProcessHandler {
+func NewProcessHandler(ingestService *ingest.Service, jobs *ingest.WorkerPool, dbService *db.Service, playlists ingest.PlaylistFetcher) *ProcessHandler {
 	return &ProcessHandler{
-		luaService: luaService,
-		dbService:  dbService,
+		ingestService: ingestService,
+		jobs:          jobs,
+		dbService:     dbService,
+		playlists:     playlists,
 	}
 }
 
-type VideoInfo struct {
-	Channel string `json:"channel"`
-	URL     string `json:"url"`
-	Title   string `json:"title"`
-}
+type VideoInfo = ingest.VideoInfo
+
+type ProcessedVideoInfo = ingest.ProcessedVideoInfo
 
 type ProcessPlaylistRequest struct {
 	Videos []VideoInfo `json:"videos"`
 }
 
-type ProcessedVideoInfo struct {
-	Channel       string `json:"channel"`
-	OriginalURL   string `json:"originalUrl"`
-	NormalizedURL string `json:"normalizedUrl"`
-	Title         string `json:"title"`
-	IsValid       bool   `json:"isValid"`
-	Error         string `json:"error,omitempty"`
+// ProcessTotals counts a job's results by status
+type ProcessTotals struct {
+	Created   int `json:"created"`
+	Duplicate int `json:"duplicate"`
+	Invalid   int `json:"invalid"`
+	Error     int `json:"error"`
 }
 
-type ProcessPlaylistResponse struct {
-	Processed []ProcessedVideoInfo `json:"processed"`
-	Total     int                  `json:"total"`
-	Valid     int                  `json:"valid"`
-	Invalid   int                  `json:"invalid"`
+type ProcessJobResponse struct {
+	ID         string               `json:"id"`
+	Status     string               `json:"status"`
+	Total      int                  `json:"total"`
+	Processed  int                  `json:"processed"`
+	Totals     ProcessTotals        `json:"totals"`
+	Results    []ProcessedVideoInfo `json:"results"`
+	Error      string               `json:"error,omitempty"`
+	Playlist   string               `json:"playlist,omitempty"`
+	Warning    string               `json:"warning,omitempty"`
+	CreatedAt  string               `json:"createdAt"`
+	UpdatedAt  string               `json:"updatedAt"`
+	StartedAt  string               `json:"startedAt,omitempty"`
+	FinishedAt string               `json:"finishedAt,omitempty"`
 }
 
-// extractVideoID extracts the video ID from a normalized YouTube URL
-// Expected format: https://www.youtube.com/watch?v=VIDEO_ID
-func extractVideoID(normalizedURL string) string {
-	// Extract video ID from normalized URL format: https://www.youtube.com/watch?v=VIDEO_ID
-	parts := strings.Split(normalizedURL, "v=")
-	if len(parts) == 2 {
-		// Remove any query parameters after the video ID
-		videoID := strings.Split(parts[1], "&")[0]
-		return videoID
-	}
-	return ""
+// newProcessJobResponse builds the API view of an ingestion job
+func newProcessJobResponse(job *db.Job) (ProcessJobResponse, error) {
+	results := []ProcessedVideoInfo{}
+	if err := json.Unmarshal(job.Results, &results); err != nil {
+		return ProcessJobResponse{}, err
+	}
+
+	response := ProcessJobResponse{
+		ID:        job.ID.String(),
+		Status:    job.Status,
+		Total:     int(job.Total),
+		Processed: int(job.Processed),
+		Results:   results,
+	}
+
+	for _, result := range results {
+		switch result.Status {
+		case ingest.VideoStatusCreated:
+			response.Totals.Created++
+		case ingest.VideoStatusDuplicate:
+			response.Totals.Duplicate++
+		case ingest.VideoStatusInvalid:
+			response.Totals.Invalid++
+		case ingest.VideoStatusError:
+			response.Totals.Error++
+		}
+	}
+
+	if job.Error != nil {
+		response.Error = *job.Error
+	}
+	if job.Warning != nil {
+		response.Warning = *job.Warning
+	}
+	if job.CreatedAt.Valid {
+		response.CreatedAt = job.CreatedAt.Time.Format(time.RFC3339)
+	}
+	if job.UpdatedAt.Valid {
+		response.UpdatedAt = job.UpdatedAt.Time.Format(time.RFC3339)
+	}
+	if job.StartedAt.Valid {
+		response.StartedAt = job.StartedAt.Time.Format(time.RFC3339)
+	}
+	if job.FinishedAt.Valid {
+		response.FinishedAt = job.FinishedAt.Time.Format(time.RFC3339)
+	}
+
+	return response, nil
 }
 
 // Playlist handles POST /api/process/playlist
-// Receives a playlist of videos and normalizes all YouTube URLs using Lua
+// Queues a playlist of videos for background normalization and returns 202 with the job
 func (h *ProcessHandler) Playlist(w http.ResponseWriter, r *http.Request) {
-	logging.Info("Received POST /api/process/playlist request")
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
 
-	// Check if user ID is available in request context (before creating new context)
-	if userID, ok := auth.GetUserID(r.Context()); ok {
-		logging.Info("Auth: User ID found in request context: %d", userID)
-	} else {
-		logging.Info("Auth: WARNING - No user ID found in request context!")
+	userID, ok := auth.GetUserID(ctx)
+	if !ok {
+		httpx.RespondError(w, http.StatusUnauthorized, "User ID not found in context")
+		return
 	}
 
 	var req ProcessPlaylistRequest
@@ -89,196 +155,88 @@ func (h *ProcessHandler) Playlist(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	logging.Info("Decoded request with %d videos", len(req.Videos))
-
 	// Validate videos array
 	if len(req.Videos) == 0 {
 		httpx.RespondError(w, http.StatusBadRequest, "videos array cannot be empty")
 		return
 	}
 
-	// Create context with timeout (allow more time for processing multiple URLs)
-	// Use request context to preserve auth middleware's user ID
-	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
-	defer cancel()
-
-	processed := make([]ProcessedVideoInfo, 0, len(req.Videos))
-	validURLs := make([]string, 0)
-	failedURLs := make([]string, 0)
-
-	// Process each video URL
-	for _, video := range req.Videos {
-		// Normalize URL using Lua script
-		result, err := h.luaService.NormalizeURL(ctx, video.URL)
-		if err != nil {
-			processed = append(processed, ProcessedVideoInfo{
-				Channel:       video.Channel,
-				OriginalURL:   video.URL,
-				NormalizedURL: "",
-				Title:         video.Title,
-				IsValid:       false,
-				Error:         "Failed to normalize URL: " + err.Error(),
-			})
-			failedURLs = append(failedURLs, video.URL)
-			continue
-		}
-
-		// Extract values from Lua result
-		isValid := false
-		normalizedURL := ""
-		errorMsg := ""
-
-		if val, ok := result["isValid"].(bool); ok {
-			isValid = val
-		}
-
-		if val, ok := result["normalizedUrl"].(string); ok && val != "" {
-			normalizedURL = val
-		} else if val, ok := result["normalizedUrl"]; ok && val != nil {
-			if v, ok := val.(string); ok {
-				normalizedURL = v
-			}
-		}
-
-		if val, ok := result["error"].(string); ok && val != "" {
-			errorMsg = val
-		} else if val, ok := result["error"]; ok && val != nil {
-			if v, ok := val.(string); ok {
-				errorMsg = v
-			}
-		}
+	job, err := h.jobs.Enqueue(ctx, userID, req.Videos)
+	if err != nil {
+		logging.Info("Error queueing playlist job: %s", err.Error())
+		httpx.RespondError(w, http.StatusInternalServerError, "Failed to queue playlist for processing")
+		return
+	}
 
-		// Create processed video info
-		processedVideo := ProcessedVideoInfo{
-			Channel:       video.Channel,
-			OriginalURL:   video.URL,
-			NormalizedURL: normalizedURL,
-			Title:         video.Title,
-			IsValid:       isValid,
-		}
+	response, err := newProcessJobResponse(job)
+	if err != nil {
+		logging.Info("Error building job response: %s", err.Error())
+		httpx.RespondError(w, http.StatusInternalServerError, "Failed to read job")
+		return
+	}
 
-		if errorMsg != "" {
-			processedVideo.Error = errorMsg
-		}
+	logging.Api("Queued job %s with %d videos", response.ID, response.Total)
+	w.Header().Set("Location", "/api/process/jobs/"+response.ID)
+	httpx.RespondJSON(w, http.StatusAccepted, response)
+}
 
-		if isValid {
-			validURLs = append(validURLs, normalizedURL)
-		} else {
-			failedURLs = append(failedURLs, video.URL)
-		}
+// Job handles GET /api/process/jobs/{id}
+// Returns the progress and per-URL results of an ingestion job
+func (h *ProcessHandler) Job(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
 
-		processed = append(processed, processedVideo)
+	userID, ok := auth.GetUserID(ctx)
+	if !ok {
+		httpx.RespondError(w, http.StatusUnauthorized, "User ID not found in context")
+		return
 	}
 
-	// Store valid videos in database using a transaction
-	if len(validURLs) > 0 {
-		logging.Info("Preparing to save %d valid videos to database", len(validURLs))
-		validVideos := make([]ProcessedVideoInfo, 0)
-		for _, p := range processed {
-			if p.IsValid && p.NormalizedURL != "" {
-				validVideos = append(validVideos, p)
-			}
-		}
+	// Parse UUID
+	var id pgtype.UUID
+	if err := id.Scan(chi.URLParam(r, "id")); err != nil {
+		httpx.RespondError(w, http.StatusBadRequest, "Invalid job ID")
+		return
+	}
 
-		if len(validVideos) > 0 {
-			logging.Info("Starting database transaction for %d videos", len(validVideos))
-			// Use transaction for batch insert
-			err := h.dbService.DB.WithTx(ctx, func(q *db.Queries) error {
-				logging.Info("Inside transaction, processing %d videos", len(validVideos))
-				savedCount := 0
-				for i, video := range validVideos {
-					videoID := extractVideoID(video.NormalizedURL)
-					if videoID == "" {
-						logging.Info("Warning: Could not extract video ID from URL: %s", video.NormalizedURL)
-						continue
-					}
-
-					// Get user ID from context (set by auth middleware)
-					userID, ok := auth.GetUserID(ctx)
-					if !ok {
-						logging.Info("DB: ERROR - No user ID in context, cannot save video: %s", video.Title)
-						logging.Info("DB: Context details - checking if auth middleware ran")
-						// This is a critical error - abort transaction
-						return fmt.Errorf("user ID not found in context - auth middleware may not have run")
-					}
-
-					logging.Info("DB: Attempting to create video %d/%d: %s (ID: %s, URL: %s, User: %s)", i+1, len(validVideos), video.Title, videoID, video.NormalizedURL, userID)
-					result, err := q.CreateVideo(ctx, &db.CreateVideoParams{
-						VideoID:       videoID,
-						NormalizedUrl: video.NormalizedURL,
-						OriginalUrl:   video.OriginalURL,
-						Title:         video.Title,
-						Channel:       video.Channel,
-						UserID:        userID,
-					})
-
-					if err != nil {
-						// ON CONFLICT DO NOTHING returns no rows, which is expected for duplicates
-						if errors.Is(err, pgx.ErrNoRows) {
-							logging.Info("DB: Video already exists (duplicate, skipped): '%s' (normalized URL: %s)", video.Title, video.NormalizedURL)
-							continue
-						}
-						// Log actual errors - these are real database errors
-						logging.Info("DB: ERROR saving video '%s': %s (error type: %T)", video.Title, err.Error(), err)
-						// Continue processing other videos, but log the error
-						// Don't abort transaction on individual video errors
-						continue
-					}
-
-					if result == nil {
-						logging.Info("DB: WARNING - CreateVideo returned nil result for '%s' (this should not happen)", video.Title)
-						continue
-					}
-
-					logging.Info("DB: ✅ Successfully saved video '%s' (DB ID: %d, normalized URL: %s)", video.Title, result.ID, result.NormalizedUrl)
-					savedCount++
-				}
-				logging.Info("DB: Processed %d videos, successfully saved %d new videos, skipped %d duplicates", len(validVideos), savedCount, len(validVideos)-savedCount)
-				return nil
-			})
-
-			if err != nil {
-				logging.Info("DB: Database transaction error: %s", err.Error())
-			} else {
-				logging.Info("DB: Transaction completed successfully")
-			}
+	job, err := h.dbService.Queries.GetJobForUser(ctx, &db.GetJobForUserParams{
+		ID:     id,
+		UserID: userID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			httpx.RespondError(w, http.StatusNotFound, "Job not found")
+			return
 		}
+		logging.Info("Error getting job: %s", err.Error())
+		httpx.RespondError(w, http.StatusInternalServerError, "Failed to fetch job")
+		return
 	}
 
-	// Create response
-	response := ProcessPlaylistResponse{
-		Processed: processed,
-		Total:     len(req.Videos),
-		Valid:     len(validURLs),
-		Invalid:   len(failedURLs),
+	response, err := newProcessJobResponse(job)
+	if err != nil {
+		logging.Info("Error building job response: %s", err.Error())
+		httpx.RespondError(w, http.StatusInternalServerError, "Failed to read job")
+		return
 	}
 
-	// Log summary
-	if len(validURLs) > 0 {
-		logging.Api("[SUCCESS]")
-		logging.Api("%d urls extracted successfully", len(validURLs))
-		for _, url := range validURLs {
-			logging.Info("%s", url)
-		}
-	}
-	if len(failedURLs) > 0 {
-		if len(validURLs) > 0 {
-			logging.Info("")
-		}
-		logging.Info("[FAILED]")
-		logging.Info("%d urls failed", len(failedURLs))
-		for _, url := range failedURLs {
-			logging.Info("%s", url)
+	if job.PlaylistID != nil {
+		playlist, err := h.dbService.Queries.GetPlaylistByID(ctx, &db.GetPlaylistByIDParams{
+			ID:     *job.PlaylistID,
+			UserID: userID,
+		})
+		if err == nil {
+			response.Playlist = playlist.Name
 		}
 	}
 
-	logging.Info("Sending response: %d total, %d valid, %d invalid", response.Total, response.Valid, response.Invalid)
 	httpx.RespondJSON(w, http.StatusOK, response)
-	logging.Info("Response sent successfully")
 }
 
 type ProcessVideoRequest struct {
 	Video VideoInfo `json:"video"`
+	// CreatePlaylist adds the members of a playlist URL to a new ekko playlist of the same name
+	CreatePlaylist bool `json:"createPlaylist"`
 }
 
 type ProcessVideoResponse struct {
@@ -286,7 +244,10 @@ type ProcessVideoResponse struct {
 }
 
 // Video handles POST /api/process/video
-// Receives a video and normalizes the YouTube URL using Lua
+// Receives a video and normalizes its URL using the Lua normalizer for its platform
+// Valid videos are saved; the result's status reports whether it was created or a duplicate.
+// Returns 500 if the video could not be saved.
+// YouTube playlist URLs are expanded into their videos, which are queued as a job (see playlistURL).
 func (h *ProcessHandler) Video(w http.ResponseWriter, r *http.Request) {
 	var req ProcessVideoRequest
 
@@ -303,115 +264,288 @@ func (h *ProcessHandler) Video(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if playlistID, ok := ingest.YouTubePlaylistID(req.Video.URL); ok {
+		h.playlistURL(w, r, playlistID, req.CreatePlaylist)
+		return
+	}
+
 	// Create context with timeout
 	// Use request context to preserve auth middleware's user ID
 	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 	defer cancel()
 
+	// Get user ID from context (set by auth middleware)
+	userID, hasUserID := auth.GetUserID(ctx)
+
 	// Normalize URL using Lua script
-	result, err := h.luaService.NormalizeURL(ctx, req.Video.URL)
-	if err != nil {
-		httpx.RespondJSON(w, http.StatusOK, ProcessVideoResponse{
-			Processed: ProcessedVideoInfo{
-				Channel:       req.Video.Channel,
-				OriginalURL:   req.Video.URL,
-				NormalizedURL: "",
-				Title:         req.Video.Title,
-				IsValid:       false,
-				Error:         "Failed to normalize URL: " + err.Error(),
-			},
-		})
+	processedVideo := h.ingestService.Normalize(ctx, userID, req.Video)
+
+	// Log result
+	if processedVideo.IsValid {
+		logging.Info("[SUCCESS]")
+		logging.Info("1 urls extracted successfully")
+		logging.Info("%s", processedVideo.NormalizedURL)
+	} else {
 		logging.Info("[FAILED]")
 		logging.Info("1 urls failed")
 		logging.Info("%s", req.Video.URL)
-		return
 	}
 
-	// Extract values from Lua result
-	isValid := false
-	normalizedURL := ""
-	errorMsg := ""
+	// Store valid video in database
+	if processedVideo.IsValid {
+		if !hasUserID {
+			httpx.RespondError(w, http.StatusUnauthorized, "User ID not found in context")
+			return
+		}
+
+		saved := []ProcessedVideoInfo{processedVideo}
+		if err := h.ingestService.Save(ctx, userID, saved); err != nil {
+			logging.Info("DB: Error saving video to database: %s - %s", req.Video.Title, err.Error())
+			httpx.RespondError(w, http.StatusInternalServerError, "Failed to save video")
+			return
+		}
+		processedVideo = saved[0]
+	}
+
+	httpx.RespondJSON(w, http.StatusOK, ProcessVideoResponse{
+		Processed: processedVideo,
+	})
+}
 
-	if val, ok := result["isValid"].(bool); ok {
-		isValid = val
+// playlistURL expands a YouTube playlist URL submitted to Video
+// The playlist's videos are fetched and queued as a job, and 202 is returned with the job like
+// Playlist does. With createPlaylist the saved videos also go into a new ekko playlist named
+// after the YouTube playlist, in the same order.
+func (h *ProcessHandler) playlistURL(w http.ResponseWriter, r *http.Request, playlistID string, createPlaylist bool) {
+	userID, ok := auth.GetUserID(r.Context())
+	if !ok {
+		httpx.RespondError(w, http.StatusUnauthorized, "User ID not found in context")
+		return
+	}
+
+	if h.playlists == nil {
+		httpx.RespondError(w, http.StatusBadRequest, "Playlist URLs are not supported")
+		return
 	}
 
-	if val, ok := result["normalizedUrl"].(string); ok && val != "" {
-		normalizedURL = val
-	} else if val, ok := result["normalizedUrl"]; ok && val != nil {
-		if v, ok := val.(string); ok {
-			normalizedURL = v
+	fetchCtx, fetchCancel := context.WithTimeout(r.Context(), playlistFetchTimeout)
+	fetched, err := h.playlists.FetchPlaylist(fetchCtx, playlistID)
+	fetchCancel()
+	if err != nil {
+		if errors.Is(err, ingest.ErrPlaylistNotFound) {
+			httpx.RespondError(w, http.StatusNotFound, "Playlist not found or private")
+			return
 		}
+		logging.Info("Error fetching playlist %s: %s", playlistID, err.Error())
+		httpx.RespondError(w, http.StatusBadGateway, "Failed to fetch playlist")
+		return
+	}
+
+	if len(fetched.Videos) == 0 {
+		httpx.RespondError(w, http.StatusUnprocessableEntity, "Playlist has no videos")
+		return
 	}
 
-	if val, ok := result["error"].(string); ok && val != "" {
-		errorMsg = val
-	} else if val, ok := result["error"]; ok && val != nil {
-		if v, ok := val.(string); ok {
-			errorMsg = v
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	var into *int64
+	playlistName := ""
+	if createPlaylist {
+		playlist, err := h.ingestService.CreatePlaylist(ctx, userID, fetched.Title)
+		if err != nil {
+			logging.Info("Error creating playlist for %s: %s", playlistID, err.Error())
+			httpx.RespondError(w, http.StatusInternalServerError, "Failed to create playlist")
+			return
 		}
+		playlistName = playlist.Name
+		into = &playlist.ID
+	}
+	job, err := h.jobs.EnqueuePlaylist(ctx, userID, fetched, into)
+	if err != nil {
+		logging.Info("Error queueing playlist job: %s", err.Error())
+		httpx.RespondError(w, http.StatusInternalServerError, "Failed to queue playlist for processing")
+		return
 	}
 
-	// Create processed video info
-	processedVideo := ProcessedVideoInfo{
-		Channel:       req.Video.Channel,
-		OriginalURL:   req.Video.URL,
-		NormalizedURL: normalizedURL,
-		Title:         req.Video.Title,
-		IsValid:       isValid,
+	response, err := newProcessJobResponse(job)
+	if err != nil {
+		logging.Info("Error building job response: %s", err.Error())
+		httpx.RespondError(w, http.StatusInternalServerError, "Failed to read job")
+		return
 	}
+	response.Playlist = playlistName
+
+	logging.Api("Queued job %s with %d videos from playlist %s", response.ID, response.Total, playlistID)
+	w.Header().Set("Location", "/api/process/jobs/"+response.ID)
+	httpx.RespondJSON(w, http.StatusAccepted, response)
+}
 
-	if errorMsg != "" {
-		processedVideo.Error = errorMsg
+// StreamResult is one line of a streamed ingestion response
+// Line is the 1-based input line the result belongs to
+type StreamResult struct {
+	Line int `json:"line"`
+	ProcessedVideoInfo
+}
+
+// streamLine is one non-blank line read from a stream request
+type streamLine struct {
+	number int
+	data   []byte
+	err    error
+}
+
+// Stream handles POST /api/process/stream
+// Reads application/x-ndjson with one VideoInfo per line and writes one StreamResult per
+// non-blank line, in input order, as soon as each batch of lines has been saved.
+// There is no body size limit or overall timeout; the connection is dropped after
+// streamIdleTimeout without input or progress.
+func (h *ProcessHandler) Stream(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.GetUserID(r.Context())
+	if !ok {
+		httpx.RespondError(w, http.StatusUnauthorized, "User ID not found in context")
+		return
 	}
 
-	// Log result
-	if isValid {
-		logging.Info("[SUCCESS]")
-		logging.Info("1 urls extracted successfully")
-		logging.Info("%s", normalizedURL)
-	} else {
-		logging.Info("[FAILED]")
-		logging.Info("1 urls failed")
-		logging.Info("%s", req.Video.URL)
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != ndjsonContentType {
+		httpx.RespondError(w, http.StatusUnsupportedMediaType, "Content-Type must be "+ndjsonContentType)
+		return
 	}
 
-	// Store valid video in database
-	if isValid && normalizedURL != "" {
-		videoID := extractVideoID(normalizedURL)
-		if videoID != "" {
-			// Get user ID from context (set by auth middleware)
-			userID, ok := auth.GetUserID(ctx)
-			if !ok {
-				logging.Info("DB: Warning: No user ID in context, skipping video: %s", req.Video.Title)
-			} else {
-				logging.Info("DB: Creating video: %s (ID: %s, URL: %s, User: %d)", req.Video.Title, videoID, normalizedURL, userID)
-				result, err := h.dbService.Queries.CreateVideo(ctx, &db.CreateVideoParams{
-					VideoID:       videoID,
-					NormalizedUrl: normalizedURL,
-					OriginalUrl:   req.Video.URL,
-					Title:         req.Video.Title,
-					Channel:       req.Video.Channel,
-					UserID:        userID,
-				})
-
-				if err != nil {
-					// ON CONFLICT DO NOTHING returns no rows, which is expected for duplicates
-					if errors.Is(err, pgx.ErrNoRows) {
-						logging.Info("DB: Video already exists in database (duplicate, skipped): '%s' (normalized URL: %s)", req.Video.Title, normalizedURL)
-					} else {
-						logging.Info("DB: Error saving video to database: %s - %s (error type: %T)", req.Video.Title, err.Error(), err)
-					}
-				} else {
-					logging.Info("DB: Successfully saved video '%s' (DB ID: %d, normalized URL: %s)", req.Video.Title, result.ID, result.NormalizedUrl)
+	rc := http.NewResponseController(w)
+	// Results are written while the request is still being read
+	if err := rc.EnableFullDuplex(); err != nil {
+		logging.Info("Stream: Full duplex unavailable: %s", err.Error())
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	lines := make(chan streamLine, streamBatchSize)
+	go readStreamLines(r, rc, lines, done)
+
+	w.Header().Set("Content-Type", ndjsonContentType)
+	w.WriteHeader(http.StatusOK)
+	encoder := json.NewEncoder(w)
+
+	total := 0
+	for line := range lines {
+		// Take whatever else has already arrived, up to a full batch
+		batch := []streamLine{line}
+	collect:
+		for len(batch) < streamBatchSize {
+			select {
+			case next, ok := <-lines:
+				if !ok {
+					break collect
 				}
+				batch = append(batch, next)
+			default:
+				break collect
+			}
+		}
+
+		results := h.processStreamBatch(r.Context(), userID, batch)
+
+		_ = rc.SetWriteDeadline(time.Now().Add(streamIdleTimeout))
+		for _, result := range results {
+			if err := encoder.Encode(result); err != nil {
+				logging.Info("Stream: Failed to write results: %s", err.Error())
+				return
 			}
-		} else {
-			logging.Info("Warning: Could not extract video ID from URL: %s", normalizedURL)
 		}
+		if err := rc.Flush(); err != nil {
+			logging.Info("Stream: Failed to flush results: %s", err.Error())
+			return
+		}
+		total += len(results)
 	}
 
-	httpx.RespondJSON(w, http.StatusOK, ProcessVideoResponse{
-		Processed: processedVideo,
-	})
+	logging.Info("Stream: Processed %d lines for user %s", total, userID)
+}
+
+// processStreamBatch decodes, normalizes and saves a batch of stream lines
+func (h *ProcessHandler) processStreamBatch(ctx context.Context, userID string, batch []streamLine) []StreamResult {
+	ctx, cancel := context.WithTimeout(ctx, streamBatchTimeout)
+	defer cancel()
+
+	results := make([]StreamResult, len(batch))
+	videos := make([]VideoInfo, 0, len(batch))
+	indexes := make([]int, 0, len(batch))
+	for i, line := range batch {
+		results[i].Line = line.number
+		if line.err != nil {
+			results[i].Status = ingest.VideoStatusError
+			results[i].Error = "Failed to read line: " + line.err.Error()
+			continue
+		}
+
+		var video VideoInfo
+		decoder := json.NewDecoder(bytes.NewReader(line.data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&video); err != nil {
+			results[i].Status = ingest.VideoStatusInvalid
+			results[i].Error = "Invalid JSON: " + err.Error()
+			continue
+		}
+		if video.URL == "" {
+			results[i].Channel = video.Channel
+			results[i].Title = video.Title
+			results[i].Status = ingest.VideoStatusInvalid
+			results[i].Error = "url field is required"
+			continue
+		}
+
+		videos = append(videos, video)
+		indexes = append(indexes, i)
+	}
+
+	if len(videos) == 0 {
+		return results
+	}
+
+	processed := h.ingestService.NormalizeAll(ctx, userID, videos)
+	if err := h.ingestService.Save(ctx, userID, processed); err != nil {
+		logging.Info("Stream: Failed to save videos: %s", err.Error())
+	}
+	for n, i := range indexes {
+		results[i].ProcessedVideoInfo = processed[n]
+	}
+	return results
+}
+
+// readStreamLines sends the non-blank lines of the request body to lines until the body
+// ends, a read fails or done is closed. A read error is sent as a final line.
+func readStreamLines(r *http.Request, rc *http.ResponseController, lines chan<- streamLine, done <-chan struct{}) {
+	defer close(lines)
+
+	scanner := bufio.NewScanner(r.Body)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxStreamLineSize)
+
+	number := 0
+	for {
+		_ = rc.SetReadDeadline(time.Now().Add(streamIdleTimeout))
+		if !scanner.Scan() {
+			break
+		}
+		number++
+
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+
+		select {
+		case lines <- streamLine{number: number, data: bytes.Clone(data)}:
+		case <-done:
+			return
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		select {
+		case lines <- streamLine{number: number + 1, err: err}:
+		case <-done:
+		}
+	}
 }
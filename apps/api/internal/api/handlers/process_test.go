@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/ekkolyth/ekko-playlist/api/internal/api/auth"
+	"github.com/ekkolyth/ekko-playlist/api/internal/db"
+	"github.com/ekkolyth/ekko-playlist/api/internal/ingest"
+	"github.com/ekkolyth/ekko-playlist/api/internal/lua"
+)
+
+// newUnreachableDB returns a database service whose connections always fail
+// The pool connects lazily, so only statements fail, as they do when the database goes away.
+func newUnreachableDB(t *testing.T) *db.Service {
+	t.Helper()
+	pool, err := pgxpool.New(context.Background(), "postgres://ekko@127.0.0.1:1/ekko?connect_timeout=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pool.Close)
+	queries := db.New(pool)
+	return &db.Service{DB: &db.DB{Pool: pool, Queries: queries}, Queries: queries}
+}
+
+func newStreamHandler(t *testing.T, hooks map[string]string) *ProcessHandler {
+	t.Helper()
+	luaService, err := lua.NewService()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { luaService.Close() })
+	if err := luaService.SetDatabaseScripts(hooks); err != nil {
+		t.Fatal(err)
+	}
+	dbService := newUnreachableDB(t)
+	return NewProcessHandler(ingest.NewService(luaService, dbService), nil, dbService, nil)
+}
+
+func streamRequest(body string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/api/process/stream", strings.NewReader(body))
+	r.Header.Set("Content-Type", ndjsonContentType+"; charset=utf-8")
+	return r.WithContext(auth.WithUser(r.Context(), "user", "user@example.com"))
+}
+
+func TestStreamMixedBatch(t *testing.T) {
+	h := newStreamHandler(t, map[string]string{
+		"hooks/reject.lua": `function on_ingest(video)
+			if video.title == "spam" then return { reject = true, reason = "spam" } end
+		end`,
+	})
+
+	body := strings.Join([]string{
+		`{"url":"https://www.youtube.com/watch?v=dQw4w9WgXcQ","title":"Never Gonna Give You Up"}`,
+		`{"url":`,
+		``,
+		`{"title":"no url","channel":"somebody"}`,
+		`{"url":"https://youtu.be/dQw4w9WgXcQ","title":"spam"}`,
+		`{"url":"not a video"}`,
+		`{"url":"https://vimeo.com/76979871","unknown":true}`,
+	}, "\n")
+	w := httptest.NewRecorder()
+	h.Stream(w, streamRequest(body))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("Content-Type"); got != ndjsonContentType {
+		t.Errorf("Content-Type = %q", got)
+	}
+
+	var results []StreamResult
+	scanner := bufio.NewScanner(w.Body)
+	for scanner.Scan() {
+		var result StreamResult
+		if err := json.Unmarshal(scanner.Bytes(), &result); err != nil {
+			t.Fatalf("invalid result line %q: %v", scanner.Text(), err)
+		}
+		results = append(results, result)
+	}
+
+	want := []struct {
+		line   int
+		status string
+		error  string
+	}{
+		// Valid videos are normalized, and fail alone when they can't be saved
+		{1, ingest.VideoStatusError, "Failed to save video"},
+		{2, ingest.VideoStatusInvalid, "Invalid JSON"},
+		{4, ingest.VideoStatusInvalid, "url field is required"},
+		{5, ingest.VideoStatusInvalid, "Rejected by hooks/reject.lua: spam"},
+		{6, ingest.VideoStatusInvalid, ""},
+		{7, ingest.VideoStatusInvalid, "Invalid JSON"},
+	}
+	if len(results) != len(want) {
+		t.Fatalf("got %d results, want %d:\n%s", len(results), len(want), w.Body.String())
+	}
+	for i, tt := range want {
+		result := results[i]
+		if result.Line != tt.line || result.Status != tt.status || !strings.Contains(result.Error, tt.error) {
+			t.Errorf("result %d = line %d, status %q, error %q; want line %d, status %q, error containing %q",
+				i, result.Line, result.Status, result.Error, tt.line, tt.status, tt.error)
+		}
+	}
+
+	if first := results[0]; !first.IsValid || first.Platform != "youtube" || first.VideoID != "dQw4w9WgXcQ" {
+		t.Errorf("first result = %+v, want the normalized video", first)
+	}
+	if results[2].Channel != "somebody" || results[2].Title != "no url" {
+		t.Errorf("result without url = %+v, want its title and channel echoed", results[2])
+	}
+}
+
+func TestStreamRequiresNDJSON(t *testing.T) {
+	h := newStreamHandler(t, nil)
+
+	r := streamRequest(`{"url":"https://youtu.be/dQw4w9WgXcQ"}`)
+	r.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	h.Stream(w, r)
+
+	if w.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusUnsupportedMediaType)
+	}
+}
@@ -0,0 +1,321 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/ekkolyth/ekko-playlist/api/internal/api/auth"
+	"github.com/ekkolyth/ekko-playlist/api/internal/api/httpx"
+	"github.com/ekkolyth/ekko-playlist/api/internal/db"
+	"github.com/ekkolyth/ekko-playlist/api/internal/logging"
+	"github.com/ekkolyth/ekko-playlist/api/internal/lua"
+	"github.com/ekkolyth/ekko-playlist/api/internal/scripts"
+)
+
+// maxDryRunURLs caps how many sample URLs a single dry run may normalize
+const maxDryRunURLs = 100
+
+type ScriptsHandler struct {
+	scriptsService *scripts.Service
+	luaService     *lua.Service
+}
+
+func NewScriptsHandler(scriptsService *scripts.Service, luaService *lua.Service) *ScriptsHandler {
+	return &ScriptsHandler{
+		scriptsService: scriptsService,
+		luaService:     luaService,
+	}
+}
+
+type ScriptSummary struct {
+	Name          string `json:"name"`
+	Origin        string `json:"origin"`
+	ActiveVersion *int32 `json:"activeVersion,omitempty"`
+}
+
+type ListScriptsResponse struct {
+	Scripts []ScriptSummary `json:"scripts"`
+}
+
+type ScriptVersionResponse struct {
+	Version     int32  `json:"version"`
+	Source      string `json:"source"`
+	Active      bool   `json:"active"`
+	CreatedBy   string `json:"createdBy,omitempty"`
+	CreatedAt   string `json:"createdAt"`
+	ActivatedAt string `json:"activatedAt,omitempty"`
+}
+
+type ScriptResponse struct {
+	Name     string                  `json:"name"`
+	Origin   string                  `json:"origin,omitempty"`
+	Source   string                  `json:"source"`
+	Versions []ScriptVersionResponse `json:"versions"`
+}
+
+type CreateScriptVersionRequest struct {
+	Source   string `json:"source"`
+	Activate bool   `json:"activate"`
+}
+
+type DryRunScriptRequest struct {
+	Source string   `json:"source"`
+	URLs   []string `json:"urls"`
+}
+
+type DryRunScriptResponse struct {
+	Results []scripts.DryRunResult `json:"results"`
+}
+
+func newScriptVersionResponse(script *db.LuaScript) ScriptVersionResponse {
+	response := ScriptVersionResponse{
+		Version: script.Version,
+		Source:  script.Source,
+		Active:  script.Active,
+	}
+	if script.CreatedBy.Valid {
+		response.CreatedBy = script.CreatedBy.String()
+	}
+	if script.CreatedAt.Valid {
+		response.CreatedAt = script.CreatedAt.Time.Format(time.RFC3339)
+	}
+	if script.ActivatedAt.Valid {
+		response.ActivatedAt = script.ActivatedAt.Time.Format(time.RFC3339)
+	}
+	return response
+}
+
+// scriptName reads the {name} URL parameter
+// Names contain slashes (e.g. normalizers/youtube.lua), so clients send them URL-encoded
+func scriptName(r *http.Request) (string, bool) {
+	name, err := url.PathUnescape(chi.URLParam(r, "name"))
+	if err != nil || !lua.ValidScriptName(name) {
+		return "", false
+	}
+	return name, true
+}
+
+// respondScriptError maps scripts service errors to responses
+func respondScriptError(w http.ResponseWriter, err error, action string) {
+	var validationErr *scripts.ValidationError
+	switch {
+	case errors.Is(err, scripts.ErrInvalidName):
+		httpx.RespondError(w, http.StatusBadRequest, "Invalid script name")
+	case errors.Is(err, scripts.ErrNotFound):
+		httpx.RespondError(w, http.StatusNotFound, "Script version not found")
+	case errors.As(err, &validationErr):
+		httpx.RespondError(w, http.StatusUnprocessableEntity, validationErr.Error())
+	default:
+		logging.Info("Error trying to %s: %s", action, err.Error())
+		httpx.RespondError(w, http.StatusInternalServerError, "Failed to "+action)
+	}
+}
+
+// List handles GET /api/admin/scripts
+// Returns every loaded script, where it came from and its active database version
+func (h *ScriptsHandler) List(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	activeVersions, err := h.scriptsService.ActiveVersions(ctx)
+	if err != nil {
+		respondScriptError(w, err, "list scripts")
+		return
+	}
+
+	loaded := h.luaService.Scripts()
+	response := ListScriptsResponse{
+		Scripts: make([]ScriptSummary, 0, len(loaded)),
+	}
+	for _, script := range loaded {
+		summary := ScriptSummary{
+			Name:   script.Name,
+			Origin: script.Origin,
+		}
+		if version, ok := activeVersions[script.Name]; ok {
+			summary.ActiveVersion = &version
+		}
+		response.Scripts = append(response.Scripts, summary)
+	}
+
+	httpx.RespondJSON(w, http.StatusOK, response)
+}
+
+// Get handles GET /api/admin/scripts/{name}
+// Returns the script's current source and its stored version history
+func (h *ScriptsHandler) Get(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	name, ok := scriptName(r)
+	if !ok {
+		httpx.RespondError(w, http.StatusBadRequest, "Invalid script name")
+		return
+	}
+
+	versions, err := h.scriptsService.Versions(ctx, name)
+	if err != nil {
+		respondScriptError(w, err, "list script versions")
+		return
+	}
+
+	source, loaded := h.luaService.Source(name)
+	if !loaded && len(versions) == 0 {
+		httpx.RespondError(w, http.StatusNotFound, "Script not found")
+		return
+	}
+
+	response := ScriptResponse{
+		Name:     name,
+		Source:   source,
+		Versions: make([]ScriptVersionResponse, 0, len(versions)),
+	}
+	for _, info := range h.luaService.Scripts() {
+		if info.Name == name {
+			response.Origin = info.Origin
+			break
+		}
+	}
+	for _, version := range versions {
+		response.Versions = append(response.Versions, newScriptVersionResponse(version))
+	}
+
+	httpx.RespondJSON(w, http.StatusOK, response)
+}
+
+// CreateVersion handles POST /api/admin/scripts/{name}/versions
+// Saves a new version of the script, activating it if requested
+// Returns 422 if the script doesn't compile or fails its self-test
+func (h *ScriptsHandler) CreateVersion(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	userID, ok := auth.GetUserID(ctx)
+	if !ok {
+		httpx.RespondError(w, http.StatusUnauthorized, "User ID not found in context")
+		return
+	}
+
+	name, ok := scriptName(r)
+	if !ok {
+		httpx.RespondError(w, http.StatusBadRequest, "Invalid script name")
+		return
+	}
+
+	var req CreateScriptVersionRequest
+	if err := httpx.DecodeJSON(w, r, &req, 1<<20); err != nil {
+		httpx.RespondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if req.Source == "" {
+		httpx.RespondError(w, http.StatusBadRequest, "Script source is required")
+		return
+	}
+
+	script, err := h.scriptsService.CreateVersion(ctx, name, req.Source, userID, req.Activate)
+	if err != nil {
+		respondScriptError(w, err, "save script")
+		return
+	}
+
+	httpx.RespondJSON(w, http.StatusCreated, newScriptVersionResponse(script))
+}
+
+// Activate handles POST /api/admin/scripts/{name}/versions/{version}/activate
+// Makes a stored version the active version of the script
+func (h *ScriptsHandler) Activate(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	name, ok := scriptName(r)
+	if !ok {
+		httpx.RespondError(w, http.StatusBadRequest, "Invalid script name")
+		return
+	}
+
+	version, err := strconv.ParseInt(chi.URLParam(r, "version"), 10, 32)
+	if err != nil {
+		httpx.RespondError(w, http.StatusBadRequest, "Invalid script version")
+		return
+	}
+
+	script, err := h.scriptsService.Activate(ctx, name, int32(version))
+	if err != nil {
+		respondScriptError(w, err, "activate script")
+		return
+	}
+
+	httpx.RespondJSON(w, http.StatusOK, newScriptVersionResponse(script))
+}
+
+// Deactivate handles DELETE /api/admin/scripts/{name}/active
+// Reverts the script to its built-in version, keeping the stored versions
+func (h *ScriptsHandler) Deactivate(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	name, ok := scriptName(r)
+	if !ok {
+		httpx.RespondError(w, http.StatusBadRequest, "Invalid script name")
+		return
+	}
+
+	if err := h.scriptsService.Deactivate(ctx, name); err != nil {
+		respondScriptError(w, err, "deactivate script")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DryRun handles POST /api/admin/scripts/{name}/dry-run
+// Normalizes sample URLs with a candidate script and with the current scripts, side by side
+// Nothing is saved or activated
+func (h *ScriptsHandler) DryRun(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	name, ok := scriptName(r)
+	if !ok {
+		httpx.RespondError(w, http.StatusBadRequest, "Invalid script name")
+		return
+	}
+
+	var req DryRunScriptRequest
+	if err := httpx.DecodeJSON(w, r, &req, 1<<20); err != nil {
+		httpx.RespondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if req.Source == "" {
+		httpx.RespondError(w, http.StatusBadRequest, "Script source is required")
+		return
+	}
+
+	if len(req.URLs) == 0 {
+		httpx.RespondError(w, http.StatusBadRequest, "urls array cannot be empty")
+		return
+	}
+
+	if len(req.URLs) > maxDryRunURLs {
+		httpx.RespondError(w, http.StatusBadRequest, "Too many urls (max "+strconv.Itoa(maxDryRunURLs)+")")
+		return
+	}
+
+	results, err := h.scriptsService.DryRun(ctx, name, req.Source, req.URLs)
+	if err != nil {
+		respondScriptError(w, err, "dry-run script")
+		return
+	}
+
+	httpx.RespondJSON(w, http.StatusOK, DryRunScriptResponse{
+		Results: results,
+	})
+}
@@ -315,21 +315,19 @@ func (h *TagsHandler) AssignTags(w http.ResponseWriter, r *http.Request) {
 
 	// Verify all videos belong to user
 	for _, videoID := range req.VideoIDs {
-		video, err := h.dbService.Queries.GetVideoByID(ctx, videoID)
-		if err != nil {
+		if _, err := h.dbService.Queries.GetVideoForUser(ctx, &db.GetVideoForUserParams{
+			ID:     videoID,
+			UserID: userID,
+		}); err != nil {
 			httpx.RespondError(w, http.StatusNotFound, "Video not found")
 			return
 		}
-		if video.UserID != userID {
-			httpx.RespondError(w, http.StatusForbidden, "You don't have permission to tag this video")
-			return
-		}
 	}
 
 	// Bulk insert
 	err := h.dbService.Queries.AddVideoTags(ctx, &db.AddVideoTagsParams{
-		Column1: req.VideoIDs,
-		Column2: req.TagIDs,
+		VideoIds: req.VideoIDs,
+		TagIds:   req.TagIDs,
 	})
 	if err != nil {
 		logging.Info("Error assigning tags: %s", err.Error())
@@ -366,19 +364,18 @@ func (h *TagsHandler) UnassignTags(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Verify video belongs to user
-	video, err := h.dbService.Queries.GetVideoByID(ctx, req.VideoID)
+	_, err := h.dbService.Queries.GetVideoForUser(ctx, &db.GetVideoForUserParams{
+		ID:     req.VideoID,
+		UserID: userID,
+	})
 	if err != nil {
 		httpx.RespondError(w, http.StatusNotFound, "Video not found")
 		return
 	}
-	if video.UserID != userID {
-		httpx.RespondError(w, http.StatusForbidden, "You don't have permission to untag this video")
-		return
-	}
 
 	err = h.dbService.Queries.RemoveVideoTags(ctx, &db.RemoveVideoTagsParams{
 		VideoID: req.VideoID,
-		Column2: req.TagIDs,
+		TagIds:  req.TagIDs,
 	})
 	if err != nil {
 		logging.Info("Error unassigning tags: %s", err.Error())
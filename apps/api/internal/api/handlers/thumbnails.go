@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/ekkolyth/ekko-playlist/api/internal/api/auth"
+	"github.com/ekkolyth/ekko-playlist/api/internal/api/httpx"
+	"github.com/ekkolyth/ekko-playlist/api/internal/db"
+	"github.com/ekkolyth/ekko-playlist/api/internal/logging"
+	"github.com/ekkolyth/ekko-playlist/api/internal/thumbnails"
+)
+
+type ThumbnailsHandler struct {
+	dbService *db.Service
+	store     *thumbnails.Store
+}
+
+func NewThumbnailsHandler(dbService *db.Service) *ThumbnailsHandler {
+	return &ThumbnailsHandler{
+		dbService: dbService,
+		store:     thumbnails.NewStore(thumbnails.Dir()),
+	}
+}
+
+// Serve handles GET /api/uploads/thumbnails/{filename}
+// Serves a cached thumbnail to users who have saved a video with it
+func (h *ThumbnailsHandler) Serve(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	userID, ok := auth.GetUserID(ctx)
+	if !ok {
+		httpx.RespondError(w, http.StatusUnauthorized, "User ID not found in context")
+		return
+	}
+
+	filename := chi.URLParam(r, "filename")
+	hash, ok := thumbnails.ParseFilename(filename)
+	if !ok {
+		httpx.RespondError(w, http.StatusNotFound, "Thumbnail not found")
+		return
+	}
+
+	// Thumbnails are shared between users, so they are only served to users with a video using them
+	saved, err := h.dbService.Queries.UserHasThumbnail(ctx, &db.UserHasThumbnailParams{
+		UserID:        userID,
+		ThumbnailHash: &hash,
+	})
+	if err != nil {
+		logging.Info("Error checking thumbnail access: %s", err.Error())
+		httpx.RespondError(w, http.StatusInternalServerError, "Failed to fetch thumbnail")
+		return
+	}
+	if !saved {
+		httpx.RespondError(w, http.StatusNotFound, "Thumbnail not found")
+		return
+	}
+
+	file, err := os.Open(h.store.Path(filename))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			httpx.RespondError(w, http.StatusNotFound, "Thumbnail not found")
+			return
+		}
+		logging.Info("Error opening thumbnail: %s", err.Error())
+		httpx.RespondError(w, http.StatusInternalServerError, "Failed to fetch thumbnail")
+		return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		logging.Info("Error reading thumbnail: %s", err.Error())
+		httpx.RespondError(w, http.StatusInternalServerError, "Failed to fetch thumbnail")
+		return
+	}
+
+	// The content of a file never changes, its name is the hash of the image
+	w.Header().Set("Content-Type", "image/jpeg")
+	w.Header().Set("Cache-Control", "private, max-age=31536000, immutable")
+	http.ServeContent(w, r, filename, info.ModTime(), file)
+}
@@ -2,15 +2,22 @@ package handlers
 
 import (
 	"context"
+	"html"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
 	"github.com/ekkolyth/ekko-playlist/api/internal/api/auth"
 	"github.com/ekkolyth/ekko-playlist/api/internal/api/httpx"
+	"github.com/ekkolyth/ekko-playlist/api/internal/api/pagination"
+	"github.com/ekkolyth/ekko-playlist/api/internal/availability"
 	"github.com/ekkolyth/ekko-playlist/api/internal/db"
 	"github.com/ekkolyth/ekko-playlist/api/internal/logging"
+	"github.com/ekkolyth/ekko-playlist/api/internal/thumbnails"
 )
 
 type VideosHandler struct {
@@ -36,19 +43,76 @@ type VideoResponse struct {
 	OriginalURL   string    `json:"originalUrl"`
 	Title         string    `json:"title"`
 	Channel       string    `json:"channel"`
+	ChannelID     *int64    `json:"channelId"`
+	Platform      string    `json:"platform"`
+	StartSeconds  int32     `json:"startSeconds"`
 	UserID        string    `json:"userId"`
 	CreatedAt     string    `json:"createdAt"`
 	Tags          []TagInfo `json:"tags"`
+
+	// Supplied by the client or filled in by the metadata enricher; null until known
+	ThumbnailURL      *string `json:"thumbnailUrl"`
+	DurationSeconds   *int32  `json:"durationSeconds"`
+	PublishedAt       *string `json:"publishedAt"`
+	ViewCount         *int64  `json:"viewCount"`
+	ChannelHandle     *string `json:"channelHandle"`
+	ChannelExternalID *string `json:"channelExternalId"`
+	MetadataStatus    string  `json:"metadataStatus"`
+	// URLs of the cached copies of the thumbnail by size (small, medium); null until cached
+	CachedThumbnails map[string]string `json:"cachedThumbnails"`
+
+	// Set by the availability monitor; "unknown" until the video's first check
+	Availability       string  `json:"availability"`
+	AvailabilityReason *string `json:"availabilityReason"`
+	LastCheckedAt      *string `json:"lastCheckedAt"`
+
+	// How the video matched the search; only set when searching
+	Match *SearchMatch `json:"match,omitempty"`
+
+	// Position of the video in a playlist; only set in playlists
+	Position *int32 `json:"position,omitempty"`
+}
+
+// SearchMatch is how a video matched a search
+// Title and Channel are HTML-escaped, with the words that matched wrapped in <mark> tags.
+type SearchMatch struct {
+	Rank    float32 `json:"rank"`
+	Title   string  `json:"title"`
+	Channel string  `json:"channel"`
 }
 
 type ListVideosResponse struct {
 	Videos []VideoResponse `json:"videos"`
+	// Cursor of the next page, or null on the last page
+	NextCursor *string `json:"nextCursor"`
+	// Number of videos matching the filters, across all pages; only on the first page
+	Total *int `json:"total,omitempty"`
 }
 
+// Sort fields of video lists
+const (
+	sortCreatedAt = "created_at"
+	sortTitle     = "title"
+	sortChannel   = "channel"
+	sortRelevance = "relevance"
+	sortPosition  = "position"
+)
+
 // List handles GET /api/videos
 // Returns a list of videos for the authenticated user
 // Supports optional "channels" query parameter for filtering (comma-separated or array format)
 // Supports optional "unassigned" query parameter to filter videos not in any playlist
+// Supports optional "search" query parameter for full-text search in web search syntax ("rick astley" -live),
+// ranked by relevance and combined with every other filter
+// Supports "limit" (1-200) and "cursor" (nextCursor of the previous page) query parameters for pagination;
+// without either every video is returned, as before lists were paged
+// Supports optional "sort" query parameter: created_at, title, channel or, when searching, relevance;
+// prefixed with - for descending (default -created_at, or -relevance when searching)
+// Supports optional "platform" query parameter for filtering by platform (comma-separated, e.g. youtube,vimeo)
+// Supports optional "availability" query parameter for filtering by availability (available, unavailable or unknown)
+// Supports optional "created_after", "created_before", "published_after" and "published_before" query parameters
+// (YYYY-MM-DD or RFC 3339; after bounds are inclusive, before bounds exclusive)
+// All filters, the sort and the page are applied in a single db.VideoQuery
 func (h *VideosHandler) List(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 	defer cancel()
@@ -117,147 +181,195 @@ func (h *VideosHandler) List(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	// Parse search query parameter
+	// Parse platform filter from query parameters
+	platforms := make(map[string]bool)
+	for _, platform := range strings.Split(r.URL.Query().Get("platform"), ",") {
+		platform = strings.ToLower(strings.TrimSpace(platform))
+		if platform != "" {
+			platforms[platform] = true
+		}
+	}
+
+	// Parse availability filter from query parameters
+	availabilityFilter := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("availability")))
+	switch availabilityFilter {
+	case "", availability.StatusAvailable, availability.StatusUnavailable, availability.StatusUnknown:
+	default:
+		httpx.RespondError(w, http.StatusBadRequest, "availability must be available, unavailable or unknown")
+		return
+	}
+
+	// Parse search query parameter (web search syntax, matched against title, channel and tags)
 	searchTerm := strings.TrimSpace(r.URL.Query().Get("search"))
-	searchPattern := ""
+
+	// Parse pagination query parameters; search results are ordered by relevance by default
+	limit, err := pageLimit(r)
+	if err != nil {
+		httpx.RespondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	sortFields := []string{sortCreatedAt, sortTitle, sortChannel}
+	defaultSort := pagination.Sort{Field: sortCreatedAt, Desc: true}
 	if searchTerm != "" {
-		// Format search term for ILIKE pattern matching
-		searchPattern = "%" + searchTerm + "%"
+		sortFields = append(sortFields, sortRelevance)
+		defaultSort = pagination.Sort{Field: sortRelevance, Desc: true}
+	}
+	sorting, err := pagination.ParseSort(r.URL.Query().Get("sort"), sortFields, defaultSort)
+	if err != nil {
+		httpx.RespondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	after, err := pagination.DecodeCursor(r.URL.Query().Get("cursor"), sorting)
+	if err != nil {
+		httpx.RespondError(w, http.StatusBadRequest, "Invalid cursor")
+		return
 	}
 
-	var videos []*db.Video
-	var err error
-
-	// Tag filtering uses AND logic (videos must have ALL selected tags)
-	// If tags are specified, filter by tags first, then apply other filters
-	if len(tagIDs) > 0 {
-		// First filter by tags
-		videos, err = h.dbService.Queries.FilterVideosByTagsAnd(ctx, &db.FilterVideosByTagsAndParams{
-			UserID:  userID,
-			Column2: tagIDs,
-		})
-		if err != nil {
-			logging.Info("Error filtering videos by tags: %s", err.Error())
-			httpx.RespondError(w, http.StatusInternalServerError, "Failed to filter videos by tags")
+	query := &db.VideoQuery{
+		UserID:          userID,
+		Search:          searchTerm,
+		HeadlineOptions: headlineOptions,
+		Channels:        channels,
+		TagIDs:          tagIDs,
+		Unassigned:      showUnassigned,
+		Availability:    availabilityFilter,
+		Sort:            db.VideoSort(sorting.Field),
+		SortDesc:        sorting.Desc,
+	}
+	if limit > 0 {
+		// One more than the page, to know whether there is a next page
+		query.Limit = limit + 1
+	}
+	for platform := range platforms {
+		query.Platforms = append(query.Platforms, platform)
+	}
+	for _, dateRange := range []struct {
+		param string
+		bound *time.Time
+	}{
+		{"created_after", &query.CreatedAfter},
+		{"created_before", &query.CreatedBefore},
+		{"published_after", &query.PublishedAfter},
+		{"published_before", &query.PublishedBefore},
+	} {
+		value := strings.TrimSpace(r.URL.Query().Get(dateRange.param))
+		if value == "" {
+			continue
+		}
+		if *dateRange.bound, err = parseDate(value); err != nil {
+			httpx.RespondError(w, http.StatusBadRequest, dateRange.param+" must be a date (YYYY-MM-DD) or an RFC 3339 time")
 			return
 		}
+	}
+	query.After = videoKey(after)
 
-		// Then filter by channels if specified (in-memory filter)
-		if len(channels) > 0 {
-			filtered := videos[:0]
-			for _, video := range videos {
-				for _, channel := range channels {
-					if video.Channel == channel {
-						filtered = append(filtered, video)
-						break
-					}
-				}
-			}
-			videos = filtered
-		}
+	rows, err := h.dbService.Queries.QueryVideos(ctx, query)
+	if err != nil {
+		logging.Info("Error listing videos: %s", err.Error())
+		httpx.RespondError(w, http.StatusInternalServerError, "Failed to fetch videos")
+		return
+	}
 
-		// Then filter by unassigned if specified (in-memory filter)
-		if showUnassigned {
-			allVideoIDs := make([]int64, len(videos))
-			for i, v := range videos {
-				allVideoIDs[i] = v.ID
-			}
-			// Get videos that are in playlists
-			var playlistVideoIDs []int64
-			if len(allVideoIDs) > 0 {
-				rows, err := h.dbService.DB.Pool.Query(ctx, "SELECT DISTINCT video_id FROM playlist_videos WHERE video_id = ANY($1)", allVideoIDs)
-				if err == nil {
-					defer rows.Close()
-					for rows.Next() {
-						var id int64
-						if err := rows.Scan(&id); err == nil {
-							playlistVideoIDs = append(playlistVideoIDs, id)
-						}
-					}
-				}
-			}
-			playlistVideoIDSet := make(map[int64]bool)
-			for _, id := range playlistVideoIDs {
-				playlistVideoIDSet[id] = true
-			}
-			filtered := videos[:0]
-			for _, video := range videos {
-				if !playlistVideoIDSet[video.ID] {
-					filtered = append(filtered, video)
-				}
-			}
-			videos = filtered
-		}
+	var nextCursor string
+	if limit > 0 && len(rows) > limit {
+		rows = rows[:limit]
+		nextCursor = pagination.EncodeCursor(sorting, videoSortKey(sorting.Field)(rows[limit-1]))
+	}
 
-		// Search is not supported with tag filtering for now
-		// (would require more complex SQL queries)
-	} else if searchPattern != "" {
-		// Use appropriate query based on filters
-		// Search-enabled queries
-		if showUnassigned {
-			if len(channels) > 0 {
-				// Unassigned videos with channel filter and search
-				videos, err = h.dbService.Queries.ListVideosUnassignedFilteredWithSearch(ctx, &db.ListVideosUnassignedFilteredWithSearchParams{
-					UserID:  userID,
-					Column2: channels,
-					Title:   searchPattern,
-				})
-			} else {
-				// Unassigned videos with search only
-				videos, err = h.dbService.Queries.ListVideosUnassignedWithSearch(ctx, &db.ListVideosUnassignedWithSearchParams{
-					UserID: userID,
-					Title:  searchPattern,
-				})
-			}
-		} else {
-			if len(channels) > 0 {
-				// Regular videos with channel filter and search
-				videos, err = h.dbService.Queries.ListVideosFilteredWithSearch(ctx, &db.ListVideosFilteredWithSearchParams{
-					UserID:  userID,
-					Column2: channels,
-					Title:   searchPattern,
-				})
-			} else {
-				// Regular videos with search only
-				videos, err = h.dbService.Queries.ListVideosWithSearch(ctx, &db.ListVideosWithSearchParams{
-					UserID: userID,
-					Title:  searchPattern,
-				})
+	videos := make([]*db.Video, 0, len(rows))
+	var matches map[int64]*SearchMatch
+	if searchTerm != "" {
+		matches = make(map[int64]*SearchMatch, len(rows))
+	}
+	for _, row := range rows {
+		videos = append(videos, row.Video)
+		if matches != nil {
+			matches[row.Video.ID] = &SearchMatch{
+				Rank:    row.Rank,
+				Title:   highlightHTML(row.TitleHighlight),
+				Channel: highlightHTML(row.ChannelHighlight),
 			}
 		}
-	} else {
-		// Non-search queries
-		if showUnassigned {
-			if len(channels) > 0 {
-				// Unassigned videos with channel filter
-				videos, err = h.dbService.Queries.ListVideosUnassignedFiltered(ctx, &db.ListVideosUnassignedFilteredParams{
-					UserID:  userID,
-					Column2: channels,
-				})
-			} else {
-				// Unassigned videos without channel filter
-				videos, err = h.dbService.Queries.ListVideosUnassigned(ctx, userID)
-			}
-		} else {
-			if len(channels) > 0 {
-				// Regular videos with channel filter
-				videos, err = h.dbService.Queries.ListVideosFiltered(ctx, &db.ListVideosFilteredParams{
-					UserID:  userID,
-					Column2: channels,
-				})
-			} else {
-				// Regular videos without channel filter
-				videos, err = h.dbService.Queries.ListVideos(ctx, userID)
+	}
+
+	response := ListVideosResponse{
+		Videos:     videoResponses(ctx, h.dbService.Queries, videos),
+		NextCursor: optionalCursor(nextCursor),
+	}
+
+	// The total counts every page and is only given on the first, so it is counted at most once
+	// per listing; a first page that is also the last one already has every video
+	if after == nil {
+		total := len(videos)
+		if nextCursor != "" {
+			count, err := h.dbService.Queries.CountVideos(ctx, query)
+			if err != nil {
+				logging.Info("Error counting videos: %s", err.Error())
+				httpx.RespondError(w, http.StatusInternalServerError, "Failed to fetch videos")
+				return
 			}
+			total = int(count)
 		}
+		response.Total = &total
+	}
+	for i := range response.Videos {
+		response.Videos[i].Match = matches[response.Videos[i].ID]
+	}
+
+	httpx.RespondJSON(w, http.StatusOK, response)
+}
+
+// Get handles GET /api/videos/{id}
+// Returns a single video of the authenticated user, including its enriched metadata
+func (h *VideosHandler) Get(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	// Get user ID from context (set by auth middleware)
+	userID, ok := auth.GetUserID(ctx)
+	if !ok {
+		httpx.RespondError(w, http.StatusUnauthorized, "User ID not found in context")
+		return
 	}
 
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
 	if err != nil {
-		logging.Info("Error listing videos: %s", err.Error())
-		httpx.RespondError(w, http.StatusInternalServerError, "Failed to fetch videos")
+		httpx.RespondError(w, http.StatusBadRequest, "Invalid video ID")
+		return
+	}
+
+	rows, err := h.dbService.Queries.QueryVideos(ctx, &db.VideoQuery{UserID: userID, ID: id})
+	if err != nil {
+		logging.Info("Error getting video: %s", err.Error())
+		httpx.RespondError(w, http.StatusInternalServerError, "Failed to fetch video")
+		return
+	}
+	if len(rows) == 0 {
+		httpx.RespondError(w, http.StatusNotFound, "Video not found")
+		return
+	}
+	video := rows[0].Video
+
+	videoTags, err := h.dbService.Queries.GetVideoTags(ctx, video.ID)
+	if err != nil {
+		logging.Info("Error getting video tags: %s", err.Error())
+		httpx.RespondError(w, http.StatusInternalServerError, "Failed to fetch video")
 		return
 	}
+	tags := make([]TagInfo, 0, len(videoTags))
+	for _, tag := range videoTags {
+		tags = append(tags, TagInfo{
+			ID:    tag.ID,
+			Name:  tag.Name,
+			Color: tag.Color,
+		})
+	}
+
+	httpx.RespondJSON(w, http.StatusOK, newVideoResponse(video, tags))
+}
 
+// videoResponses converts videos to responses, with their tags
+func videoResponses(ctx context.Context, queries *db.Queries, videos []*db.Video) []VideoResponse {
 	// Get video IDs
 	videoIDs := make([]int64, len(videos))
 	for i, video := range videos {
@@ -267,7 +379,7 @@ func (h *VideosHandler) List(w http.ResponseWriter, r *http.Request) {
 	// Fetch tags for all videos
 	var videoTags []*db.GetVideoTagsForVideosRow
 	if len(videoIDs) > 0 {
-		videoTags, _ = h.dbService.Queries.GetVideoTagsForVideos(ctx, videoIDs)
+		videoTags, _ = queries.GetVideoTagsForVideos(ctx, videoIDs)
 	}
 
 	// Group tags by video_id
@@ -280,35 +392,142 @@ func (h *VideosHandler) List(w http.ResponseWriter, r *http.Request) {
 		})
 	}
 
-	response := ListVideosResponse{
-		Videos: make([]VideoResponse, 0, len(videos)),
-	}
-
+	responses := make([]VideoResponse, 0, len(videos))
 	for _, video := range videos {
-		createdAt := ""
-		if video.CreatedAt.Valid {
-			createdAt = video.CreatedAt.Time.Format(time.RFC3339)
-		}
-
 		tags := tagsByVideoID[video.ID]
 		if tags == nil {
 			tags = []TagInfo{}
 		}
+		responses = append(responses, newVideoResponse(video, tags))
+	}
+	return responses
+}
 
-		response.Videos = append(response.Videos, VideoResponse{
-			ID:            video.ID,
-			VideoID:       video.VideoID,
-			NormalizedURL: video.NormalizedUrl,
-			OriginalURL:   video.OriginalUrl,
-			Title:         video.Title,
-			Channel:       video.Channel,
-			UserID:        video.UserID,
-			CreatedAt:     createdAt,
-			Tags:          tags,
-		})
+// newVideoResponse converts a video to its response
+func newVideoResponse(video *db.Video, tags []TagInfo) VideoResponse {
+	createdAt := ""
+	if video.CreatedAt.Valid {
+		createdAt = video.CreatedAt.Time.Format(time.RFC3339)
 	}
 
-	httpx.RespondJSON(w, http.StatusOK, response)
+	return VideoResponse{
+		ID:                video.ID,
+		VideoID:           video.VideoID,
+		NormalizedURL:     video.NormalizedUrl,
+		OriginalURL:       video.OriginalUrl,
+		Title:             video.Title,
+		Channel:           video.Channel,
+		ChannelID:         video.ChannelID,
+		Platform:          video.Platform,
+		StartSeconds:      video.StartSeconds,
+		UserID:            video.UserID,
+		CreatedAt:         createdAt,
+		Tags:              tags,
+		ThumbnailURL:      video.ThumbnailUrl,
+		DurationSeconds:   video.DurationSeconds,
+		PublishedAt:       formatOptionalTime(video.PublishedAt),
+		ViewCount:         video.ViewCount,
+		ChannelHandle:     video.ChannelHandle,
+		ChannelExternalID: video.ChannelExternalID,
+		MetadataStatus:    video.MetadataStatus,
+		CachedThumbnails:  cachedThumbnails(video.ThumbnailHash),
+
+		Availability:       video.Availability,
+		AvailabilityReason: video.AvailabilityReason,
+		LastCheckedAt:      formatOptionalTime(video.LastCheckedAt),
+	}
+}
+
+// videoSortKey returns a function giving a listed video's key in a sort field
+// Titles and channels are keyed by the lower-cased text the database sorted by; relevance is the
+// search rank, and position the place in a playlist.
+func videoSortKey(field string) func(*db.VideoQueryRow) pagination.Key {
+	return func(row *db.VideoQueryRow) pagination.Key {
+		key := pagination.Key{ID: row.Video.ID}
+		switch field {
+		case sortTitle, sortChannel:
+			key.Text = row.SortText
+		case sortRelevance:
+			key.Number = float64(row.Rank)
+		case sortPosition:
+			key.Number = float64(row.Position)
+		default:
+			key.Number = float64(row.Video.CreatedAt.Time.UnixMicro())
+		}
+		return key
+	}
+}
+
+// pageLimit returns the page size of a list request: its limit, DefaultLimit if it only has a
+// cursor, or 0 for no pages if it has neither
+func pageLimit(r *http.Request) (int, error) {
+	if r.URL.Query().Get("limit") == "" && r.URL.Query().Get("cursor") == "" {
+		return 0, nil
+	}
+	return pagination.ParseLimit(r.URL.Query().Get("limit"))
+}
+
+// videoKey converts the key of a video list cursor to a db.VideoKey, or returns nil if there is none
+// The cursor's number is the created_at time in microseconds, the search rank or the playlist position.
+func videoKey(after *pagination.Key) *db.VideoKey {
+	if after == nil {
+		return nil
+	}
+	return &db.VideoKey{
+		CreatedAt: time.UnixMicro(int64(after.Number)),
+		Text:      after.Text,
+		Rank:      float32(after.Number),
+		Position:  int32(after.Number),
+		ID:        after.ID,
+	}
+}
+
+// optionalCursor returns a pointer to a cursor, or nil on the last page
+func optionalCursor(cursor string) *string {
+	if cursor == "" {
+		return nil
+	}
+	return &cursor
+}
+
+// parseDate parses a date range bound, either a date (midnight UTC) or an RFC 3339 time
+func parseDate(value string) (time.Time, error) {
+	if date, err := time.Parse(time.DateOnly, value); err == nil {
+		return date, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// highlightStart and highlightStop mark the words that matched a search in ts_headline's output
+// They are private use characters, so they survive HTML escaping and don't occur in titles.
+const (
+	highlightStart = "\uE000"
+	highlightStop  = "\uE001"
+)
+
+// headlineOptions makes ts_headline return the whole text with the matches marked
+var headlineOptions = "StartSel=" + highlightStart + ", StopSel=" + highlightStop + ", HighlightAll=true"
+
+// highlightHTML escapes a ts_headline result and turns its marks into <mark> tags
+func highlightHTML(headline string) string {
+	return strings.NewReplacer(highlightStart, "<mark>", highlightStop, "</mark>").Replace(html.EscapeString(headline))
+}
+
+// cachedThumbnails returns the URLs of a video's cached thumbnail, or nil if it isn't cached
+func cachedThumbnails(hash *string) map[string]string {
+	if hash == nil {
+		return nil
+	}
+	return thumbnails.URLs(*hash)
+}
+
+// formatOptionalTime formats a nullable timestamp as RFC3339, or nil when it is NULL
+func formatOptionalTime(t pgtype.Timestamptz) *string {
+	if !t.Valid {
+		return nil
+	}
+	formatted := t.Time.Format(time.RFC3339)
+	return &formatted
 }
 
 type DeleteVideoRequest struct {
@@ -343,8 +562,8 @@ func (h *VideosHandler) Delete(w http.ResponseWriter, r *http.Request) {
 
 	// Delete videos
 	err := h.dbService.Queries.DeleteVideos(ctx, &db.DeleteVideosParams{
-		Column1: req.VideoIDs,
-		UserID:  userID,
+		Ids:    req.VideoIDs,
+		UserID: userID,
 	})
 
 	if err != nil {
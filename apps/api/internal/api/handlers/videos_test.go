@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/ekkolyth/ekko-playlist/api/internal/api/pagination"
+	"github.com/ekkolyth/ekko-playlist/api/internal/db"
+)
+
+func TestPageLimit(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    int
+		wantErr bool
+	}{
+		// Clients that don't page get every video
+		{"", 0, false},
+		{"?sort=title", 0, false},
+		{"?limit=10", 10, false},
+		{"?cursor=abc", pagination.DefaultLimit, false},
+		{"?limit=0", 0, true},
+		{"?limit=1000&cursor=abc", 0, true},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/api/videos"+tt.query, nil)
+		got, err := pageLimit(r)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("pageLimit(%q) = %d, %v; want %d, error %t", tt.query, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestVideoSortKey(t *testing.T) {
+	createdAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	row := &db.VideoQueryRow{
+		Video:    &db.Video{ID: 7, Title: "İstanbul", Channel: "ÇAĞRI", CreatedAt: pgtype.Timestamptz{Time: createdAt, Valid: true}},
+		Rank:     0.5,
+		Position: 3,
+		// As lower-cased by the database, which Go's strings.ToLower doesn't always match
+		SortText: "i̇stanbul",
+	}
+
+	tests := map[string]pagination.Key{
+		sortTitle:     {Text: "i̇stanbul", ID: 7},
+		sortChannel:   {Text: "i̇stanbul", ID: 7},
+		sortRelevance: {Number: 0.5, ID: 7},
+		sortPosition:  {Number: 3, ID: 7},
+		sortCreatedAt: {Number: float64(createdAt.UnixMicro()), ID: 7},
+	}
+	for field, want := range tests {
+		if got := videoSortKey(field)(row); got != want {
+			t.Errorf("videoSortKey(%s) = %+v, want %+v", field, got, want)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package httpserver
 import (
 	"net/http"
 	"os"
+	"slices"
 	"strings"
 	"time"
 
@@ -12,18 +13,24 @@ import (
 
 	"github.com/ekkolyth/ekko-playlist/api/internal/api/auth"
 	"github.com/ekkolyth/ekko-playlist/api/internal/api/handlers"
+	"github.com/ekkolyth/ekko-playlist/api/internal/api/idempotency"
 	"github.com/ekkolyth/ekko-playlist/api/internal/db"
+	"github.com/ekkolyth/ekko-playlist/api/internal/ingest"
 	"github.com/ekkolyth/ekko-playlist/api/internal/lua"
+	"github.com/ekkolyth/ekko-playlist/api/internal/scripts"
 )
 
-func NewRouter(dbService *db.Service, luaService *lua.Service) http.Handler {
+// streamingRoutes run for as long as the client keeps sending, so they manage their own timeouts
+var streamingRoutes = []string{"/api/process/stream"}
+
+func NewRouter(dbService *db.Service, luaService *lua.Service, ingestService *ingest.Service, jobs *ingest.WorkerPool, scriptsService *scripts.Service, playlistFetcher ingest.PlaylistFetcher) http.Handler {
 	router := chi.NewRouter()
 
 	// standard middleware
 	router.Use(middleware.RequestID)
 	router.Use(middleware.RealIP)
 	router.Use(middleware.Recoverer)
-	router.Use(middleware.Timeout(15 * time.Second))
+	router.Use(timeoutExcept(15*time.Second, streamingRoutes...))
 
 	allowedOrigins := envList("CORS_ALLOWED_ORIGINS")
 
@@ -52,13 +59,15 @@ func NewRouter(dbService *db.Service, luaService *lua.Service) http.Handler {
 			"Authorization",
 			"Origin",
 			"Referer",
+			idempotency.HeaderName,
 		},
 		ExposedHeaders: []string{"Location",
 			"X-Request-ID",
 			"Retry-After",
 			"RateLimit-Limit",
 			"RateLimit-Remaining",
-			"RateLimit-Reset"},
+			"RateLimit-Reset",
+			idempotency.ReplayedHeader},
 		AllowCredentials: true,
 		MaxAge:           1800, // 1 Hour
 	}))
@@ -88,6 +97,9 @@ func NewRouter(dbService *db.Service, luaService *lua.Service) http.Handler {
 	// Create auth middleware
 	authMiddleware := auth.AuthMiddleware(dbService)
 
+	// Idempotency-Key support for mutations clients may retry; must come after authMiddleware
+	idempotent := idempotency.Middleware(dbService)
+
 	// Token management routes - require authentication
 	tokensHandler := handlers.NewTokensHandler(dbService)
 	router.Route("/api/tokens", func(tokens chi.Router) {
@@ -129,18 +141,22 @@ func NewRouter(dbService *db.Service, luaService *lua.Service) http.Handler {
 
 	// Upload routes - require authentication
 	uploadHandler := handlers.NewUploadHandler()
+	thumbnailsHandler := handlers.NewThumbnailsHandler(dbService)
 	router.Route("/api/uploads", func(uploads chi.Router) {
 		uploads.Use(authMiddleware)
 		uploads.Get("/{filename}", uploadHandler.ServeFile)
+		uploads.Get("/thumbnails/{filename}", thumbnailsHandler.Serve)
 	})
 
 	router.Route("/api", func(api chi.Router) {
 		// Process routes (playlist and video) - require authentication
-		processHandler := handlers.NewProcessHandler(luaService, dbService)
+		processHandler := handlers.NewProcessHandler(ingestService, jobs, dbService, playlistFetcher)
 		api.Route("/process", func(process chi.Router) {
 			process.Use(authMiddleware)
-			process.Post("/playlist", processHandler.Playlist)
-			process.Post("/video", processHandler.Video)
+			process.With(idempotent).Post("/playlist", processHandler.Playlist)
+			process.With(idempotent).Post("/video", processHandler.Video)
+			process.Post("/stream", processHandler.Stream)
+			process.Get("/jobs/{id}", processHandler.Job)
 		})
 
 		// Videos routes - require authentication
@@ -148,9 +164,19 @@ func NewRouter(dbService *db.Service, luaService *lua.Service) http.Handler {
 		api.Route("/videos", func(videos chi.Router) {
 			videos.Use(authMiddleware)
 			videos.Get("/", videosHandler.List)
+			videos.Get("/{id}", videosHandler.Get)
 			videos.Delete("/", videosHandler.Delete)
 		})
 
+		// Channels routes - require authentication
+		channelsHandler := handlers.NewChannelsHandler(dbService)
+		api.Route("/channels", func(channels chi.Router) {
+			channels.Use(authMiddleware)
+			channels.Get("/", channelsHandler.List)
+			channels.Get("/{id}/videos", channelsHandler.Videos)
+			channels.Post("/{id}/merge", channelsHandler.Merge)
+		})
+
 		// Playlists routes - require authentication
 		playlistsHandler := handlers.NewPlaylistsHandler(dbService)
 		api.Route("/playlists", func(playlists chi.Router) {
@@ -166,8 +192,8 @@ func NewRouter(dbService *db.Service, luaService *lua.Service) http.Handler {
 		playlistVideosHandler := handlers.NewPlaylistVideosHandler(dbService)
 		api.Route("/playlists/{id}/videos", func(playlistVideos chi.Router) {
 			playlistVideos.Use(authMiddleware)
-			playlistVideos.Post("/bulk", playlistVideosHandler.BulkAddVideos)
-			playlistVideos.Post("/", playlistVideosHandler.AddVideo)
+			playlistVideos.With(idempotent).Post("/bulk", playlistVideosHandler.BulkAddVideos)
+			playlistVideos.With(idempotent).Post("/", playlistVideosHandler.AddVideo)
 			playlistVideos.Delete("/{videoId}", playlistVideosHandler.RemoveVideo)
 		})
 
@@ -190,6 +216,21 @@ func NewRouter(dbService *db.Service, luaService *lua.Service) http.Handler {
 			preferences.Get("/", preferencesHandler.Get)
 			preferences.Patch("/", preferencesHandler.Update)
 		})
+
+		// Admin script routes - require authentication as one of ADMIN_USER_IDS
+		// Script names contain slashes, so {name} is URL-encoded (normalizers%2Fyoutube.lua)
+		scriptsHandler := handlers.NewScriptsHandler(scriptsService, luaService)
+		adminMiddleware := auth.AdminMiddleware(envList("ADMIN_USER_IDS"))
+		api.Route("/admin/scripts", func(adminScripts chi.Router) {
+			adminScripts.Use(authMiddleware)
+			adminScripts.Use(adminMiddleware)
+			adminScripts.Get("/", scriptsHandler.List)
+			adminScripts.Get("/{name}", scriptsHandler.Get)
+			adminScripts.Post("/{name}/versions", scriptsHandler.CreateVersion)
+			adminScripts.Post("/{name}/versions/{version}/activate", scriptsHandler.Activate)
+			adminScripts.Delete("/{name}/active", scriptsHandler.Deactivate)
+			adminScripts.Post("/{name}/dry-run", scriptsHandler.DryRun)
+		})
 	})
 
 	return router
@@ -206,3 +247,17 @@ func envList(key string) []string {
 	}
 	return parts
 }
+
+// timeoutExcept applies middleware.Timeout to every request except those for the given paths
+func timeoutExcept(timeout time.Duration, paths ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		withTimeout := middleware.Timeout(timeout)(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if slices.Contains(paths, r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			withTimeout.ServeHTTP(w, r)
+		})
+	}
+}
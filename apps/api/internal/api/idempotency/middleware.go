@@ -0,0 +1,305 @@
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/ekkolyth/ekko-playlist/api/internal/api/auth"
+	"github.com/ekkolyth/ekko-playlist/api/internal/api/httpx"
+	"github.com/ekkolyth/ekko-playlist/api/internal/db"
+	"github.com/ekkolyth/ekko-playlist/api/internal/logging"
+)
+
+// HeaderName is the request header carrying the client's idempotency key
+const HeaderName = "Idempotency-Key"
+
+// ReplayedHeader is set on responses replayed from a stored request
+const ReplayedHeader = "Idempotent-Replayed"
+
+const (
+	// maxKeyLength caps the length of an idempotency key
+	maxKeyLength = 255
+	// maxRequestBody caps the request bodies that are hashed (the playlist limit)
+	maxRequestBody = 10 << 20
+	// maxResponseBody caps the responses that are stored; larger responses aren't replayable
+	maxResponseBody = 1 << 20
+	// cleanupInterval is how often expired keys are deleted
+	cleanupInterval = time.Hour
+	// inProgressRetryAfter is the Retry-After sent while the original request is still running
+	inProgressRetryAfter = 1
+)
+
+// replayedHeaders are the response headers stored and replayed besides Content-Type
+var replayedHeaders = []string{"Location", "Content-Location", "ETag", "Last-Modified"}
+
+// keyStore stores idempotency keys and their responses; *db.Queries implements it
+type keyStore interface {
+	ClaimIdempotencyKey(ctx context.Context, arg *db.ClaimIdempotencyKeyParams) (*db.IdempotencyKey, error)
+	GetIdempotencyKey(ctx context.Context, arg *db.GetIdempotencyKeyParams) (*db.IdempotencyKey, error)
+	CompleteIdempotencyKey(ctx context.Context, arg *db.CompleteIdempotencyKeyParams) error
+	DeleteIdempotencyKey(ctx context.Context, arg *db.DeleteIdempotencyKeyParams) error
+	DeleteExpiredIdempotencyKeys(ctx context.Context) (int64, error)
+}
+
+// Middleware makes a route idempotent for requests sent with an Idempotency-Key header
+// The first request with a key runs normally and its response is stored. Replays of the same
+// key by the same user within 24 hours get the stored response, with its replayedHeaders and
+// Idempotent-Replayed: true, instead of running again. Reusing a key for a different request is rejected with 422, and a
+// replay that arrives while the original is still running gets 409.
+// Server errors (5xx) aren't stored, so those requests can be retried with the same key.
+// Requests without the header are passed through untouched. Must run after auth.AuthMiddleware.
+func Middleware(dbService *db.Service) func(http.Handler) http.Handler {
+	return middleware(dbService.Queries)
+}
+
+func middleware(store keyStore) func(http.Handler) http.Handler {
+	var lastCleanup atomic.Int64
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(HeaderName)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			userID, ok := auth.GetUserID(r.Context())
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if len(key) > maxKeyLength {
+				httpx.RespondError(w, http.StatusBadRequest, HeaderName+" must be at most "+strconv.Itoa(maxKeyLength)+" characters")
+				return
+			}
+
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBody))
+			if err != nil {
+				httpx.RespondError(w, http.StatusRequestEntityTooLarge, "Request body too large")
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			requestHash := hashRequest(r, body)
+
+			ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+			defer cancel()
+
+			_, err = store.ClaimIdempotencyKey(ctx, &db.ClaimIdempotencyKeyParams{
+				UserID:      userID,
+				Key:         key,
+				Method:      r.Method,
+				Path:        r.URL.Path,
+				RequestHash: requestHash,
+			})
+			if errors.Is(err, pgx.ErrNoRows) {
+				// The key is in use; replay or reject
+				replay(ctx, w, store, userID, key, requestHash)
+				return
+			}
+			if err != nil {
+				logging.Info("Idempotency: Failed to claim key: %s", err.Error())
+				httpx.RespondError(w, http.StatusInternalServerError, "Failed to process idempotency key")
+				return
+			}
+
+			recorder := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+			completed := false
+			defer func() {
+				// Release the key if the handler panicked, so the request can be retried
+				if !completed {
+					release(store, userID, key)
+				}
+			}()
+
+			next.ServeHTTP(recorder, r)
+			completed = true
+
+			storeCtx, storeCancel := context.WithTimeout(context.WithoutCancel(r.Context()), 5*time.Second)
+			defer storeCancel()
+
+			if recorder.status >= http.StatusInternalServerError || recorder.overflow {
+				release(store, userID, key)
+				return
+			}
+
+			status := int32(recorder.status)
+			contentType := recorder.Header().Get("Content-Type")
+			err = store.CompleteIdempotencyKey(storeCtx, &db.CompleteIdempotencyKeyParams{
+				UserID:          userID,
+				Key:             key,
+				StatusCode:      &status,
+				ContentType:     &contentType,
+				ResponseBody:    recorder.body.Bytes(),
+				ResponseHeaders: encodeHeaders(recorder.Header()),
+			})
+			if err != nil {
+				logging.Info("Idempotency: Failed to store response: %s", err.Error())
+				release(store, userID, key)
+			}
+
+			cleanup(store, &lastCleanup)
+		})
+	}
+}
+
+// replay answers a request whose key is already in use
+func replay(ctx context.Context, w http.ResponseWriter, store keyStore, userID, key, requestHash string) {
+	stored, err := store.GetIdempotencyKey(ctx, &db.GetIdempotencyKeyParams{
+		UserID: userID,
+		Key:    key,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			// Released between the claim and the lookup
+			w.Header().Set("Retry-After", strconv.Itoa(inProgressRetryAfter))
+			httpx.RespondError(w, http.StatusConflict, "A request with this "+HeaderName+" is in progress")
+			return
+		}
+		logging.Info("Idempotency: Failed to load key: %s", err.Error())
+		httpx.RespondError(w, http.StatusInternalServerError, "Failed to process idempotency key")
+		return
+	}
+
+	if stored.RequestHash != requestHash {
+		httpx.RespondError(w, http.StatusUnprocessableEntity, HeaderName+" was already used for a different request")
+		return
+	}
+
+	if stored.StatusCode == nil {
+		w.Header().Set("Retry-After", strconv.Itoa(inProgressRetryAfter))
+		httpx.RespondError(w, http.StatusConflict, "A request with this "+HeaderName+" is in progress")
+		return
+	}
+
+	if stored.ContentType != nil && *stored.ContentType != "" {
+		w.Header().Set("Content-Type", *stored.ContentType)
+	}
+	for name, values := range decodeHeaders(stored.ResponseHeaders) {
+		w.Header()[name] = values
+	}
+	w.Header().Set(ReplayedHeader, "true")
+	w.WriteHeader(int(*stored.StatusCode))
+	_, _ = w.Write(stored.ResponseBody)
+}
+
+// release deletes a claimed key so the request can be retried
+func release(store keyStore, userID, key string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := store.DeleteIdempotencyKey(ctx, &db.DeleteIdempotencyKeyParams{
+		UserID: userID,
+		Key:    key,
+	}); err != nil {
+		logging.Info("Idempotency: Failed to release key: %s", err.Error())
+	}
+}
+
+// cleanup deletes expired keys in the background, at most once per cleanupInterval
+func cleanup(store keyStore, lastCleanup *atomic.Int64) {
+	now := time.Now().Unix()
+	last := lastCleanup.Load()
+	if now-last < int64(cleanupInterval/time.Second) || !lastCleanup.CompareAndSwap(last, now) {
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		deleted, err := store.DeleteExpiredIdempotencyKeys(ctx)
+		if err != nil {
+			logging.Info("Idempotency: Failed to delete expired keys: %s", err.Error())
+			return
+		}
+		if deleted > 0 {
+			logging.Info("Idempotency: Deleted %d expired keys", deleted)
+		}
+	}()
+}
+
+// encodeHeaders returns the replayedHeaders set in header as JSON, or nil if there are none
+func encodeHeaders(header http.Header) []byte {
+	stored := make(http.Header)
+	for _, name := range replayedHeaders {
+		if values := header.Values(name); len(values) > 0 {
+			stored[name] = values
+		}
+	}
+	if len(stored) == 0 {
+		return nil
+	}
+	encoded, err := json.Marshal(stored)
+	if err != nil {
+		return nil
+	}
+	return encoded
+}
+
+// decodeHeaders returns the headers stored by encodeHeaders
+func decodeHeaders(data []byte) http.Header {
+	var header http.Header
+	if len(data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		logging.Info("Idempotency: Failed to decode stored headers: %s", err.Error())
+		return nil
+	}
+	return header
+}
+
+// hashRequest identifies a request by its method, path, query and body
+func hashRequest(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(r.Method + " " + r.URL.RequestURI() + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// responseRecorder passes a response through while keeping a copy of it
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+	// overflow is set once the response is too large to store
+	overflow bool
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if r.wroteHeader {
+		return
+	}
+	r.status = status
+	r.wroteHeader = true
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(p []byte) (int, error) {
+	if !r.wroteHeader {
+		r.WriteHeader(http.StatusOK)
+	}
+	if !r.overflow {
+		if r.body.Len()+len(p) > maxResponseBody {
+			r.overflow = true
+			r.body.Reset()
+		} else {
+			r.body.Write(p)
+		}
+	}
+	return r.ResponseWriter.Write(p)
+}
@@ -0,0 +1,193 @@
+package idempotency
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/ekkolyth/ekko-playlist/api/internal/api/auth"
+	"github.com/ekkolyth/ekko-playlist/api/internal/db"
+)
+
+// memoryStore is a keyStore keeping keys in memory; keys never expire
+type memoryStore struct {
+	mu   sync.Mutex
+	keys map[string]*db.IdempotencyKey
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{keys: make(map[string]*db.IdempotencyKey)}
+}
+
+func (s *memoryStore) ClaimIdempotencyKey(ctx context.Context, arg *db.ClaimIdempotencyKeyParams) (*db.IdempotencyKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.keys[arg.UserID+"/"+arg.Key]; ok {
+		return nil, pgx.ErrNoRows
+	}
+	key := &db.IdempotencyKey{UserID: arg.UserID, Key: arg.Key, Method: arg.Method, Path: arg.Path, RequestHash: arg.RequestHash}
+	s.keys[arg.UserID+"/"+arg.Key] = key
+	return key, nil
+}
+
+func (s *memoryStore) GetIdempotencyKey(ctx context.Context, arg *db.GetIdempotencyKeyParams) (*db.IdempotencyKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key, ok := s.keys[arg.UserID+"/"+arg.Key]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+	stored := *key
+	return &stored, nil
+}
+
+func (s *memoryStore) CompleteIdempotencyKey(ctx context.Context, arg *db.CompleteIdempotencyKeyParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if key, ok := s.keys[arg.UserID+"/"+arg.Key]; ok {
+		key.StatusCode = arg.StatusCode
+		key.ContentType = arg.ContentType
+		key.ResponseBody = arg.ResponseBody
+		key.ResponseHeaders = arg.ResponseHeaders
+	}
+	return nil
+}
+
+func (s *memoryStore) DeleteIdempotencyKey(ctx context.Context, arg *db.DeleteIdempotencyKeyParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.keys, arg.UserID+"/"+arg.Key)
+	return nil
+}
+
+func (s *memoryStore) DeleteExpiredIdempotencyKeys(ctx context.Context) (int64, error) {
+	return 0, nil
+}
+
+// queueHandler answers like the playlist import, counting how many times it ran
+type queueHandler struct {
+	calls  int
+	status int
+}
+
+func (h *queueHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.calls++
+	body, _ := io.ReadAll(r.Body)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/api/jobs/job-1")
+	w.Header().Set("X-Request-ID", "not replayed")
+	w.WriteHeader(h.status)
+	_, _ = w.Write([]byte(`{"received":` + string(body) + `}`))
+}
+
+func send(t *testing.T, handler http.Handler, userID, key, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodPost, "/api/process/playlist", strings.NewReader(body))
+	if key != "" {
+		r.Header.Set(HeaderName, key)
+	}
+	r = r.WithContext(auth.WithUser(r.Context(), userID, userID+"@example.com"))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+func TestMiddlewareReplay(t *testing.T) {
+	next := &queueHandler{status: http.StatusAccepted}
+	handler := middleware(newMemoryStore())(next)
+
+	first := send(t, handler, "user", "key-1", `{"videos":[]}`)
+	if first.Code != http.StatusAccepted || first.Header().Get("Location") != "/api/jobs/job-1" {
+		t.Fatalf("first response = %d with Location %q", first.Code, first.Header().Get("Location"))
+	}
+	if first.Header().Get(ReplayedHeader) != "" {
+		t.Errorf("first response is marked replayed")
+	}
+
+	replayed := send(t, handler, "user", "key-1", `{"videos":[]}`)
+	if next.calls != 1 {
+		t.Fatalf("handler ran %d times, want once", next.calls)
+	}
+	if replayed.Code != http.StatusAccepted || replayed.Body.String() != first.Body.String() {
+		t.Errorf("replay = %d %q, want %d %q", replayed.Code, replayed.Body.String(), first.Code, first.Body.String())
+	}
+	for name, want := range map[string]string{
+		"Location":     "/api/jobs/job-1",
+		"Content-Type": "application/json",
+		ReplayedHeader: "true",
+		"X-Request-ID": "",
+	} {
+		if got := replayed.Header().Get(name); got != want {
+			t.Errorf("replayed %s = %q, want %q", name, got, want)
+		}
+	}
+
+	// Keys belong to a user
+	if other := send(t, handler, "other", "key-1", `{"videos":[]}`); other.Header().Get(ReplayedHeader) != "" || next.calls != 2 {
+		t.Errorf("another user's request with the same key was replayed")
+	}
+}
+
+func TestMiddlewareKeyConflict(t *testing.T) {
+	next := &queueHandler{status: http.StatusAccepted}
+	handler := middleware(newMemoryStore())(next)
+
+	send(t, handler, "user", "key-1", `{"videos":[1]}`)
+	conflict := send(t, handler, "user", "key-1", `{"videos":[2]}`)
+	if conflict.Code != http.StatusUnprocessableEntity {
+		t.Errorf("status = %d, want %d", conflict.Code, http.StatusUnprocessableEntity)
+	}
+	if next.calls != 1 {
+		t.Errorf("handler ran %d times, want once", next.calls)
+	}
+}
+
+func TestMiddlewareInProgress(t *testing.T) {
+	store := newMemoryStore()
+	handler := middleware(store)(&queueHandler{status: http.StatusAccepted})
+
+	// A claimed key without a response belongs to a request that is still running
+	body := `{"videos":[]}`
+	r := httptest.NewRequest(http.MethodPost, "/api/process/playlist", strings.NewReader(body))
+	if _, err := store.ClaimIdempotencyKey(context.Background(), &db.ClaimIdempotencyKeyParams{
+		UserID: "user", Key: "key-1", RequestHash: hashRequest(r, []byte(body)),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	w := send(t, handler, "user", "key-1", body)
+	if w.Code != http.StatusConflict || w.Header().Get("Retry-After") == "" {
+		t.Errorf("status = %d, Retry-After = %q, want 409 with Retry-After", w.Code, w.Header().Get("Retry-After"))
+	}
+}
+
+func TestMiddlewareServerErrorsAreRetried(t *testing.T) {
+	next := &queueHandler{status: http.StatusInternalServerError}
+	handler := middleware(newMemoryStore())(next)
+
+	send(t, handler, "user", "key-1", `{}`)
+	next.status = http.StatusAccepted
+	if w := send(t, handler, "user", "key-1", `{}`); w.Code != http.StatusAccepted || w.Header().Get(ReplayedHeader) != "" {
+		t.Errorf("retry = %d, replayed %q, want the request to run again", w.Code, w.Header().Get(ReplayedHeader))
+	}
+	if next.calls != 2 {
+		t.Errorf("handler ran %d times, want twice", next.calls)
+	}
+}
+
+func TestMiddlewareWithoutKey(t *testing.T) {
+	next := &queueHandler{status: http.StatusAccepted}
+	handler := middleware(newMemoryStore())(next)
+
+	send(t, handler, "user", "", `{}`)
+	send(t, handler, "user", "", `{}`)
+	if next.calls != 2 {
+		t.Errorf("handler ran %d times, want twice", next.calls)
+	}
+}
@@ -0,0 +1,96 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DefaultLimit is the page size when a request doesn't give one
+	DefaultLimit = 50
+	// MaxLimit is the largest page size a request can ask for
+	MaxLimit = 200
+)
+
+// ErrInvalidCursor is returned for cursors that are malformed or were made for another sort
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// ParseLimit parses a limit query parameter, returning DefaultLimit if it is empty
+func ParseLimit(value string) (int, error) {
+	if value == "" {
+		return DefaultLimit, nil
+	}
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit < 1 || limit > MaxLimit {
+		return 0, fmt.Errorf("limit must be a number from 1 to %d", MaxLimit)
+	}
+	return limit, nil
+}
+
+// Sort is the order of a list: a field, ascending unless Desc
+type Sort struct {
+	Field string
+	Desc  bool
+}
+
+// ParseSort parses a sort query parameter such as "title", or "-created_at" for descending
+// Only the given fields are accepted; an empty value returns def.
+func ParseSort(value string, fields []string, def Sort) (Sort, error) {
+	if value == "" {
+		return def, nil
+	}
+	sort := Sort{Field: strings.TrimPrefix(value, "-"), Desc: strings.HasPrefix(value, "-")}
+	if !slices.Contains(fields, sort.Field) {
+		return Sort{}, fmt.Errorf("sort must be one of %s, optionally prefixed with - for descending", strings.Join(fields, ", "))
+	}
+	return sort, nil
+}
+
+func (s Sort) String() string {
+	if s.Desc {
+		return "-" + s.Field
+	}
+	return s.Field
+}
+
+// Key is an item's place in a sort order: the value of the sorted field, as text or as a number,
+// then the item's ID to order items with the same value
+type Key struct {
+	Text   string  `json:"t,omitempty"`
+	Number float64 `json:"n,omitempty"`
+	ID     int64   `json:"id"`
+}
+
+// cursor is what a cursor encodes: the sort it belongs to and the key of the last item before it
+type cursor struct {
+	Sort string `json:"s"`
+	Key
+}
+
+// EncodeCursor returns the opaque cursor of the items after key in sort
+func EncodeCursor(sort Sort, key Key) string {
+	data, _ := json.Marshal(cursor{Sort: sort.String(), Key: key})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor returns the key a cursor continues after, or nil for an empty cursor
+// A cursor only continues the sort it was made for.
+func DecodeCursor(value string, sort Sort) (*Key, error) {
+	if value == "" {
+		return nil, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var decoded cursor
+	if err := json.Unmarshal(data, &decoded); err != nil || decoded.Sort != sort.String() {
+		return nil, ErrInvalidCursor
+	}
+	return &decoded.Key, nil
+}
@@ -0,0 +1,60 @@
+package pagination
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCursor(t *testing.T) {
+	sort := Sort{Field: "created_at", Desc: true}
+	key := Key{Number: 1718000000123456, ID: 42}
+
+	got, err := DecodeCursor(EncodeCursor(sort, key), sort)
+	if err != nil || *got != key {
+		t.Errorf("DecodeCursor(EncodeCursor(%v)) = %v, %v", key, got, err)
+	}
+
+	if got, err := DecodeCursor("", sort); got != nil || err != nil {
+		t.Errorf("DecodeCursor(\"\") = %v, %v, want nil, nil", got, err)
+	}
+	for _, value := range []string{"not base64!", "bm90IGpzb24", EncodeCursor(Sort{Field: "created_at"}, key)} {
+		if _, err := DecodeCursor(value, sort); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("DecodeCursor(%q) = %v, want ErrInvalidCursor", value, err)
+		}
+	}
+}
+
+func TestParseLimit(t *testing.T) {
+	cases := map[string]int{"": DefaultLimit, "1": 1, "200": 200, "0": 0, "201": 0, "-5": 0, "ten": 0}
+	for value, want := range cases {
+		got, err := ParseLimit(value)
+		if want == 0 && err == nil {
+			t.Errorf("ParseLimit(%q) = %d, want an error", value, got)
+		}
+		if want != 0 && (err != nil || got != want) {
+			t.Errorf("ParseLimit(%q) = %d, %v, want %d", value, got, err, want)
+		}
+	}
+}
+
+func TestParseSort(t *testing.T) {
+	fields := []string{"created_at", "title"}
+	def := Sort{Field: "created_at", Desc: true}
+	cases := map[string]Sort{
+		"":            def,
+		"title":       {Field: "title"},
+		"-title":      {Field: "title", Desc: true},
+		"created_at":  {Field: "created_at"},
+		"-created_at": {Field: "created_at", Desc: true},
+	}
+	for value, want := range cases {
+		if got, err := ParseSort(value, fields, def); err != nil || got != want {
+			t.Errorf("ParseSort(%q) = %v, %v, want %v", value, got, err, want)
+		}
+	}
+	for _, value := range []string{"position", "--title", "title-"} {
+		if _, err := ParseSort(value, fields, def); err == nil {
+			t.Errorf("ParseSort(%q) succeeded, want an error", value)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package availability
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Availability values, as stored in videos.availability
+const (
+	StatusUnknown     = "unknown"
+	StatusAvailable   = "available"
+	StatusUnavailable = "unavailable"
+)
+
+// Reasons a video is unavailable
+const (
+	ReasonRemoved = "removed"
+	ReasonPrivate = "private"
+)
+
+// maxResponseSize caps a checker response
+const maxResponseSize = 1 << 20
+
+// Video identifies a saved video to a checker
+type Video struct {
+	Platform string
+	VideoID  string
+	URL      string
+}
+
+// Result is what a checker found out about a video
+type Result struct {
+	Available bool
+	// Reason says why an unavailable video can't be watched, e.g. ReasonRemoved
+	Reason string
+}
+
+// Checker probes whether saved videos can still be watched
+type Checker interface {
+	// Name identifies the checker in logs
+	Name() string
+	// Supports reports whether the checker knows videos of the platform
+	Supports(platform string) bool
+	// Check returns whether the video is available
+	// An error means the check itself failed and says nothing about the video.
+	Check(ctx context.Context, video Video) (Result, error)
+}
+
+// Chain asks the checkers that support a video in turn until one of them answers
+type Chain []Checker
+
+func (c Chain) Name() string {
+	return "chain"
+}
+
+func (c Chain) Supports(platform string) bool {
+	for _, checker := range c {
+		if checker.Supports(platform) {
+			return true
+		}
+	}
+	return false
+}
+
+// Check returns the first answer; errors are only returned if no checker answered
+func (c Chain) Check(ctx context.Context, video Video) (Result, error) {
+	var errs []error
+	for _, checker := range c {
+		if !checker.Supports(video.Platform) {
+			continue
+		}
+
+		result, err := checker.Check(ctx, video)
+		if err == nil {
+			return result, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", checker.Name(), err))
+	}
+
+	if len(errs) == 0 {
+		return Result{}, fmt.Errorf("no checker supports %s videos", video.Platform)
+	}
+	return Result{}, errors.Join(errs...)
+}
@@ -0,0 +1,247 @@
+package availability
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ekkolyth/ekko-playlist/api/internal/publichttp"
+)
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// newFakePlatform serves an oEmbed endpoint at /oembed and video pages at /watch/{id}, answering
+// with the status set for each video ID
+func newFakePlatform(t *testing.T, statuses map[string]int) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/oembed", func(w http.ResponseWriter, r *http.Request) {
+		videoURL := r.URL.Query().Get("url")
+		for id, status := range statuses {
+			if videoURL == "https://www.youtube.com/watch?v="+id {
+				writeJSON(w, status, map[string]string{"title": id})
+				return
+			}
+		}
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "unknown url"})
+	})
+	mux.HandleFunc("/watch/{id}", func(w http.ResponseWriter, r *http.Request) {
+		status, ok := statuses[r.PathValue("id")]
+		if !ok {
+			status = http.StatusNotFound
+		}
+		w.WriteHeader(status)
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestHTTPCheckerOEmbed(t *testing.T) {
+	server := newFakePlatform(t, map[string]int{
+		"public":       http.StatusOK,
+		"unembeddable": http.StatusUnauthorized,
+		"forbidden":    http.StatusForbidden,
+		"removed":      http.StatusNotFound,
+		"flaky":        http.StatusServiceUnavailable,
+	})
+	checker := NewHTTPChecker()
+	checker.client = server.Client()
+	checker.endpoints = map[string]string{"youtube": server.URL + "/oembed"}
+
+	// oEmbed refusing a video doesn't mean it's private, only that it exists
+	cases := map[string]Result{
+		"public":       {Available: true},
+		"unembeddable": {Available: true},
+		"forbidden":    {Available: true},
+		"removed":      {Reason: ReasonRemoved},
+	}
+	for id, want := range cases {
+		got, err := checker.Check(context.Background(), Video{
+			Platform: "youtube",
+			VideoID:  id,
+			URL:      "https://www.youtube.com/watch?v=" + id,
+		})
+		if err != nil {
+			t.Errorf("Check(%s) failed: %v", id, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("Check(%s) = %+v, want %+v", id, got, want)
+		}
+	}
+
+	if _, err := checker.Check(context.Background(), Video{
+		Platform: "youtube",
+		VideoID:  "flaky",
+		URL:      "https://www.youtube.com/watch?v=flaky",
+	}); err == nil {
+		t.Error("Check(flaky) succeeded, want an error for a 503")
+	}
+}
+
+func TestHTTPCheckerPage(t *testing.T) {
+	server := newFakePlatform(t, map[string]int{
+		"live":    http.StatusOK,
+		"gone":    http.StatusGone,
+		"blocked": http.StatusForbidden,
+	})
+	checker := NewHTTPChecker()
+	checker.client = server.Client()
+	checker.endpoints = map[string]string{}
+
+	cases := map[string]Result{
+		"live":    {Available: true},
+		"gone":    {Reason: ReasonRemoved},
+		"missing": {Reason: ReasonRemoved},
+	}
+	for id, want := range cases {
+		got, err := checker.Check(context.Background(), Video{
+			Platform: "twitch",
+			VideoID:  id,
+			URL:      server.URL + "/watch/" + id,
+		})
+		if err != nil {
+			t.Errorf("Check(%s) failed: %v", id, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("Check(%s) = %+v, want %+v", id, got, want)
+		}
+	}
+
+	// A page refusing the checker says nothing about the video
+	if _, err := checker.Check(context.Background(), Video{
+		Platform: "twitch",
+		VideoID:  "blocked",
+		URL:      server.URL + "/watch/blocked",
+	}); err == nil {
+		t.Error("Check(blocked) succeeded, want an error for a 403 page")
+	}
+}
+
+func TestHTTPCheckerRefusesLocalURLs(t *testing.T) {
+	server := newFakePlatform(t, map[string]int{"live": http.StatusOK})
+	checker := NewHTTPChecker()
+
+	// Federated platforms accept any host, including the API's own network
+	_, err := checker.Check(context.Background(), Video{
+		Platform: "peertube",
+		VideoID:  "live",
+		URL:      server.URL + "/watch/live",
+	})
+	if !errors.Is(err, publichttp.ErrNotPublic) {
+		t.Errorf("Check of a loopback URL = %v, want ErrNotPublic", err)
+	}
+
+	if _, err := checker.Check(context.Background(), Video{
+		Platform: "peertube",
+		VideoID:  "passwd",
+		URL:      "file:///etc/passwd",
+	}); err == nil {
+		t.Error("Check of a file URL succeeded")
+	}
+}
+
+func TestYouTubeChecker(t *testing.T) {
+	statuses := map[string]map[string]string{
+		"public":   {"uploadStatus": "processed", "privacyStatus": "public"},
+		"unlisted": {"uploadStatus": "processed", "privacyStatus": "unlisted"},
+		"private":  {"uploadStatus": "processed", "privacyStatus": "private"},
+		"rejected": {"uploadStatus": "rejected", "privacyStatus": "public", "rejectionReason": "copyright"},
+		"deleted":  {"uploadStatus": "deleted", "privacyStatus": "public"},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if r.URL.Path != "/youtube/v3/videos" || query.Get("key") != "test-key" || query.Get("part") != "status" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "bad request"})
+			return
+		}
+		if query.Get("id") == "quota" {
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": "quotaExceeded"})
+			return
+		}
+		items := []interface{}{}
+		if status, ok := statuses[query.Get("id")]; ok {
+			items = append(items, map[string]interface{}{"status": status})
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"items": items})
+	}))
+	defer server.Close()
+
+	checker := NewYouTubeChecker("test-key")
+	checker.apiURL = server.URL + "/youtube/v3"
+
+	cases := map[string]Result{
+		"public":   {Available: true},
+		"unlisted": {Available: true},
+		"private":  {Reason: ReasonPrivate},
+		"rejected": {Reason: "rejected: copyright"},
+		"deleted":  {Reason: ReasonRemoved},
+		"missing":  {Reason: ReasonRemoved},
+	}
+	for id, want := range cases {
+		got, err := checker.Check(context.Background(), Video{Platform: "youtube", VideoID: id})
+		if err != nil {
+			t.Errorf("Check(%s) failed: %v", id, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("Check(%s) = %+v, want %+v", id, got, want)
+		}
+	}
+
+	// An API error says nothing about the video
+	if _, err := checker.Check(context.Background(), Video{Platform: "youtube", VideoID: "quota"}); err == nil {
+		t.Error("Check(quota) succeeded, want an error")
+	}
+}
+
+// stubChecker answers every check with the same result
+type stubChecker struct {
+	platform string
+	result   Result
+	err      error
+	calls    int
+}
+
+func (s *stubChecker) Name() string                  { return "stub-" + s.platform }
+func (s *stubChecker) Supports(platform string) bool { return platform == s.platform }
+func (s *stubChecker) Check(ctx context.Context, video Video) (Result, error) {
+	s.calls++
+	return s.result, s.err
+}
+
+func TestChain(t *testing.T) {
+	failing := &stubChecker{platform: "youtube", err: errors.New("quota exceeded")}
+	fallback := &stubChecker{platform: "youtube", result: Result{Reason: ReasonRemoved}}
+	unused := &stubChecker{platform: "youtube", result: Result{Available: true}}
+	chain := Chain{failing, fallback, unused}
+
+	got, err := chain.Check(context.Background(), Video{Platform: "youtube", VideoID: "x"})
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if got != (Result{Reason: ReasonRemoved}) {
+		t.Errorf("Check = %+v, want the fallback's result", got)
+	}
+	if failing.calls != 1 || fallback.calls != 1 || unused.calls != 0 {
+		t.Errorf("calls = %d, %d, %d, want 1, 1, 0", failing.calls, fallback.calls, unused.calls)
+	}
+
+	if _, err := (Chain{failing}).Check(context.Background(), Video{Platform: "youtube"}); err == nil {
+		t.Error("Check with only failing checkers succeeded, want an error")
+	}
+	if chain.Supports("vimeo") {
+		t.Error("Supports(vimeo) = true, want false")
+	}
+}
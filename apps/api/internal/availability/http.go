@@ -0,0 +1,87 @@
+package availability
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/ekkolyth/ekko-playlist/api/internal/publichttp"
+)
+
+// oEmbedEndpoints are the oEmbed endpoints of the platforms that have one
+var oEmbedEndpoints = map[string]string{
+	"youtube":     "https://www.youtube.com/oembed",
+	"vimeo":       "https://vimeo.com/api/oembed.json",
+	"dailymotion": "https://www.dailymotion.com/services/oembed",
+	"soundcloud":  "https://soundcloud.com/oembed",
+}
+
+// httpCheckTimeout bounds a single HTTP check
+const httpCheckTimeout = 10 * time.Second
+
+// HTTPChecker probes videos over plain HTTP, needing no API keys
+// Platforms with an oEmbed endpoint are asked through it, since their video pages load for
+// removed videos too; for other platforms the video's page is requested, which only catches
+// videos whose page is gone. Video URLs of federated platforms can name any host, so only
+// public addresses are connected to.
+type HTTPChecker struct {
+	client    *http.Client
+	endpoints map[string]string
+}
+
+// NewHTTPChecker creates an HTTP checker using the known oEmbed endpoints
+func NewHTTPChecker() *HTTPChecker {
+	return &HTTPChecker{
+		client:    publichttp.NewClient(httpCheckTimeout),
+		endpoints: oEmbedEndpoints,
+	}
+}
+
+func (c *HTTPChecker) Name() string {
+	return "http"
+}
+
+func (c *HTTPChecker) Supports(platform string) bool {
+	return true
+}
+
+func (c *HTTPChecker) Check(ctx context.Context, video Video) (Result, error) {
+	target := video.URL
+	endpoint, oEmbed := c.endpoints[video.Platform]
+	if oEmbed {
+		query := url.Values{"url": {video.URL}, "format": {"json"}}
+		target = endpoint + "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return Result{}, err
+	}
+	if req.URL.Scheme != "https" && req.URL.Scheme != "http" {
+		return Result{}, fmt.Errorf("unsupported scheme %q", req.URL.Scheme)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return Result{}, err
+	}
+	defer resp.Body.Close()
+	// Drain what is left of the body so the connection can be reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseSize))
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return Result{Available: true}, nil
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return Result{Reason: ReasonRemoved}, nil
+	case oEmbed && (resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden):
+		// oEmbed endpoints refuse private videos and videos that can't be embedded alike, but
+		// only for videos that exist. Telling them apart takes the Data API checker.
+		return Result{Available: true}, nil
+	default:
+		return Result{}, fmt.Errorf("%s returned %s", req.URL.Host, resp.Status)
+	}
+}
@@ -0,0 +1,192 @@
+package availability
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ekkolyth/ekko-playlist/api/internal/db"
+	"github.com/ekkolyth/ekko-playlist/api/internal/logging"
+)
+
+const (
+	// checkPollInterval is how often the monitor looks for videos that are due a check
+	checkPollInterval = time.Minute
+	// checkBatchSize is how many videos are claimed at a time
+	checkBatchSize = 20
+	// checkLease is how long a claimed video is hidden from other monitors; a video whose
+	// monitor died is picked up again once it runs out
+	checkLease = 5 * time.Minute
+	// checkTimeout bounds the check of one video
+	checkTimeout = 15 * time.Second
+	// checkSpacing is the pause between two checks, so a large library doesn't get us rate limited
+	checkSpacing = time.Second
+	// checkRetryDelay is how long to wait before retrying a check that failed
+	checkRetryDelay = time.Hour
+)
+
+// Monitor re-checks the availability of saved videos in the background
+// Every video is checked once it is saved and again every interval. A result is recorded for
+// every user's copy of the video, so a video saved by many users is checked once.
+type Monitor struct {
+	dbService *db.Service
+	checker   Checker
+	interval  time.Duration
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+}
+
+// NewMonitor creates a monitor that checks videos with checker every interval
+func NewMonitor(dbService *db.Service, checker Checker, interval time.Duration) *Monitor {
+	return &Monitor{
+		dbService: dbService,
+		checker:   checker,
+		interval:  interval,
+	}
+}
+
+// Start starts checking videos that are due
+func (m *Monitor) Start(ctx context.Context) {
+	ctx, m.cancel = context.WithCancel(ctx)
+
+	m.wg.Add(1)
+	go m.run(ctx)
+	logging.Info("Availability: Started monitor, checking videos every %s", m.interval)
+}
+
+// Stop signals the monitor to stop and waits for the current check to finish
+// Videos of an interrupted batch are checked once their lease runs out.
+func (m *Monitor) Stop(ctx context.Context) error {
+	if m.cancel == nil {
+		return nil
+	}
+	m.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		logging.Info("Availability: Monitor stopped")
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("timed out waiting for the availability monitor: %w", ctx.Err())
+	}
+}
+
+func (m *Monitor) run(ctx context.Context) {
+	defer m.wg.Done()
+
+	ticker := time.NewTicker(checkPollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Keep claiming until there is nothing left that is due
+		for ctx.Err() == nil {
+			videos, err := m.dbService.Queries.ClaimVideosForAvailabilityCheck(ctx, &db.ClaimVideosForAvailabilityCheckParams{
+				Limit:        checkBatchSize,
+				LeaseSeconds: int32(checkLease / time.Second),
+			})
+			if err != nil {
+				if ctx.Err() == nil {
+					logging.Info("Availability: Failed to claim videos: %s", err.Error())
+				}
+				break
+			}
+			if len(videos) == 0 {
+				break
+			}
+
+			// Copies of the same video saved by different users are recorded together
+			checked := make(map[Video]bool, len(videos))
+			for _, video := range videos {
+				key := Video{Platform: video.Platform, VideoID: video.VideoID}
+				if checked[key] {
+					continue
+				}
+				checked[key] = true
+
+				if !m.check(ctx, video) {
+					continue
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(checkSpacing):
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// check probes a claimed video and records the result
+// It reports whether the checker was asked, so the caller knows to pause before the next check.
+func (m *Monitor) check(ctx context.Context, video *db.ClaimVideosForAvailabilityCheckRow) bool {
+	saveCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Videos no checker knows stay unknown and are looked at again next interval
+	if !m.checker.Supports(video.Platform) {
+		if err := m.retry(saveCtx, video.ID, m.interval); err != nil {
+			logging.Info("Availability: Failed to reschedule video %d: %s", video.ID, err.Error())
+		}
+		return false
+	}
+
+	checkCtx, cancelCheck := context.WithTimeout(ctx, checkTimeout)
+	result, err := m.checker.Check(checkCtx, Video{
+		Platform: video.Platform,
+		VideoID:  video.VideoID,
+		URL:      video.NormalizedUrl,
+	})
+	cancelCheck()
+	// A check cut short by shutdown is retried when the lease runs out
+	if ctx.Err() != nil {
+		return true
+	}
+
+	if err != nil {
+		logging.Info("Availability: Failed to check video %d (%s %s): %s", video.ID, video.Platform, video.VideoID, err.Error())
+		err = m.retry(saveCtx, video.ID, checkRetryDelay)
+	} else {
+		err = m.save(saveCtx, video, result)
+	}
+	if err != nil {
+		logging.Info("Availability: Failed to record availability of video %d: %s", video.ID, err.Error())
+	}
+	return true
+}
+
+// save records a check result on every copy of the video
+func (m *Monitor) save(ctx context.Context, video *db.ClaimVideosForAvailabilityCheckRow, result Result) error {
+	params := &db.SetVideoAvailabilityParams{
+		Platform:         video.Platform,
+		VideoID:          video.VideoID,
+		Availability:     StatusAvailable,
+		NextCheckSeconds: int32(m.interval / time.Second),
+	}
+	if !result.Available {
+		logging.Info("Availability: Video %s %s is unavailable: %s", video.Platform, video.VideoID, result.Reason)
+		params.Availability = StatusUnavailable
+		params.AvailabilityReason = &result.Reason
+	}
+	return m.dbService.Queries.SetVideoAvailability(ctx, params)
+}
+
+// retry schedules the video's next check after delay, leaving its availability as it is
+func (m *Monitor) retry(ctx context.Context, id int64, delay time.Duration) error {
+	return m.dbService.Queries.RetryVideoAvailabilityCheck(ctx, &db.RetryVideoAvailabilityCheckParams{
+		ID:           id,
+		DelaySeconds: int32(delay / time.Second),
+	})
+}
@@ -0,0 +1,103 @@
+package availability
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// youtubeAPIURL is the YouTube Data API v3 base URL
+const youtubeAPIURL = "https://www.googleapis.com/youtube/v3"
+
+// YouTubeChecker reads the status of YouTube videos from the Data API
+type YouTubeChecker struct {
+	apiKey string
+	client *http.Client
+	apiURL string
+}
+
+// NewYouTubeChecker creates a Data API checker using apiKey
+func NewYouTubeChecker(apiKey string) *YouTubeChecker {
+	return &YouTubeChecker{
+		apiKey: apiKey,
+		client: &http.Client{Timeout: 10 * time.Second},
+		apiURL: youtubeAPIURL,
+	}
+}
+
+func (c *YouTubeChecker) Name() string {
+	return "youtube"
+}
+
+func (c *YouTubeChecker) Supports(platform string) bool {
+	return platform == "youtube"
+}
+
+// youtubeStatusResponse is the part of a videos.list response that is used
+type youtubeStatusResponse struct {
+	Items []struct {
+		Status struct {
+			UploadStatus    string `json:"uploadStatus"`
+			PrivacyStatus   string `json:"privacyStatus"`
+			FailureReason   string `json:"failureReason"`
+			RejectionReason string `json:"rejectionReason"`
+		} `json:"status"`
+	} `json:"items"`
+}
+
+func (c *YouTubeChecker) Check(ctx context.Context, video Video) (Result, error) {
+	query := url.Values{
+		"part": {"status"},
+		"id":   {video.VideoID},
+		"key":  {c.apiKey},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.apiURL+"/videos?"+query.Encode(), nil)
+	if err != nil {
+		return Result{}, err
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return Result{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Result{}, fmt.Errorf("YouTube API returned %s", resp.Status)
+	}
+
+	var body youtubeStatusResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(&body); err != nil {
+		return Result{}, fmt.Errorf("invalid YouTube API response: %w", err)
+	}
+	// Deleted videos and other people's private videos are simply left out of the response
+	if len(body.Items) == 0 {
+		return Result{Reason: ReasonRemoved}, nil
+	}
+
+	status := body.Items[0].Status
+	switch {
+	case status.UploadStatus == "deleted":
+		return Result{Reason: ReasonRemoved}, nil
+	case status.UploadStatus == "rejected":
+		return Result{Reason: withDetail("rejected", status.RejectionReason)}, nil
+	case status.UploadStatus == "failed":
+		return Result{Reason: withDetail("upload failed", status.FailureReason)}, nil
+	case status.PrivacyStatus == "private":
+		return Result{Reason: ReasonPrivate}, nil
+	default:
+		return Result{Available: true}, nil
+	}
+}
+
+// withDetail appends the API's more specific reason, if it gave one
+func withDetail(reason, detail string) string {
+	if detail == "" {
+		return reason
+	}
+	return reason + ": " + detail
+}
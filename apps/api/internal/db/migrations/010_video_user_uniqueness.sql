@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Videos were globally unique, so a second user saving a video someone else
+-- already had was silently dropped. Uniqueness is now scoped per user.
+-- Existing rows already satisfy the narrower constraint, so no data needs to move.
+alter table videos drop constraint if exists videos_video_id_key;
+alter table videos drop constraint if exists videos_normalized_url_key;
+
+alter table videos
+    add constraint unique_user_video_normalized_url unique (user_id, normalized_url);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+-- Collapse rows saved by more than one user back to the earliest copy,
+-- otherwise the global constraints below cannot be restored.
+delete from videos v
+using videos older
+where v.normalized_url = older.normalized_url
+  and v.id > older.id;
+
+delete from videos v
+using videos older
+where v.video_id = older.video_id
+  and v.id > older.id;
+
+alter table videos drop constraint if exists unique_user_video_normalized_url;
+
+alter table videos add constraint videos_video_id_key unique (video_id);
+alter table videos add constraint videos_normalized_url_key unique (normalized_url);
+-- +goose StatementEnd
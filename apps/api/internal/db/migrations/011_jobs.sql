@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+create table jobs (
+    id uuid primary key default gen_random_uuid(),
+    user_id uuid not null references "user"(id) on delete cascade,
+    status text not null default 'queued',
+    payload jsonb not null,
+    results jsonb not null default '[]'::jsonb,
+    total integer not null,
+    processed integer not null default 0,
+    error text,
+    created_at timestamptz not null default now(),
+    updated_at timestamptz not null default now(),
+    started_at timestamptz,
+    finished_at timestamptz,
+    constraint jobs_status_check check (status in ('queued', 'running', 'completed', 'failed'))
+);
+
+create index idx_jobs_user_id on jobs(user_id);
+create index idx_jobs_status_created_at on jobs(status, created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table if exists jobs;
+-- +goose StatementEnd
@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Videos can now come from platforms other than YouTube.
+-- Every video saved so far was normalized by the YouTube-only script.
+alter table videos add column platform text not null default 'youtube';
+
+create index if not exists idx_videos_user_platform on videos(user_id, platform);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop index if exists idx_videos_user_platform;
+
+alter table videos drop column if exists platform;
+-- +goose StatementEnd
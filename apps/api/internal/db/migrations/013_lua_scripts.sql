@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Lua scripts edited through the admin API. Every save is a new version;
+-- at most one version per script is active, and active versions override
+-- the scripts built into the API.
+create table lua_scripts (
+    id bigserial primary key,
+    name text not null,
+    version integer not null,
+    source text not null,
+    active boolean not null default false,
+    created_by uuid references "user"(id) on delete set null,
+    created_at timestamptz not null default now(),
+    activated_at timestamptz,
+    constraint unique_lua_script_version unique (name, version)
+);
+
+create unique index idx_lua_scripts_active_name on lua_scripts(name) where active;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table if exists lua_scripts;
+-- +goose StatementEnd
@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+create table idempotency_keys (
+    user_id uuid not null references "user"(id) on delete cascade,
+    key text not null,
+    method text not null,
+    path text not null,
+    request_hash text not null,
+    status_code integer,
+    content_type text,
+    response_body bytea,
+    created_at timestamptz not null default now(),
+    completed_at timestamptz,
+    primary key (user_id, key)
+);
+
+create index idx_idempotency_keys_created_at on idempotency_keys(created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table if exists idempotency_keys;
+-- +goose StatementEnd
@@ -0,0 +1,29 @@
+-- +goose Up
+-- +goose StatementBegin
+-- A saved video can start at a timestamp, so the same video can be saved once per moment.
+-- 0 means the video plays from the beginning, which is what every existing row does.
+alter table videos add column start_seconds integer not null default 0
+    constraint videos_start_seconds_check check (start_seconds >= 0);
+
+alter table videos drop constraint if exists unique_user_video_normalized_url;
+
+alter table videos
+    add constraint unique_user_video_normalized_url_start unique (user_id, normalized_url, start_seconds);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+-- Keep the earliest saved moment of each video, otherwise the narrower constraint cannot be restored.
+delete from videos v
+using videos older
+where v.user_id = older.user_id
+  and v.normalized_url = older.normalized_url
+  and v.id > older.id;
+
+alter table videos drop constraint if exists unique_user_video_normalized_url_start;
+
+alter table videos
+    add constraint unique_user_video_normalized_url unique (user_id, normalized_url);
+
+alter table videos drop column if exists start_seconds;
+-- +goose StatementEnd
@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+-- A job created from a YouTube playlist URL can add its videos to an ekko playlist, in order.
+alter table jobs add column playlist_id bigint references playlists(id) on delete set null;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+alter table jobs drop column if exists playlist_id;
+-- +goose StatementEnd
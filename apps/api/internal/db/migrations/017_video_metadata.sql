@@ -0,0 +1,34 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Metadata filled in after ingest by the background enricher (internal/metadata).
+-- metadata_status is pending until a provider answers, then enriched, or failed once the
+-- video is gone or every retry is used up. Existing videos start pending and get backfilled.
+alter table videos
+    add column thumbnail_url text,
+    add column duration_seconds integer,
+    add column published_at timestamptz,
+    add column channel_external_id text,
+    add column metadata_status text not null default 'pending',
+    add column metadata_attempts integer not null default 0,
+    add column metadata_next_attempt_at timestamptz default now(),
+    add column metadata_error text,
+    add constraint videos_metadata_status_check check (metadata_status in ('pending', 'enriched', 'failed'));
+
+create index idx_videos_metadata_pending on videos(metadata_next_attempt_at) where metadata_status = 'pending';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop index if exists idx_videos_metadata_pending;
+
+alter table videos
+    drop constraint if exists videos_metadata_status_check,
+    drop column if exists metadata_error,
+    drop column if exists metadata_next_attempt_at,
+    drop column if exists metadata_attempts,
+    drop column if exists metadata_status,
+    drop column if exists channel_external_id,
+    drop column if exists published_at,
+    drop column if exists duration_seconds,
+    drop column if exists thumbnail_url;
+-- +goose StatementEnd
@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Metadata the client read off the video's page when saving it, alongside what the enricher
+-- fills in. Duration, thumbnail and upload date reuse the enricher's columns.
+alter table videos
+    add column view_count bigint
+        constraint videos_view_count_check check (view_count >= 0),
+    add column channel_handle text;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+alter table videos
+    drop column if exists channel_handle,
+    drop column if exists view_count;
+-- +goose StatementEnd
@@ -0,0 +1,48 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Channels a user has saved videos from. videos.channel stays as the name the video was saved
+-- with; channel_id groups videos whose channel names differ but are the same channel.
+-- A channel is identified by its external ID once the enricher knows it, otherwise by its handle,
+-- otherwise by its name.
+create table channels (
+    id bigserial primary key,
+    user_id uuid not null references "user"(id) on delete cascade,
+    platform text not null,
+    external_id text,
+    handle text,
+    name text not null,
+    avatar_url text,
+    created_at timestamptz not null default now(),
+    updated_at timestamptz not null default now()
+);
+
+create index idx_channels_user_id on channels(user_id);
+create unique index unique_user_channel_external_id on channels(user_id, platform, external_id)
+    where external_id is not null;
+create unique index unique_user_channel_handle on channels(user_id, platform, lower(handle))
+    where handle is not null;
+
+alter table videos add column channel_id bigint references channels(id) on delete set null;
+
+create index idx_videos_channel_id on videos(channel_id);
+
+-- One channel per distinct channel name saved so far; duplicates can be merged afterwards
+insert into channels (user_id, platform, name)
+select distinct user_id, platform, channel
+from videos
+where channel <> '';
+
+update videos v
+set channel_id = c.id
+from channels c
+where c.user_id = v.user_id
+  and c.platform = v.platform
+  and c.name = v.channel;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+alter table videos drop column if exists channel_id;
+
+drop table if exists channels;
+-- +goose StatementEnd
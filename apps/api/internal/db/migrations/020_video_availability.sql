@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Whether a saved video can still be watched, as last seen by the availability checker.
+-- availability is unknown until the first check. availability_next_check_at schedules the next
+-- check; existing videos are due right away.
+alter table videos
+    add column availability text not null default 'unknown',
+    add column availability_reason text,
+    add column last_checked_at timestamptz,
+    add column availability_next_check_at timestamptz not null default now(),
+    add constraint videos_availability_check check (availability in ('unknown', 'available', 'unavailable'));
+
+create index idx_videos_availability_next_check on videos(availability_next_check_at);
+create index idx_videos_platform_video_id on videos(platform, video_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop index if exists idx_videos_platform_video_id;
+drop index if exists idx_videos_availability_next_check;
+
+alter table videos
+    drop constraint if exists videos_availability_check,
+    drop column if exists availability_next_check_at,
+    drop column if exists last_checked_at,
+    drop column if exists availability_reason,
+    drop column if exists availability;
+-- +goose StatementEnd
@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Thumbnails cached in the upload directory. thumbnail_hash names the cached files and is set
+-- once thumbnail_url has been fetched; thumbnail_source_url is the thumbnail_url it was fetched
+-- from, so a video whose thumbnail_url changes is fetched again.
+alter table videos
+    add column thumbnail_hash text,
+    add column thumbnail_source_url text,
+    add column thumbnail_cache_attempts integer not null default 0,
+    add column thumbnail_cache_next_attempt_at timestamptz not null default now();
+
+create index idx_videos_thumbnail_hash on videos(thumbnail_hash) where thumbnail_hash is not null;
+create index idx_videos_thumbnail_cache_due on videos(thumbnail_cache_next_attempt_at)
+    where thumbnail_url is not null and thumbnail_source_url is distinct from thumbnail_url;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop index if exists idx_videos_thumbnail_cache_due;
+drop index if exists idx_videos_thumbnail_hash;
+
+alter table videos
+    drop column if exists thumbnail_cache_next_attempt_at,
+    drop column if exists thumbnail_cache_attempts,
+    drop column if exists thumbnail_source_url,
+    drop column if exists thumbnail_hash;
+-- +goose StatementEnd
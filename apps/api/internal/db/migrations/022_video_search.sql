@@ -0,0 +1,71 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Full-text search over a video's title, channel and tag names, weighted in that order.
+-- A generated column can only read its own row, so tag_names keeps a copy of the names of the
+-- video's tags, maintained by the triggers below.
+alter table videos add column tag_names text not null default '';
+
+alter table videos add column search_vector tsvector generated always as (
+    setweight(to_tsvector('english', title), 'A') ||
+    setweight(to_tsvector('english', channel), 'B') ||
+    setweight(to_tsvector('english', tag_names), 'C')
+) stored;
+
+create index idx_videos_search_vector on videos using gin (search_vector);
+
+create function refresh_video_tag_names(video_ids bigint[]) returns void as $$
+    update videos v
+    set tag_names = coalesce((
+        select string_agg(t.name, ' ' order by t.name)
+        from video_tags vt
+        join tags t on t.id = vt.tag_id
+        where vt.video_id = v.id
+    ), '')
+    where v.id = any(video_ids);
+$$ language sql;
+
+create function video_tags_refresh_tag_names() returns trigger as $$
+begin
+    if tg_op in ('INSERT', 'UPDATE') then
+        perform refresh_video_tag_names(array[new.video_id]);
+    end if;
+    if tg_op in ('DELETE', 'UPDATE') then
+        perform refresh_video_tag_names(array[old.video_id]);
+    end if;
+    return null;
+end;
+$$ language plpgsql;
+
+create trigger video_tags_tag_names
+    after insert or update or delete on video_tags
+    for each row execute function video_tags_refresh_tag_names();
+
+create function tags_refresh_tag_names() returns trigger as $$
+begin
+    perform refresh_video_tag_names(array(select video_id from video_tags where tag_id = new.id));
+    return null;
+end;
+$$ language plpgsql;
+
+create trigger tags_tag_names
+    after update of name on tags
+    for each row when (old.name is distinct from new.name)
+    execute function tags_refresh_tag_names();
+
+select refresh_video_tag_names(array(select distinct video_id from video_tags));
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop trigger if exists tags_tag_names on tags;
+drop trigger if exists video_tags_tag_names on video_tags;
+drop function if exists tags_refresh_tag_names();
+drop function if exists video_tags_refresh_tag_names();
+drop function if exists refresh_video_tag_names(bigint[]);
+
+drop index if exists idx_videos_search_vector;
+
+alter table videos
+    drop column if exists search_vector,
+    drop column if exists tag_names;
+-- +goose StatementEnd
@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Keyset pagination of a user's videos in each sort of GET /api/videos. Titles and channels sort
+-- lower-cased in byte order, the same order the API compares cursors in.
+create index idx_videos_user_created_at on videos(user_id, created_at, id);
+create index idx_videos_user_title on videos(user_id, (lower(title) collate "C"), id);
+create index idx_videos_user_channel on videos(user_id, (lower(channel) collate "C"), id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop index if exists idx_videos_user_channel;
+drop index if exists idx_videos_user_title;
+drop index if exists idx_videos_user_created_at;
+-- +goose StatementEnd
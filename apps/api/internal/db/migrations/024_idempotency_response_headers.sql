@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Headers of the stored response that are replayed with it, e.g. the Location of a queued job
+alter table idempotency_keys add column response_headers jsonb;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+alter table idempotency_keys drop column if exists response_headers;
+-- +goose StatementEnd
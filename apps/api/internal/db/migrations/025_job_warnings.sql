@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Set on jobs whose input is known to be incomplete, e.g. a playlist read from its 15-video feed
+alter table jobs add column warning text;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+alter table jobs drop column if exists warning;
+-- +goose StatementEnd
@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Names and handles of channels merged into another channel. New videos saved under them join
+-- that channel instead of bringing the merged channel back.
+create table channel_aliases (
+    channel_id bigint not null references channels(id) on delete cascade,
+    name text not null,
+    handle text,
+    created_at timestamptz not null default now()
+);
+
+create unique index unique_channel_alias on channel_aliases(channel_id, name, coalesce(lower(handle), ''));
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table if exists channel_aliases;
+-- +goose StatementEnd
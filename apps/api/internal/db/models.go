@@ -33,12 +33,62 @@ type ApiToken struct {
 	LastUsedAt  pgtype.Timestamptz `json:"last_used_at"`
 }
 
+type Channel struct {
+	ID         int64              `json:"id"`
+	UserID     string             `json:"user_id"`
+	Platform   string             `json:"platform"`
+	ExternalID *string            `json:"external_id"`
+	Handle     *string            `json:"handle"`
+	Name       string             `json:"name"`
+	AvatarUrl  *string            `json:"avatar_url"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt  pgtype.Timestamptz `json:"updated_at"`
+}
+
+type ChannelAlias struct {
+	ChannelID int64              `json:"channel_id"`
+	Name      string             `json:"name"`
+	Handle    *string            `json:"handle"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
 type Config struct {
 	Key       string             `json:"key"`
 	Value     string             `json:"value"`
 	UpdatedAt pgtype.Timestamptz `json:"updated_at"`
 }
 
+type IdempotencyKey struct {
+	UserID          string             `json:"user_id"`
+	Key             string             `json:"key"`
+	Method          string             `json:"method"`
+	Path            string             `json:"path"`
+	RequestHash     string             `json:"request_hash"`
+	StatusCode      *int32             `json:"status_code"`
+	ContentType     *string            `json:"content_type"`
+	ResponseBody    []byte             `json:"response_body"`
+	CreatedAt       pgtype.Timestamptz `json:"created_at"`
+	CompletedAt     pgtype.Timestamptz `json:"completed_at"`
+	ResponseHeaders []byte             `json:"response_headers"`
+}
+
+type Job struct {
+	ID         pgtype.UUID        `json:"id"`
+	UserID     string             `json:"user_id"`
+	Status     string             `json:"status"`
+	Payload    []byte             `json:"payload"`
+	Results    []byte             `json:"results"`
+	Total      int32              `json:"total"`
+	Processed  int32              `json:"processed"`
+	Error      *string            `json:"error"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt  pgtype.Timestamptz `json:"updated_at"`
+	StartedAt  pgtype.Timestamptz `json:"started_at"`
+	FinishedAt pgtype.Timestamptz `json:"finished_at"`
+	PlaylistID *int64             `json:"playlist_id"`
+	Warning    *string            `json:"warning"`
+}
+
 type Jwk struct {
 	ID         pgtype.UUID      `json:"id"`
 	PublicKey  string           `json:"public_key"`
@@ -47,6 +97,17 @@ type Jwk struct {
 	ExpiresAt  pgtype.Timestamp `json:"expires_at"`
 }
 
+type LuaScript struct {
+	ID          int64              `json:"id"`
+	Name        string             `json:"name"`
+	Version     int32              `json:"version"`
+	Source      string             `json:"source"`
+	Active      bool               `json:"active"`
+	CreatedBy   pgtype.UUID        `json:"created_by"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+	ActivatedAt pgtype.Timestamptz `json:"activated_at"`
+}
+
 type OidcProvider struct {
 	ID           pgtype.UUID        `json:"id"`
 	ProviderID   string             `json:"provider_id"`
@@ -122,14 +183,37 @@ type Verification struct {
 }
 
 type Video struct {
-	ID            int64              `json:"id"`
-	VideoID       string             `json:"video_id"`
-	NormalizedUrl string             `json:"normalized_url"`
-	OriginalUrl   string             `json:"original_url"`
-	Title         string             `json:"title"`
-	Channel       string             `json:"channel"`
-	UserID        string             `json:"user_id"`
-	CreatedAt     pgtype.Timestamptz `json:"created_at"`
+	ID                          int64              `json:"id"`
+	VideoID                     string             `json:"video_id"`
+	NormalizedUrl               string             `json:"normalized_url"`
+	OriginalUrl                 string             `json:"original_url"`
+	Title                       string             `json:"title"`
+	Channel                     string             `json:"channel"`
+	UserID                      string             `json:"user_id"`
+	CreatedAt                   pgtype.Timestamptz `json:"created_at"`
+	Platform                    string             `json:"platform"`
+	StartSeconds                int32              `json:"start_seconds"`
+	ThumbnailUrl                *string            `json:"thumbnail_url"`
+	DurationSeconds             *int32             `json:"duration_seconds"`
+	PublishedAt                 pgtype.Timestamptz `json:"published_at"`
+	ChannelExternalID           *string            `json:"channel_external_id"`
+	MetadataStatus              string             `json:"metadata_status"`
+	MetadataAttempts            int32              `json:"metadata_attempts"`
+	MetadataNextAttemptAt       pgtype.Timestamptz `json:"metadata_next_attempt_at"`
+	MetadataError               *string            `json:"metadata_error"`
+	ViewCount                   *int64             `json:"view_count"`
+	ChannelHandle               *string            `json:"channel_handle"`
+	ChannelID                   *int64             `json:"channel_id"`
+	Availability                string             `json:"availability"`
+	AvailabilityReason          *string            `json:"availability_reason"`
+	LastCheckedAt               pgtype.Timestamptz `json:"last_checked_at"`
+	AvailabilityNextCheckAt     pgtype.Timestamptz `json:"availability_next_check_at"`
+	ThumbnailHash               *string            `json:"thumbnail_hash"`
+	ThumbnailSourceUrl          *string            `json:"thumbnail_source_url"`
+	ThumbnailCacheAttempts      int32              `json:"thumbnail_cache_attempts"`
+	ThumbnailCacheNextAttemptAt pgtype.Timestamptz `json:"thumbnail_cache_next_attempt_at"`
+	TagNames                    string             `json:"tag_names"`
+	SearchVector                interface{}        `json:"search_vector"`
 }
 
 type VideoTag struct {
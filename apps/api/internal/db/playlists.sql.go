@@ -7,8 +7,6 @@ package db
 
 import (
 	"context"
-
-	"github.com/jackc/pgx/v5/pgtype"
 )
 
 const AddVideoToPlaylist = `-- name: AddVideoToPlaylist :one
@@ -61,6 +59,24 @@ func (q *Queries) AddVideoToPlaylistByName(ctx context.Context, arg *AddVideoToP
 	return err
 }
 
+const AddVideosToPlaylist = `-- name: AddVideosToPlaylist :exec
+insert into playlist_videos (playlist_id, video_id, position)
+select $1::bigint, v.video_id, v.position
+from unnest($2::bigint[], $3::int[]) as v(video_id, position)
+on conflict (playlist_id, video_id) do nothing
+`
+
+type AddVideosToPlaylistParams struct {
+	PlaylistID int64   `json:"playlist_id"`
+	VideoIds   []int64 `json:"video_ids"`
+	Positions  []int32 `json:"positions"`
+}
+
+func (q *Queries) AddVideosToPlaylist(ctx context.Context, arg *AddVideosToPlaylistParams) error {
+	_, err := q.db.Exec(ctx, AddVideosToPlaylist, arg.PlaylistID, arg.VideoIds, arg.Positions)
+	return err
+}
+
 const CreatePlaylist = `-- name: CreatePlaylist :one
 insert into playlists (user_id, name)
 values ($1, $2)
@@ -100,6 +116,30 @@ func (q *Queries) DeletePlaylist(ctx context.Context, arg *DeletePlaylistParams)
 	return err
 }
 
+const GetPlaylistByID = `-- name: GetPlaylistByID :one
+select id, user_id, name, created_at, updated_at
+from playlists
+where id = $1 and user_id = $2
+`
+
+type GetPlaylistByIDParams struct {
+	ID     int64  `json:"id"`
+	UserID string `json:"user_id"`
+}
+
+func (q *Queries) GetPlaylistByID(ctx context.Context, arg *GetPlaylistByIDParams) (*Playlist, error) {
+	row := q.db.QueryRow(ctx, GetPlaylistByID, arg.ID, arg.UserID)
+	var i Playlist
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Name,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return &i, err
+}
+
 const GetPlaylistByName = `-- name: GetPlaylistByName :one
 select id, user_id, name, created_at, updated_at
 from playlists
@@ -160,124 +200,6 @@ func (q *Queries) GetPlaylistVideoCount(ctx context.Context, arg *GetPlaylistVid
 	return count, err
 }
 
-const GetPlaylistVideos = `-- name: GetPlaylistVideos :many
-select v.id, v.video_id, v.normalized_url, v.original_url, v.title, v.channel, v.user_id, v.created_at, pv.position, pv.created_at as added_at
-from playlist_videos pv
-join videos v on pv.video_id = v.id
-join playlists p on pv.playlist_id = p.id
-where p.user_id = $1 and p.name = $2
-order by pv.position, pv.created_at
-`
-
-type GetPlaylistVideosParams struct {
-	UserID string `json:"user_id"`
-	Name   string `json:"name"`
-}
-
-type GetPlaylistVideosRow struct {
-	ID            int64              `json:"id"`
-	VideoID       string             `json:"video_id"`
-	NormalizedUrl string             `json:"normalized_url"`
-	OriginalUrl   string             `json:"original_url"`
-	Title         string             `json:"title"`
-	Channel       string             `json:"channel"`
-	UserID        string             `json:"user_id"`
-	CreatedAt     pgtype.Timestamptz `json:"created_at"`
-	Position      int32              `json:"position"`
-	AddedAt       pgtype.Timestamptz `json:"added_at"`
-}
-
-func (q *Queries) GetPlaylistVideos(ctx context.Context, arg *GetPlaylistVideosParams) ([]*GetPlaylistVideosRow, error) {
-	rows, err := q.db.Query(ctx, GetPlaylistVideos, arg.UserID, arg.Name)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	items := []*GetPlaylistVideosRow{}
-	for rows.Next() {
-		var i GetPlaylistVideosRow
-		if err := rows.Scan(
-			&i.ID,
-			&i.VideoID,
-			&i.NormalizedUrl,
-			&i.OriginalUrl,
-			&i.Title,
-			&i.Channel,
-			&i.UserID,
-			&i.CreatedAt,
-			&i.Position,
-			&i.AddedAt,
-		); err != nil {
-			return nil, err
-		}
-		items = append(items, &i)
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return items, nil
-}
-
-const GetPlaylistVideosWithSearch = `-- name: GetPlaylistVideosWithSearch :many
-select v.id, v.video_id, v.normalized_url, v.original_url, v.title, v.channel, v.user_id, v.created_at, pv.position, pv.created_at as added_at
-from playlist_videos pv
-join videos v on pv.video_id = v.id
-join playlists p on pv.playlist_id = p.id
-where p.user_id = $1 and p.name = $2
-  and (v.title ILIKE $3 OR v.channel ILIKE $3)
-order by pv.position, pv.created_at
-`
-
-type GetPlaylistVideosWithSearchParams struct {
-	UserID string `json:"user_id"`
-	Name   string `json:"name"`
-	Title  string `json:"title"`
-}
-
-type GetPlaylistVideosWithSearchRow struct {
-	ID            int64              `json:"id"`
-	VideoID       string             `json:"video_id"`
-	NormalizedUrl string             `json:"normalized_url"`
-	OriginalUrl   string             `json:"original_url"`
-	Title         string             `json:"title"`
-	Channel       string             `json:"channel"`
-	UserID        string             `json:"user_id"`
-	CreatedAt     pgtype.Timestamptz `json:"created_at"`
-	Position      int32              `json:"position"`
-	AddedAt       pgtype.Timestamptz `json:"added_at"`
-}
-
-func (q *Queries) GetPlaylistVideosWithSearch(ctx context.Context, arg *GetPlaylistVideosWithSearchParams) ([]*GetPlaylistVideosWithSearchRow, error) {
-	rows, err := q.db.Query(ctx, GetPlaylistVideosWithSearch, arg.UserID, arg.Name, arg.Title)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	items := []*GetPlaylistVideosWithSearchRow{}
-	for rows.Next() {
-		var i GetPlaylistVideosWithSearchRow
-		if err := rows.Scan(
-			&i.ID,
-			&i.VideoID,
-			&i.NormalizedUrl,
-			&i.OriginalUrl,
-			&i.Title,
-			&i.Channel,
-			&i.UserID,
-			&i.CreatedAt,
-			&i.Position,
-			&i.AddedAt,
-		); err != nil {
-			return nil, err
-		}
-		items = append(items, &i)
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return items, nil
-}
-
 const ListPlaylistsByUser = `-- name: ListPlaylistsByUser :many
 select id, user_id, name, created_at, updated_at
 from playlists
@@ -11,18 +11,36 @@ import (
 )
 
 type Querier interface {
+	ActivateLuaScriptVersion(ctx context.Context, arg *ActivateLuaScriptVersionParams) (*LuaScript, error)
 	AddVideoTags(ctx context.Context, arg *AddVideoTagsParams) error
 	AddVideoToPlaylist(ctx context.Context, arg *AddVideoToPlaylistParams) (*PlaylistVideo, error)
 	AddVideoToPlaylistByName(ctx context.Context, arg *AddVideoToPlaylistByNameParams) error
+	AddVideosToPlaylist(ctx context.Context, arg *AddVideosToPlaylistParams) error
+	AssignVideoChannels(ctx context.Context, arg *AssignVideoChannelsParams) error
+	ClaimIdempotencyKey(ctx context.Context, arg *ClaimIdempotencyKeyParams) (*IdempotencyKey, error)
+	ClaimNextJob(ctx context.Context, leaseSeconds int32) (*Job, error)
+	ClaimVideosForAvailabilityCheck(ctx context.Context, arg *ClaimVideosForAvailabilityCheckParams) ([]*ClaimVideosForAvailabilityCheckRow, error)
+	ClaimVideosForEnrichment(ctx context.Context, arg *ClaimVideosForEnrichmentParams) ([]*ClaimVideosForEnrichmentRow, error)
+	ClaimVideosForThumbnailCache(ctx context.Context, arg *ClaimVideosForThumbnailCacheParams) ([]*ClaimVideosForThumbnailCacheRow, error)
 	CleanExpiredSessions(ctx context.Context) error
+	CompleteIdempotencyKey(ctx context.Context, arg *CompleteIdempotencyKeyParams) error
+	CompleteJob(ctx context.Context, id pgtype.UUID) error
 	CreateAPIToken(ctx context.Context, arg *CreateAPITokenParams) (*ApiToken, error)
+	CreateChannelAliases(ctx context.Context, arg *CreateChannelAliasesParams) error
+	CreateChannelsForVideos(ctx context.Context, arg *CreateChannelsForVideosParams) error
+	CreateJob(ctx context.Context, arg *CreateJobParams) (*Job, error)
+	CreateLuaScriptVersion(ctx context.Context, arg *CreateLuaScriptVersionParams) (*LuaScript, error)
 	CreateOIDCProvider(ctx context.Context, arg *CreateOIDCProviderParams) (*OidcProvider, error)
 	CreatePlaylist(ctx context.Context, arg *CreatePlaylistParams) (*Playlist, error)
 	CreateSession(ctx context.Context, arg *CreateSessionParams) (*Session, error)
 	CreateTag(ctx context.Context, arg *CreateTagParams) (*Tag, error)
 	CreateVerification(ctx context.Context, arg *CreateVerificationParams) (*Verification, error)
-	CreateVideo(ctx context.Context, arg *CreateVideoParams) (*Video, error)
+	CreateVideos(ctx context.Context, arg *CreateVideosParams) ([]*CreateVideosRow, error)
+	DeactivateLuaScript(ctx context.Context, name string) error
 	DeleteAPIToken(ctx context.Context, arg *DeleteAPITokenParams) error
+	DeleteChannels(ctx context.Context, arg *DeleteChannelsParams) error
+	DeleteExpiredIdempotencyKeys(ctx context.Context) (int64, error)
+	DeleteIdempotencyKey(ctx context.Context, arg *DeleteIdempotencyKeyParams) error
 	DeleteOIDCProvider(ctx context.Context, id pgtype.UUID) error
 	DeletePlaylist(ctx context.Context, arg *DeletePlaylistParams) error
 	DeleteSession(ctx context.Context, token string) error
@@ -31,17 +49,24 @@ type Querier interface {
 	DeleteVerification(ctx context.Context, value string) error
 	DeleteVideo(ctx context.Context, arg *DeleteVideoParams) error
 	DeleteVideos(ctx context.Context, arg *DeleteVideosParams) error
-	FilterVideosByTags(ctx context.Context, arg *FilterVideosByTagsParams) ([]*Video, error)
-	FilterVideosByTagsAnd(ctx context.Context, arg *FilterVideosByTagsAndParams) ([]*Video, error)
+	EnsureTag(ctx context.Context, arg *EnsureTagParams) (*Tag, error)
+	FailJob(ctx context.Context, arg *FailJobParams) error
+	FailVideoMetadata(ctx context.Context, arg *FailVideoMetadataParams) error
+	FillChannelDetails(ctx context.Context, arg *FillChannelDetailsParams) (*Channel, error)
+	FilterVideosByTags(ctx context.Context, arg *FilterVideosByTagsParams) ([]*FilterVideosByTagsRow, error)
 	GetAPITokenByHash(ctx context.Context, tokenHash string) (*GetAPITokenByHashRow, error)
+	GetChannelForUser(ctx context.Context, arg *GetChannelForUserParams) (*Channel, error)
+	GetChannelVideoCount(ctx context.Context, channelID *int64) (int64, error)
 	GetConfig(ctx context.Context, key string) (*Config, error)
+	GetIdempotencyKey(ctx context.Context, arg *GetIdempotencyKeyParams) (*IdempotencyKey, error)
+	GetJobForUser(ctx context.Context, arg *GetJobForUserParams) (*Job, error)
+	GetLuaScriptVersion(ctx context.Context, arg *GetLuaScriptVersionParams) (*LuaScript, error)
 	GetOIDCProvider(ctx context.Context, id pgtype.UUID) (*OidcProvider, error)
 	GetOIDCProviderByProviderID(ctx context.Context, providerID string) (*OidcProvider, error)
+	GetPlaylistByID(ctx context.Context, arg *GetPlaylistByIDParams) (*Playlist, error)
 	GetPlaylistByName(ctx context.Context, arg *GetPlaylistByNameParams) (*Playlist, error)
 	GetPlaylistIDByName(ctx context.Context, arg *GetPlaylistIDByNameParams) (int64, error)
 	GetPlaylistVideoCount(ctx context.Context, arg *GetPlaylistVideoCountParams) (int64, error)
-	GetPlaylistVideos(ctx context.Context, arg *GetPlaylistVideosParams) ([]*GetPlaylistVideosRow, error)
-	GetPlaylistVideosWithSearch(ctx context.Context, arg *GetPlaylistVideosWithSearchParams) ([]*GetPlaylistVideosWithSearchRow, error)
 	GetSessionByToken(ctx context.Context, token string) (*GetSessionByTokenRow, error)
 	GetTagByID(ctx context.Context, arg *GetTagByIDParams) (*Tag, error)
 	GetUserByEmail(ctx context.Context, email string) (*User, error)
@@ -50,30 +75,38 @@ type Querier interface {
 	GetUserPreferences(ctx context.Context, userID string) (*UserPreference, error)
 	GetVerificationByIdentifier(ctx context.Context, identifier string) (*Verification, error)
 	GetVerificationByValue(ctx context.Context, value string) (*Verification, error)
-	GetVideoByID(ctx context.Context, id int64) (*Video, error)
-	GetVideoByURL(ctx context.Context, normalizedUrl string) (*Video, error)
+	GetVideoForUser(ctx context.Context, arg *GetVideoForUserParams) (*GetVideoForUserRow, error)
 	GetVideoTags(ctx context.Context, videoID int64) ([]*Tag, error)
-	GetVideoTagsForVideos(ctx context.Context, dollar_1 []int64) ([]*GetVideoTagsForVideosRow, error)
+	GetVideoTagsForVideos(ctx context.Context, videoIds []int64) ([]*GetVideoTagsForVideosRow, error)
+	LinkChannelExternalID(ctx context.Context, id int64) error
 	ListAPITokensByUser(ctx context.Context, userID string) ([]*ListAPITokensByUserRow, error)
+	ListActiveLuaScripts(ctx context.Context) ([]*LuaScript, error)
 	ListAllOIDCProviders(ctx context.Context) ([]*OidcProvider, error)
+	ListChannelsByIDs(ctx context.Context, arg *ListChannelsByIDsParams) ([]*Channel, error)
+	ListChannelsWithCounts(ctx context.Context, userID string) ([]*ListChannelsWithCountsRow, error)
 	ListConfigs(ctx context.Context) ([]*Config, error)
 	ListEnabledOIDCProviders(ctx context.Context) ([]*OidcProvider, error)
+	ListLuaScriptVersions(ctx context.Context, name string) ([]*LuaScript, error)
 	ListPlaylistsByUser(ctx context.Context, userID string) ([]*Playlist, error)
 	ListRecentVerifications(ctx context.Context) ([]*Verification, error)
+	ListReferencedThumbnailHashes(ctx context.Context, hashes []string) ([]*string, error)
 	ListTags(ctx context.Context, userID string) ([]*Tag, error)
-	ListVideos(ctx context.Context, userID string) ([]*Video, error)
-	ListVideosFiltered(ctx context.Context, arg *ListVideosFilteredParams) ([]*Video, error)
-	ListVideosFilteredWithSearch(ctx context.Context, arg *ListVideosFilteredWithSearchParams) ([]*Video, error)
-	ListVideosUnassigned(ctx context.Context, userID string) ([]*Video, error)
-	ListVideosUnassignedFiltered(ctx context.Context, arg *ListVideosUnassignedFilteredParams) ([]*Video, error)
-	ListVideosUnassignedFilteredWithSearch(ctx context.Context, arg *ListVideosUnassignedFilteredWithSearchParams) ([]*Video, error)
-	ListVideosUnassignedWithSearch(ctx context.Context, arg *ListVideosUnassignedWithSearchParams) ([]*Video, error)
-	ListVideosWithSearch(ctx context.Context, arg *ListVideosWithSearchParams) ([]*Video, error)
+	ListVideoIDsByURL(ctx context.Context, arg *ListVideoIDsByURLParams) ([]*ListVideoIDsByURLRow, error)
 	ListVideosWithTags(ctx context.Context, userID string) ([]*ListVideosWithTagsRow, error)
+	MoveChannelVideos(ctx context.Context, arg *MoveChannelVideosParams) error
+	MoveVideoToExternalChannel(ctx context.Context, id int64) error
 	RemoveVideoFromPlaylist(ctx context.Context, arg *RemoveVideoFromPlaylistParams) error
 	RemoveVideoTags(ctx context.Context, arg *RemoveVideoTagsParams) error
+	RequeueJob(ctx context.Context, id pgtype.UUID) error
+	RetryVideoAvailabilityCheck(ctx context.Context, arg *RetryVideoAvailabilityCheckParams) error
+	RetryVideoMetadata(ctx context.Context, arg *RetryVideoMetadataParams) error
+	RetryVideoThumbnail(ctx context.Context, arg *RetryVideoThumbnailParams) error
+	SaveVideoMetadata(ctx context.Context, arg *SaveVideoMetadataParams) error
+	SaveVideoThumbnail(ctx context.Context, arg *SaveVideoThumbnailParams) error
+	SetVideoAvailability(ctx context.Context, arg *SetVideoAvailabilityParams) error
 	UpdateAPITokenLastUsed(ctx context.Context, id pgtype.UUID) error
 	UpdateAPITokenName(ctx context.Context, arg *UpdateAPITokenNameParams) error
+	UpdateJobProgress(ctx context.Context, arg *UpdateJobProgressParams) error
 	UpdateOIDCProvider(ctx context.Context, arg *UpdateOIDCProviderParams) (*OidcProvider, error)
 	UpdatePlaylistByName(ctx context.Context, arg *UpdatePlaylistByNameParams) (*Playlist, error)
 	UpdateTag(ctx context.Context, arg *UpdateTagParams) (*Tag, error)
@@ -81,6 +114,7 @@ type Querier interface {
 	UpdateUserProfile(ctx context.Context, arg *UpdateUserProfileParams) error
 	UpsertConfig(ctx context.Context, arg *UpsertConfigParams) (*Config, error)
 	UpsertUserPreferences(ctx context.Context, arg *UpsertUserPreferencesParams) (*UserPreference, error)
+	UserHasThumbnail(ctx context.Context, arg *UserHasThumbnailParams) (bool, error)
 }
 
 var _ Querier = (*Queries)(nil)
@@ -0,0 +1,126 @@
+-- name: ListChannelsWithCounts :many
+select c.id, c.user_id, c.platform, c.external_id, c.handle, c.name, c.avatar_url, c.created_at, c.updated_at,
+       count(v.id) as video_count
+from channels c
+join videos v on v.channel_id = c.id
+where c.user_id = $1
+group by c.id
+order by lower(c.name), c.id;
+
+-- name: GetChannelForUser :one
+select id, user_id, platform, external_id, handle, name, avatar_url, created_at, updated_at
+from channels
+where id = $1 and user_id = $2;
+
+-- name: ListChannelsByIDs :many
+select id, user_id, platform, external_id, handle, name, avatar_url, created_at, updated_at
+from channels
+where user_id = sqlc.arg(user_id) and id = any(sqlc.arg(ids)::bigint[])
+order by id;
+
+-- name: GetChannelVideoCount :one
+select count(*) as count
+from videos
+where channel_id = $1;
+
+-- name: MoveChannelVideos :exec
+update videos
+set channel_id = sqlc.arg(channel_id)
+where user_id = sqlc.arg(user_id) and channel_id = any(sqlc.arg(source_ids)::bigint[]);
+
+-- name: CreateChannelAliases :exec
+insert into channel_aliases (channel_id, name, handle)
+select sqlc.arg(channel_id)::bigint, c.name, c.handle
+from channels c
+where c.user_id = sqlc.arg(user_id) and c.id = any(sqlc.arg(source_ids)::bigint[])
+union
+select sqlc.arg(channel_id)::bigint, a.name, a.handle
+from channel_aliases a
+join channels c on c.id = a.channel_id
+where c.user_id = sqlc.arg(user_id) and c.id = any(sqlc.arg(source_ids)::bigint[])
+on conflict do nothing;
+
+-- name: DeleteChannels :exec
+delete from channels
+where user_id = sqlc.arg(user_id) and id = any(sqlc.arg(ids)::bigint[]);
+
+-- name: FillChannelDetails :one
+update channels
+set external_id = coalesce(external_id, $2),
+    handle = coalesce(handle, $3),
+    avatar_url = coalesce(avatar_url, $4),
+    updated_at = now()
+where id = $1
+returning id, user_id, platform, external_id, handle, name, avatar_url, created_at, updated_at;
+
+-- name: CreateChannelsForVideos :exec
+insert into channels (user_id, platform, handle, name)
+select distinct on (v.platform, coalesce(lower(v.channel_handle), v.channel))
+       v.user_id, v.platform, v.channel_handle, v.channel
+from videos v
+where v.user_id = sqlc.arg(user_id)
+  and v.id = any(sqlc.arg(video_ids)::bigint[])
+  and v.channel_id is null
+  and v.channel <> ''
+  and not exists (
+      select 1 from channels c
+      where c.user_id = v.user_id
+        and c.platform = v.platform
+        and (lower(c.handle) = lower(v.channel_handle)
+             or (c.name = v.channel and (c.handle is null or v.channel_handle is null))
+             or exists (
+                 select 1 from channel_aliases a
+                 where a.channel_id = c.id
+                   and (lower(a.handle) = lower(v.channel_handle)
+                        or (a.name = v.channel and (a.handle is null or v.channel_handle is null)))
+             ))
+  )
+order by v.platform, coalesce(lower(v.channel_handle), v.channel), v.id
+on conflict do nothing;
+
+-- name: AssignVideoChannels :exec
+update videos v
+set channel_id = (
+    select c.id from channels c
+    where c.user_id = v.user_id
+      and c.platform = v.platform
+      and (lower(c.handle) = lower(v.channel_handle)
+           or (c.name = v.channel and (c.handle is null or v.channel_handle is null))
+           or exists (
+               select 1 from channel_aliases a
+               where a.channel_id = c.id
+                 and (lower(a.handle) = lower(v.channel_handle)
+                      or (a.name = v.channel and (a.handle is null or v.channel_handle is null)))
+           ))
+    order by (lower(c.handle) = lower(v.channel_handle)) is true desc, c.id
+    limit 1
+)
+where v.user_id = sqlc.arg(user_id)
+  and v.id = any(sqlc.arg(video_ids)::bigint[])
+  and v.channel_id is null
+  and v.channel <> '';
+
+-- name: LinkChannelExternalID :exec
+update channels c
+set external_id = v.channel_external_id, updated_at = now()
+from videos v
+where v.id = $1
+  and c.id = v.channel_id
+  and c.external_id is null
+  and v.channel_external_id is not null
+  and not exists (
+      select 1 from channels o
+      where o.user_id = c.user_id
+        and o.platform = c.platform
+        and o.external_id = v.channel_external_id
+  );
+
+-- name: MoveVideoToExternalChannel :exec
+update videos v
+set channel_id = c.id
+from channels c
+where v.id = $1
+  and c.user_id = v.user_id
+  and c.platform = v.platform
+  and c.external_id = v.channel_external_id
+  and v.channel_id is distinct from c.id;
@@ -0,0 +1,34 @@
+-- name: ClaimIdempotencyKey :one
+insert into idempotency_keys (user_id, key, method, path, request_hash)
+values ($1, $2, $3, $4, $5)
+on conflict (user_id, key) do update
+set method = excluded.method,
+    path = excluded.path,
+    request_hash = excluded.request_hash,
+    status_code = null,
+    content_type = null,
+    response_body = null,
+    response_headers = null,
+    created_at = now(),
+    completed_at = null
+where idempotency_keys.created_at < now() - interval '24 hours'
+   or (idempotency_keys.completed_at is null and idempotency_keys.created_at < now() - interval '1 minute')
+returning user_id, key, method, path, request_hash, status_code, content_type, response_body, created_at, completed_at, response_headers;
+
+-- name: GetIdempotencyKey :one
+select user_id, key, method, path, request_hash, status_code, content_type, response_body, created_at, completed_at, response_headers
+from idempotency_keys
+where user_id = $1 and key = $2;
+
+-- name: CompleteIdempotencyKey :exec
+update idempotency_keys
+set status_code = $3, content_type = $4, response_body = $5, response_headers = $6, completed_at = now()
+where user_id = $1 and key = $2;
+
+-- name: DeleteIdempotencyKey :exec
+delete from idempotency_keys
+where user_id = $1 and key = $2;
+
+-- name: DeleteExpiredIdempotencyKeys :execrows
+delete from idempotency_keys
+where created_at < now() - interval '24 hours';
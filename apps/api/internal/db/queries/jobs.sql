@@ -0,0 +1,42 @@
+-- name: CreateJob :one
+insert into jobs (user_id, payload, total, playlist_id, warning)
+values ($1, $2, $3, $4, $5)
+returning id, user_id, status, payload, results, total, processed, error, created_at, updated_at, started_at, finished_at, playlist_id, warning;
+
+-- name: GetJobForUser :one
+select id, user_id, status, payload, results, total, processed, error, created_at, updated_at, started_at, finished_at, playlist_id, warning
+from jobs
+where id = $1 and user_id = $2;
+
+-- name: ClaimNextJob :one
+update jobs
+set status = 'running', started_at = coalesce(started_at, now()), updated_at = now()
+where id = (
+    select id from jobs
+    where status = 'queued'
+       or (status = 'running' and updated_at < now() - make_interval(secs => sqlc.arg(lease_seconds)::int))
+    order by created_at
+    limit 1
+    for update skip locked
+)
+returning id, user_id, status, payload, results, total, processed, error, created_at, updated_at, started_at, finished_at, playlist_id, warning;
+
+-- name: UpdateJobProgress :exec
+update jobs
+set processed = $2, results = $3, updated_at = now()
+where id = $1;
+
+-- name: CompleteJob :exec
+update jobs
+set status = 'completed', updated_at = now(), finished_at = now()
+where id = $1;
+
+-- name: FailJob :exec
+update jobs
+set status = 'failed', error = $2, updated_at = now(), finished_at = now()
+where id = $1;
+
+-- name: RequeueJob :exec
+update jobs
+set status = 'queued', updated_at = now()
+where id = $1 and status = 'running';
@@ -0,0 +1,34 @@
+-- name: ListActiveLuaScripts :many
+select id, name, version, source, active, created_by, created_at, activated_at
+from lua_scripts
+where active
+order by name;
+
+-- name: ListLuaScriptVersions :many
+select id, name, version, source, active, created_by, created_at, activated_at
+from lua_scripts
+where name = $1
+order by version desc;
+
+-- name: GetLuaScriptVersion :one
+select id, name, version, source, active, created_by, created_at, activated_at
+from lua_scripts
+where name = $1 and version = $2;
+
+-- name: CreateLuaScriptVersion :one
+insert into lua_scripts (name, version, source, created_by)
+values ($1, (select coalesce(max(version), 0) + 1 from lua_scripts where name = $1), $2, $3)
+returning id, name, version, source, active, created_by, created_at, activated_at;
+
+-- name: DeactivateLuaScript :exec
+update lua_scripts
+set active = false,
+    activated_at = null
+where name = $1 and active;
+
+-- name: ActivateLuaScriptVersion :one
+update lua_scripts
+set active = true,
+    activated_at = now()
+where name = $1 and version = $2
+returning id, name, version, source, active, created_by, created_at, activated_at;
@@ -3,6 +3,11 @@ insert into playlists (user_id, name)
 values ($1, $2)
 returning id, user_id, name, created_at, updated_at;
 
+-- name: GetPlaylistByID :one
+select id, user_id, name, created_at, updated_at
+from playlists
+where id = $1 and user_id = $2;
+
 -- name: GetPlaylistByName :one
 select id, user_id, name, created_at, updated_at
 from playlists
@@ -40,6 +45,12 @@ values ($1, $2, $3)
 on conflict (playlist_id, video_id) do nothing
 returning playlist_id, video_id, position, created_at;
 
+-- name: AddVideosToPlaylist :exec
+insert into playlist_videos (playlist_id, video_id, position)
+select sqlc.arg(playlist_id)::bigint, v.video_id, v.position
+from unnest(sqlc.arg(video_ids)::bigint[], sqlc.arg(positions)::int[]) as v(video_id, position)
+on conflict (playlist_id, video_id) do nothing;
+
 -- name: AddVideoToPlaylistByName :exec
 insert into playlist_videos (playlist_id, video_id, position)
 select p.id, $2, $3
@@ -54,20 +65,3 @@ where pv.playlist_id = p.id
   and p.user_id = $1
   and p.name = $2
   and pv.video_id = $3;
-
--- name: GetPlaylistVideos :many
-select v.id, v.video_id, v.normalized_url, v.original_url, v.title, v.channel, v.user_id, v.created_at, pv.position, pv.created_at as added_at
-from playlist_videos pv
-join videos v on pv.video_id = v.id
-join playlists p on pv.playlist_id = p.id
-where p.user_id = $1 and p.name = $2
-order by pv.position, pv.created_at;
-
--- name: GetPlaylistVideosWithSearch :many
-select v.id, v.video_id, v.normalized_url, v.original_url, v.title, v.channel, v.user_id, v.created_at, pv.position, pv.created_at as added_at
-from playlist_videos pv
-join videos v on pv.video_id = v.id
-join playlists p on pv.playlist_id = p.id
-where p.user_id = $1 and p.name = $2
-  and (v.title ILIKE $3 OR v.channel ILIKE $3)
-order by pv.position, pv.created_at;
\ No newline at end of file
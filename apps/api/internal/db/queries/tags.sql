@@ -3,6 +3,12 @@ insert into tags (user_id, name, color)
 values ($1, $2, $3)
 returning id, user_id, name, color, created_at, updated_at;
 
+-- name: EnsureTag :one
+insert into tags (user_id, name, color)
+values ($1, $2, $3)
+on conflict (user_id, name) do update set name = excluded.name
+returning id, user_id, name, color, created_at, updated_at;
+
 -- name: ListTags :many
 select id, user_id, name, color, created_at, updated_at
 from tags
@@ -27,13 +33,13 @@ where id = $1 and user_id = $2;
 -- name: AddVideoTags :exec
 insert into video_tags (video_id, tag_id)
 select v, t
-from unnest($1::bigint[]) as v(video_id)
-cross join unnest($2::bigint[]) as t(tag_id)
+from unnest(sqlc.arg(video_ids)::bigint[]) as v(video_id)
+cross join unnest(sqlc.arg(tag_ids)::bigint[]) as t(tag_id)
 on conflict (video_id, tag_id) do nothing;
 
 -- name: RemoveVideoTags :exec
 delete from video_tags
-where video_id = $1 and tag_id = ANY($2::bigint[]);
+where video_id = sqlc.arg(video_id) and tag_id = ANY(sqlc.arg(tag_ids)::bigint[]);
 
 -- name: GetVideoTags :many
 select t.id, t.user_id, t.name, t.color, t.created_at, t.updated_at
@@ -42,7 +48,10 @@ join tags t on vt.tag_id = t.id
 where vt.video_id = $1;
 
 -- name: ListVideosWithTags :many
-select v.id, v.video_id, v.normalized_url, v.original_url, v.title, v.channel, v.user_id, v.created_at,
+select v.id, v.video_id, v.normalized_url, v.original_url, v.title, v.channel, v.user_id, v.created_at, v.platform, v.start_seconds,
+       v.thumbnail_url, v.duration_seconds, v.published_at, v.channel_external_id, v.metadata_status, v.metadata_attempts, v.metadata_next_attempt_at, v.metadata_error,
+       v.view_count, v.channel_handle, v.channel_id, v.availability, v.availability_reason, v.last_checked_at, v.availability_next_check_at,
+       v.thumbnail_hash, v.thumbnail_source_url, v.thumbnail_cache_attempts, v.thumbnail_cache_next_attempt_at,
        t.id as tag_id, t.name as tag_name, t.color as tag_color
 from videos v
 left join video_tags vt on v.id = vt.video_id
@@ -51,26 +60,17 @@ where v.user_id = $1
 order by v.created_at desc;
 
 -- name: FilterVideosByTags :many
-select distinct v.id, v.video_id, v.normalized_url, v.original_url, v.title, v.channel, v.user_id, v.created_at
+select distinct v.id, v.video_id, v.normalized_url, v.original_url, v.title, v.channel, v.user_id, v.created_at, v.platform, v.start_seconds,
+       v.thumbnail_url, v.duration_seconds, v.published_at, v.channel_external_id, v.metadata_status, v.metadata_attempts, v.metadata_next_attempt_at, v.metadata_error,
+       v.view_count, v.channel_handle, v.channel_id, v.availability, v.availability_reason, v.last_checked_at, v.availability_next_check_at,
+       v.thumbnail_hash, v.thumbnail_source_url, v.thumbnail_cache_attempts, v.thumbnail_cache_next_attempt_at
 from videos v
 join video_tags vt on v.id = vt.video_id
-where v.user_id = $1 and vt.tag_id = ANY($2::bigint[])
+where v.user_id = sqlc.arg(user_id) and vt.tag_id = ANY(sqlc.arg(tag_ids)::bigint[])
 order by v.created_at desc;
 
 -- name: GetVideoTagsForVideos :many
 select vt.video_id, t.id as tag_id, t.name as tag_name, t.color as tag_color
 from video_tags vt
 join tags t on vt.tag_id = t.id
-where vt.video_id = ANY($1::bigint[]);
-
--- name: FilterVideosByTagsAnd :many
-select v.id, v.video_id, v.normalized_url, v.original_url, v.title, v.channel, v.user_id, v.created_at
-from videos v
-where v.user_id = $1
-  and (
-    select count(distinct vt.tag_id)
-    from video_tags vt
-    where vt.video_id = v.id
-      and vt.tag_id = ANY($2::bigint[])
-  ) = array_length($2::bigint[], 1)
-order by v.created_at desc;
\ No newline at end of file
+where vt.video_id = ANY(sqlc.arg(video_ids)::bigint[]);
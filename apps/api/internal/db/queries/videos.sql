@@ -1,84 +1,153 @@
--- name: CreateVideo :one
-INSERT INTO videos (video_id, normalized_url, original_url, title, channel, user_id)
-VALUES ($1, $2, $3, $4, $5, $6)
-ON CONFLICT (normalized_url) DO NOTHING
-RETURNING id, video_id, normalized_url, original_url, title, channel, user_id, created_at;
-
--- name: GetVideoByURL :one
-SELECT id, video_id, normalized_url, original_url, title, channel, user_id, created_at
-FROM videos
-WHERE normalized_url = $1;
+-- name: CreateVideos :many
+INSERT INTO videos (user_id, video_id, normalized_url, original_url, title, channel, platform, start_seconds,
+    thumbnail_url, duration_seconds, published_at, view_count, channel_handle)
+SELECT sqlc.arg(user_id), v.video_id, v.normalized_url, v.original_url, v.title, v.channel, v.platform, v.start_seconds,
+    NULLIF(v.thumbnail_url, ''), NULLIF(v.duration_seconds, 0), v.published_at, NULLIF(v.view_count, 0), NULLIF(v.channel_handle, '')
+FROM unnest(sqlc.arg(video_ids)::text[], sqlc.arg(normalized_urls)::text[], sqlc.arg(original_urls)::text[],
+    sqlc.arg(titles)::text[], sqlc.arg(channels)::text[], sqlc.arg(platforms)::text[], sqlc.arg(start_seconds)::int[],
+    sqlc.arg(thumbnail_urls)::text[], sqlc.arg(duration_seconds)::int[], sqlc.arg(published_ats)::timestamptz[],
+    sqlc.arg(view_counts)::bigint[], sqlc.arg(channel_handles)::text[])
+    AS v(video_id, normalized_url, original_url, title, channel, platform, start_seconds,
+    thumbnail_url, duration_seconds, published_at, view_count, channel_handle)
+ON CONFLICT (user_id, normalized_url, start_seconds) DO UPDATE
+SET title = COALESCE(NULLIF(videos.title, ''), EXCLUDED.title),
+    channel = COALESCE(NULLIF(videos.channel, ''), EXCLUDED.channel),
+    thumbnail_url = COALESCE(videos.thumbnail_url, EXCLUDED.thumbnail_url),
+    duration_seconds = COALESCE(videos.duration_seconds, EXCLUDED.duration_seconds),
+    published_at = COALESCE(videos.published_at, EXCLUDED.published_at),
+    view_count = GREATEST(videos.view_count, EXCLUDED.view_count),
+    channel_handle = COALESCE(videos.channel_handle, EXCLUDED.channel_handle)
+WHERE (videos.title = '' AND EXCLUDED.title <> '')
+   OR (videos.channel = '' AND EXCLUDED.channel <> '')
+   OR (videos.thumbnail_url IS NULL AND EXCLUDED.thumbnail_url IS NOT NULL)
+   OR (videos.duration_seconds IS NULL AND EXCLUDED.duration_seconds IS NOT NULL)
+   OR (videos.published_at IS NULL AND EXCLUDED.published_at IS NOT NULL)
+   OR (videos.channel_handle IS NULL AND EXCLUDED.channel_handle IS NOT NULL)
+   OR EXCLUDED.view_count > COALESCE(videos.view_count, -1)
+RETURNING id, video_id, normalized_url, original_url, title, channel, user_id, created_at, platform, start_seconds,
+    thumbnail_url, duration_seconds, published_at, channel_external_id, metadata_status, metadata_attempts, metadata_next_attempt_at, metadata_error,
+    view_count, channel_handle, channel_id, availability, availability_reason, last_checked_at, availability_next_check_at,
+    thumbnail_hash, thumbnail_source_url, thumbnail_cache_attempts, thumbnail_cache_next_attempt_at,
+    (xmax = 0) AS inserted;
 
--- name: GetVideoByID :one
-SELECT id, video_id, normalized_url, original_url, title, channel, user_id, created_at
+-- name: ListVideoIDsByURL :many
+SELECT id, normalized_url, start_seconds
 FROM videos
-WHERE id = $1;
+WHERE user_id = sqlc.arg(user_id) AND normalized_url = ANY(sqlc.arg(normalized_urls)::text[]);
 
--- name: ListVideos :many
-SELECT id, video_id, normalized_url, original_url, title, channel, user_id, created_at
-FROM videos
-WHERE user_id = $1
-ORDER BY created_at DESC;
+-- name: DeleteVideo :exec
+DELETE FROM videos
+WHERE id = $1 AND user_id = $2;
 
--- name: ListVideosFiltered :many
-SELECT id, video_id, normalized_url, original_url, title, channel, user_id, created_at
-FROM videos
-WHERE user_id = $1
-  AND channel = ANY($2::text[])
-ORDER BY created_at DESC;
+-- name: DeleteVideos :exec
+DELETE FROM videos
+WHERE id = ANY(sqlc.arg(ids)::bigint[]) AND user_id = sqlc.arg(user_id);
 
--- name: ListVideosUnassigned :many
-SELECT id, video_id, normalized_url, original_url, title, channel, user_id, created_at
-FROM videos
-WHERE user_id = $1
-  AND id NOT IN (SELECT DISTINCT video_id FROM playlist_videos)
-ORDER BY created_at DESC;
 
--- name: ListVideosUnassignedFiltered :many
-SELECT id, video_id, normalized_url, original_url, title, channel, user_id, created_at
+-- name: GetVideoForUser :one
+SELECT id, video_id, normalized_url, original_url, title, channel, user_id, created_at, platform, start_seconds,
+       thumbnail_url, duration_seconds, published_at, channel_external_id, metadata_status, metadata_attempts, metadata_next_attempt_at, metadata_error,
+       view_count, channel_handle, channel_id, availability, availability_reason, last_checked_at, availability_next_check_at,
+       thumbnail_hash, thumbnail_source_url, thumbnail_cache_attempts, thumbnail_cache_next_attempt_at
 FROM videos
-WHERE user_id = $1
-  AND channel = ANY($2::text[])
-  AND id NOT IN (SELECT DISTINCT video_id FROM playlist_videos)
-ORDER BY created_at DESC;
+WHERE id = $1 AND user_id = $2;
 
--- name: ListVideosWithSearch :many
-SELECT id, video_id, normalized_url, original_url, title, channel, user_id, created_at
-FROM videos
-WHERE user_id = $1
-  AND (title ILIKE $2 OR channel ILIKE $2)
-ORDER BY created_at DESC;
+-- name: ClaimVideosForEnrichment :many
+UPDATE videos
+SET metadata_next_attempt_at = now() + make_interval(secs => sqlc.arg(lease_seconds)::int)
+WHERE id IN (
+    SELECT id FROM videos
+    WHERE metadata_status = 'pending' AND metadata_next_attempt_at <= now()
+    ORDER BY metadata_next_attempt_at
+    LIMIT sqlc.arg(limit)
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, video_id, normalized_url, platform, metadata_attempts;
 
--- name: ListVideosFilteredWithSearch :many
-SELECT id, video_id, normalized_url, original_url, title, channel, user_id, created_at
-FROM videos
-WHERE user_id = $1
-  AND channel = ANY($2::text[])
-  AND (title ILIKE $3 OR channel ILIKE $3)
-ORDER BY created_at DESC;
+-- name: SaveVideoMetadata :exec
+UPDATE videos
+SET thumbnail_url = COALESCE($2, thumbnail_url),
+    duration_seconds = COALESCE($3, duration_seconds),
+    published_at = COALESCE($4, published_at),
+    channel_external_id = COALESCE($5, channel_external_id),
+    metadata_status = 'enriched',
+    metadata_error = NULL,
+    metadata_next_attempt_at = NULL
+WHERE id = $1;
 
--- name: ListVideosUnassignedWithSearch :many
-SELECT id, video_id, normalized_url, original_url, title, channel, user_id, created_at
-FROM videos
-WHERE user_id = $1
-  AND id NOT IN (SELECT DISTINCT video_id FROM playlist_videos)
-  AND (title ILIKE $2 OR channel ILIKE $2)
-ORDER BY created_at DESC;
+-- name: RetryVideoMetadata :exec
+UPDATE videos
+SET metadata_attempts = metadata_attempts + 1,
+    metadata_error = sqlc.arg(metadata_error),
+    metadata_next_attempt_at = now() + make_interval(secs => sqlc.arg(delay_seconds)::int)
+WHERE id = sqlc.arg(id);
 
--- name: ListVideosUnassignedFilteredWithSearch :many
-SELECT id, video_id, normalized_url, original_url, title, channel, user_id, created_at
-FROM videos
-WHERE user_id = $1
-  AND channel = ANY($2::text[])
-  AND id NOT IN (SELECT DISTINCT video_id FROM playlist_videos)
-  AND (title ILIKE $3 OR channel ILIKE $3)
-ORDER BY created_at DESC;
+-- name: FailVideoMetadata :exec
+UPDATE videos
+SET metadata_status = 'failed',
+    metadata_attempts = metadata_attempts + 1,
+    metadata_error = $2,
+    metadata_next_attempt_at = NULL
+WHERE id = $1;
 
--- name: DeleteVideo :exec
-DELETE FROM videos
-WHERE id = $1 AND user_id = $2;
+-- name: ClaimVideosForAvailabilityCheck :many
+UPDATE videos
+SET availability_next_check_at = now() + make_interval(secs => sqlc.arg(lease_seconds)::int)
+WHERE id IN (
+    SELECT id FROM videos
+    WHERE availability_next_check_at <= now()
+    ORDER BY availability_next_check_at
+    LIMIT sqlc.arg(limit)
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, video_id, normalized_url, platform;
 
--- name: DeleteVideos :exec
-DELETE FROM videos
-WHERE id = ANY($1::bigint[]) AND user_id = $2;
+-- name: SetVideoAvailability :exec
+UPDATE videos
+SET availability = sqlc.arg(availability),
+    availability_reason = sqlc.arg(availability_reason),
+    last_checked_at = now(),
+    availability_next_check_at = now() + make_interval(secs => sqlc.arg(next_check_seconds)::int)
+WHERE platform = sqlc.arg(platform) AND video_id = sqlc.arg(video_id);
+
+-- name: RetryVideoAvailabilityCheck :exec
+UPDATE videos
+SET availability_next_check_at = now() + make_interval(secs => sqlc.arg(delay_seconds)::int)
+WHERE id = sqlc.arg(id);
+
+-- name: ClaimVideosForThumbnailCache :many
+UPDATE videos
+SET thumbnail_cache_next_attempt_at = now() + make_interval(secs => sqlc.arg(lease_seconds)::int)
+WHERE id IN (
+    SELECT id FROM videos
+    WHERE thumbnail_url IS NOT NULL
+      AND thumbnail_source_url IS DISTINCT FROM thumbnail_url
+      AND thumbnail_cache_next_attempt_at <= now()
+    ORDER BY thumbnail_cache_next_attempt_at
+    LIMIT sqlc.arg(limit)
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, thumbnail_url, thumbnail_cache_attempts;
+
+-- name: SaveVideoThumbnail :exec
+UPDATE videos
+SET thumbnail_hash = $2,
+    thumbnail_source_url = thumbnail_url,
+    thumbnail_cache_attempts = 0
+WHERE thumbnail_url = $1;
+
+-- name: RetryVideoThumbnail :exec
+UPDATE videos
+SET thumbnail_cache_attempts = thumbnail_cache_attempts + 1,
+    thumbnail_cache_next_attempt_at = now() + make_interval(secs => sqlc.arg(delay_seconds)::int)
+WHERE id = sqlc.arg(id);
+
+-- name: ListReferencedThumbnailHashes :many
+SELECT DISTINCT thumbnail_hash FROM videos
+WHERE thumbnail_hash = ANY(sqlc.arg(hashes)::text[]);
 
+-- name: UserHasThumbnail :one
+SELECT EXISTS (
+    SELECT 1 FROM videos
+    WHERE user_id = $1 AND thumbnail_hash = $2
+);
@@ -20,12 +20,12 @@ on conflict (video_id, tag_id) do nothing
 `
 
 type AddVideoTagsParams struct {
-	Column1 []int64 `json:"column_1"`
-	Column2 []int64 `json:"column_2"`
+	VideoIds []int64 `json:"video_ids"`
+	TagIds   []int64 `json:"tag_ids"`
 }
 
 func (q *Queries) AddVideoTags(ctx context.Context, arg *AddVideoTagsParams) error {
-	_, err := q.db.Exec(ctx, AddVideoTags, arg.Column1, arg.Column2)
+	_, err := q.db.Exec(ctx, AddVideoTags, arg.VideoIds, arg.TagIds)
 	return err
 }
 
@@ -70,75 +70,90 @@ func (q *Queries) DeleteTag(ctx context.Context, arg *DeleteTagParams) error {
 	return err
 }
 
-const FilterVideosByTags = `-- name: FilterVideosByTags :many
-select distinct v.id, v.video_id, v.normalized_url, v.original_url, v.title, v.channel, v.user_id, v.created_at
-from videos v
-join video_tags vt on v.id = vt.video_id
-where v.user_id = $1 and vt.tag_id = ANY($2::bigint[])
-order by v.created_at desc
+const EnsureTag = `-- name: EnsureTag :one
+insert into tags (user_id, name, color)
+values ($1, $2, $3)
+on conflict (user_id, name) do update set name = excluded.name
+returning id, user_id, name, color, created_at, updated_at
 `
 
-type FilterVideosByTagsParams struct {
-	UserID  string  `json:"user_id"`
-	Column2 []int64 `json:"column_2"`
+type EnsureTagParams struct {
+	UserID string `json:"user_id"`
+	Name   string `json:"name"`
+	Color  string `json:"color"`
 }
 
-func (q *Queries) FilterVideosByTags(ctx context.Context, arg *FilterVideosByTagsParams) ([]*Video, error) {
-	rows, err := q.db.Query(ctx, FilterVideosByTags, arg.UserID, arg.Column2)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	items := []*Video{}
-	for rows.Next() {
-		var i Video
-		if err := rows.Scan(
-			&i.ID,
-			&i.VideoID,
-			&i.NormalizedUrl,
-			&i.OriginalUrl,
-			&i.Title,
-			&i.Channel,
-			&i.UserID,
-			&i.CreatedAt,
-		); err != nil {
-			return nil, err
-		}
-		items = append(items, &i)
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return items, nil
+func (q *Queries) EnsureTag(ctx context.Context, arg *EnsureTagParams) (*Tag, error) {
+	row := q.db.QueryRow(ctx, EnsureTag, arg.UserID, arg.Name, arg.Color)
+	var i Tag
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Name,
+		&i.Color,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return &i, err
 }
 
-const FilterVideosByTagsAnd = `-- name: FilterVideosByTagsAnd :many
-select v.id, v.video_id, v.normalized_url, v.original_url, v.title, v.channel, v.user_id, v.created_at
+const FilterVideosByTags = `-- name: FilterVideosByTags :many
+select distinct v.id, v.video_id, v.normalized_url, v.original_url, v.title, v.channel, v.user_id, v.created_at, v.platform, v.start_seconds,
+       v.thumbnail_url, v.duration_seconds, v.published_at, v.channel_external_id, v.metadata_status, v.metadata_attempts, v.metadata_next_attempt_at, v.metadata_error,
+       v.view_count, v.channel_handle, v.channel_id, v.availability, v.availability_reason, v.last_checked_at, v.availability_next_check_at,
+       v.thumbnail_hash, v.thumbnail_source_url, v.thumbnail_cache_attempts, v.thumbnail_cache_next_attempt_at
 from videos v
-where v.user_id = $1
-  and (
-    select count(distinct vt.tag_id)
-    from video_tags vt
-    where vt.video_id = v.id
-      and vt.tag_id = ANY($2::bigint[])
-  ) = array_length($2::bigint[], 1)
+join video_tags vt on v.id = vt.video_id
+where v.user_id = $1 and vt.tag_id = ANY($2::bigint[])
 order by v.created_at desc
 `
 
-type FilterVideosByTagsAndParams struct {
-	UserID  string  `json:"user_id"`
-	Column2 []int64 `json:"column_2"`
+type FilterVideosByTagsParams struct {
+	UserID string  `json:"user_id"`
+	TagIds []int64 `json:"tag_ids"`
+}
+
+type FilterVideosByTagsRow struct {
+	ID                          int64              `json:"id"`
+	VideoID                     string             `json:"video_id"`
+	NormalizedUrl               string             `json:"normalized_url"`
+	OriginalUrl                 string             `json:"original_url"`
+	Title                       string             `json:"title"`
+	Channel                     string             `json:"channel"`
+	UserID                      string             `json:"user_id"`
+	CreatedAt                   pgtype.Timestamptz `json:"created_at"`
+	Platform                    string             `json:"platform"`
+	StartSeconds                int32              `json:"start_seconds"`
+	ThumbnailUrl                *string            `json:"thumbnail_url"`
+	DurationSeconds             *int32             `json:"duration_seconds"`
+	PublishedAt                 pgtype.Timestamptz `json:"published_at"`
+	ChannelExternalID           *string            `json:"channel_external_id"`
+	MetadataStatus              string             `json:"metadata_status"`
+	MetadataAttempts            int32              `json:"metadata_attempts"`
+	MetadataNextAttemptAt       pgtype.Timestamptz `json:"metadata_next_attempt_at"`
+	MetadataError               *string            `json:"metadata_error"`
+	ViewCount                   *int64             `json:"view_count"`
+	ChannelHandle               *string            `json:"channel_handle"`
+	ChannelID                   *int64             `json:"channel_id"`
+	Availability                string             `json:"availability"`
+	AvailabilityReason          *string            `json:"availability_reason"`
+	LastCheckedAt               pgtype.Timestamptz `json:"last_checked_at"`
+	AvailabilityNextCheckAt     pgtype.Timestamptz `json:"availability_next_check_at"`
+	ThumbnailHash               *string            `json:"thumbnail_hash"`
+	ThumbnailSourceUrl          *string            `json:"thumbnail_source_url"`
+	ThumbnailCacheAttempts      int32              `json:"thumbnail_cache_attempts"`
+	ThumbnailCacheNextAttemptAt pgtype.Timestamptz `json:"thumbnail_cache_next_attempt_at"`
 }
 
-func (q *Queries) FilterVideosByTagsAnd(ctx context.Context, arg *FilterVideosByTagsAndParams) ([]*Video, error) {
-	rows, err := q.db.Query(ctx, FilterVideosByTagsAnd, arg.UserID, arg.Column2)
+func (q *Queries) FilterVideosByTags(ctx context.Context, arg *FilterVideosByTagsParams) ([]*FilterVideosByTagsRow, error) {
+	rows, err := q.db.Query(ctx, FilterVideosByTags, arg.UserID, arg.TagIds)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	items := []*Video{}
+	items := []*FilterVideosByTagsRow{}
 	for rows.Next() {
-		var i Video
+		var i FilterVideosByTagsRow
 		if err := rows.Scan(
 			&i.ID,
 			&i.VideoID,
@@ -148,6 +163,27 @@ func (q *Queries) FilterVideosByTagsAnd(ctx context.Context, arg *FilterVideosBy
 			&i.Channel,
 			&i.UserID,
 			&i.CreatedAt,
+			&i.Platform,
+			&i.StartSeconds,
+			&i.ThumbnailUrl,
+			&i.DurationSeconds,
+			&i.PublishedAt,
+			&i.ChannelExternalID,
+			&i.MetadataStatus,
+			&i.MetadataAttempts,
+			&i.MetadataNextAttemptAt,
+			&i.MetadataError,
+			&i.ViewCount,
+			&i.ChannelHandle,
+			&i.ChannelID,
+			&i.Availability,
+			&i.AvailabilityReason,
+			&i.LastCheckedAt,
+			&i.AvailabilityNextCheckAt,
+			&i.ThumbnailHash,
+			&i.ThumbnailSourceUrl,
+			&i.ThumbnailCacheAttempts,
+			&i.ThumbnailCacheNextAttemptAt,
 		); err != nil {
 			return nil, err
 		}
@@ -232,8 +268,8 @@ type GetVideoTagsForVideosRow struct {
 	TagColor string `json:"tag_color"`
 }
 
-func (q *Queries) GetVideoTagsForVideos(ctx context.Context, dollar_1 []int64) ([]*GetVideoTagsForVideosRow, error) {
-	rows, err := q.db.Query(ctx, GetVideoTagsForVideos, dollar_1)
+func (q *Queries) GetVideoTagsForVideos(ctx context.Context, videoIds []int64) ([]*GetVideoTagsForVideosRow, error) {
+	rows, err := q.db.Query(ctx, GetVideoTagsForVideos, videoIds)
 	if err != nil {
 		return nil, err
 	}
@@ -292,7 +328,10 @@ func (q *Queries) ListTags(ctx context.Context, userID string) ([]*Tag, error) {
 }
 
 const ListVideosWithTags = `-- name: ListVideosWithTags :many
-select v.id, v.video_id, v.normalized_url, v.original_url, v.title, v.channel, v.user_id, v.created_at,
+select v.id, v.video_id, v.normalized_url, v.original_url, v.title, v.channel, v.user_id, v.created_at, v.platform, v.start_seconds,
+       v.thumbnail_url, v.duration_seconds, v.published_at, v.channel_external_id, v.metadata_status, v.metadata_attempts, v.metadata_next_attempt_at, v.metadata_error,
+       v.view_count, v.channel_handle, v.channel_id, v.availability, v.availability_reason, v.last_checked_at, v.availability_next_check_at,
+       v.thumbnail_hash, v.thumbnail_source_url, v.thumbnail_cache_attempts, v.thumbnail_cache_next_attempt_at,
        t.id as tag_id, t.name as tag_name, t.color as tag_color
 from videos v
 left join video_tags vt on v.id = vt.video_id
@@ -302,17 +341,38 @@ order by v.created_at desc
 `
 
 type ListVideosWithTagsRow struct {
-	ID            int64              `json:"id"`
-	VideoID       string             `json:"video_id"`
-	NormalizedUrl string             `json:"normalized_url"`
-	OriginalUrl   string             `json:"original_url"`
-	Title         string             `json:"title"`
-	Channel       string             `json:"channel"`
-	UserID        string             `json:"user_id"`
-	CreatedAt     pgtype.Timestamptz `json:"created_at"`
-	TagID         *int64             `json:"tag_id"`
-	TagName       *string            `json:"tag_name"`
-	TagColor      *string            `json:"tag_color"`
+	ID                          int64              `json:"id"`
+	VideoID                     string             `json:"video_id"`
+	NormalizedUrl               string             `json:"normalized_url"`
+	OriginalUrl                 string             `json:"original_url"`
+	Title                       string             `json:"title"`
+	Channel                     string             `json:"channel"`
+	UserID                      string             `json:"user_id"`
+	CreatedAt                   pgtype.Timestamptz `json:"created_at"`
+	Platform                    string             `json:"platform"`
+	StartSeconds                int32              `json:"start_seconds"`
+	ThumbnailUrl                *string            `json:"thumbnail_url"`
+	DurationSeconds             *int32             `json:"duration_seconds"`
+	PublishedAt                 pgtype.Timestamptz `json:"published_at"`
+	ChannelExternalID           *string            `json:"channel_external_id"`
+	MetadataStatus              string             `json:"metadata_status"`
+	MetadataAttempts            int32              `json:"metadata_attempts"`
+	MetadataNextAttemptAt       pgtype.Timestamptz `json:"metadata_next_attempt_at"`
+	MetadataError               *string            `json:"metadata_error"`
+	ViewCount                   *int64             `json:"view_count"`
+	ChannelHandle               *string            `json:"channel_handle"`
+	ChannelID                   *int64             `json:"channel_id"`
+	Availability                string             `json:"availability"`
+	AvailabilityReason          *string            `json:"availability_reason"`
+	LastCheckedAt               pgtype.Timestamptz `json:"last_checked_at"`
+	AvailabilityNextCheckAt     pgtype.Timestamptz `json:"availability_next_check_at"`
+	ThumbnailHash               *string            `json:"thumbnail_hash"`
+	ThumbnailSourceUrl          *string            `json:"thumbnail_source_url"`
+	ThumbnailCacheAttempts      int32              `json:"thumbnail_cache_attempts"`
+	ThumbnailCacheNextAttemptAt pgtype.Timestamptz `json:"thumbnail_cache_next_attempt_at"`
+	TagID                       *int64             `json:"tag_id"`
+	TagName                     *string            `json:"tag_name"`
+	TagColor                    *string            `json:"tag_color"`
 }
 
 func (q *Queries) ListVideosWithTags(ctx context.Context, userID string) ([]*ListVideosWithTagsRow, error) {
@@ -333,6 +393,27 @@ func (q *Queries) ListVideosWithTags(ctx context.Context, userID string) ([]*Lis
 			&i.Channel,
 			&i.UserID,
 			&i.CreatedAt,
+			&i.Platform,
+			&i.StartSeconds,
+			&i.ThumbnailUrl,
+			&i.DurationSeconds,
+			&i.PublishedAt,
+			&i.ChannelExternalID,
+			&i.MetadataStatus,
+			&i.MetadataAttempts,
+			&i.MetadataNextAttemptAt,
+			&i.MetadataError,
+			&i.ViewCount,
+			&i.ChannelHandle,
+			&i.ChannelID,
+			&i.Availability,
+			&i.AvailabilityReason,
+			&i.LastCheckedAt,
+			&i.AvailabilityNextCheckAt,
+			&i.ThumbnailHash,
+			&i.ThumbnailSourceUrl,
+			&i.ThumbnailCacheAttempts,
+			&i.ThumbnailCacheNextAttemptAt,
 			&i.TagID,
 			&i.TagName,
 			&i.TagColor,
@@ -354,11 +435,11 @@ where video_id = $1 and tag_id = ANY($2::bigint[])
 
 type RemoveVideoTagsParams struct {
 	VideoID int64   `json:"video_id"`
-	Column2 []int64 `json:"column_2"`
+	TagIds  []int64 `json:"tag_ids"`
 }
 
 func (q *Queries) RemoveVideoTags(ctx context.Context, arg *RemoveVideoTagsParams) error {
-	_, err := q.db.Exec(ctx, RemoveVideoTags, arg.VideoID, arg.Column2)
+	_, err := q.db.Exec(ctx, RemoveVideoTags, arg.VideoID, arg.TagIds)
 	return err
 }
 
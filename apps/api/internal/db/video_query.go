@@ -0,0 +1,346 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrRelevanceWithoutSearch indicates a VideoQuery sorted by relevance has no search.
+var ErrRelevanceWithoutSearch = errors.New("relevance sort requires a search")
+
+// ErrPositionWithoutPlaylist indicates a VideoQuery sorted by position has no playlist.
+var ErrPositionWithoutPlaylist = errors.New("position sort requires a playlist")
+
+// VideoSort is a field a VideoQuery sorts by.
+type VideoSort string
+
+const (
+	VideoSortCreatedAt VideoSort = "created_at"
+	VideoSortTitle     VideoSort = "title"
+	VideoSortChannel   VideoSort = "channel"
+	VideoSortRelevance VideoSort = "relevance"
+	VideoSortPosition  VideoSort = "position"
+)
+
+// VideoQuery lists a user's videos. Its filters are optional and combine with AND.
+type VideoQuery struct {
+	UserID string
+
+	// ID matches a single video, if it is not 0
+	ID int64
+	// Search matches titles, channels and tag names, in web search syntax
+	Search string
+	// HeadlineOptions are the ts_headline options of the search highlights
+	HeadlineOptions string
+	// Channels matches videos from any of the channels
+	Channels []string
+	// ChannelID matches the videos grouped under a channel, if it is not 0
+	ChannelID int64
+	// PlaylistID matches the videos in a playlist, if it is not 0; the rows then have their position
+	PlaylistID int64
+	// TagIDs matches videos having all of the tags
+	TagIDs []int64
+	// Unassigned matches videos that are in no playlist
+	Unassigned bool
+	// Platforms matches videos on any of the platforms
+	Platforms []string
+	// Availability matches videos with the availability status
+	Availability string
+
+	// Date ranges of when videos were saved and published. After bounds are inclusive, Before bounds
+	// exclusive, and zero times are no bound.
+	CreatedAfter    time.Time
+	CreatedBefore   time.Time
+	PublishedAfter  time.Time
+	PublishedBefore time.Time
+
+	// Sort and SortDesc order the videos, ties are broken by ID; the default is created_at
+	Sort     VideoSort
+	SortDesc bool
+	// After is the key of the last video of the previous page
+	After *VideoKey
+	// Limit is the maximum number of videos, or 0 for no limit
+	Limit int
+}
+
+// VideoKey is the position of a video in a VideoQuery's sort. Only the field sorted by and the ID
+// are compared.
+type VideoKey struct {
+	CreatedAt time.Time
+	// Text is the lower-cased title or channel
+	Text     string
+	Rank     float32
+	Position int32
+	ID       int64
+}
+
+// VideoQueryRow is a video listed by a VideoQuery, with how it matched the search if there is one.
+type VideoQueryRow struct {
+	Video            *Video
+	Rank             float32
+	TitleHighlight   string
+	ChannelHighlight string
+	// SortText is the lower-cased title or channel the query sorts by, lower-cased by the database
+	// so it continues the sort as the VideoKey Text of the next page; it is empty for other sorts
+	SortText string
+	// Position is the video's position in the query's playlist, or 0 without one
+	Position int32
+}
+
+// videoColumns are the columns of Video, in the order they are scanned
+// The search columns tag_names and search_vector are only matched against, never read.
+const videoColumns = `v.id, v.video_id, v.normalized_url, v.original_url, v.title, v.channel, v.user_id, v.created_at, v.platform, v.start_seconds,
+       v.thumbnail_url, v.duration_seconds, v.published_at, v.channel_external_id, v.metadata_status, v.metadata_attempts, v.metadata_next_attempt_at, v.metadata_error,
+       v.view_count, v.channel_handle, v.channel_id, v.availability, v.availability_reason, v.last_checked_at, v.availability_next_check_at,
+       v.thumbnail_hash, v.thumbnail_source_url, v.thumbnail_cache_attempts, v.thumbnail_cache_next_attempt_at`
+
+// queryArgs collects the arguments of a query
+type queryArgs []interface{}
+
+// add appends an argument and returns its placeholder
+func (a *queryArgs) add(value interface{}) string {
+	*a = append(*a, value)
+	return fmt.Sprintf("$%d", len(*a))
+}
+
+// SQL returns the statement listing the query's videos and its arguments.
+func (q *VideoQuery) SQL() (string, []interface{}, error) {
+	if q.Sort == VideoSortRelevance && q.Search == "" {
+		return "", nil, ErrRelevanceWithoutSearch
+	}
+	if q.Sort == VideoSortPosition && q.PlaylistID == 0 {
+		return "", nil, ErrPositionWithoutPlaylist
+	}
+
+	var args queryArgs
+	from, where := q.filters(&args)
+
+	var sql strings.Builder
+	sql.WriteString("SELECT " + videoColumns + ",\n")
+	if q.Search != "" {
+		options := args.add(q.HeadlineOptions)
+		sql.WriteString("       ts_rank_cd(v.search_vector, query) AS rank,\n")
+		sql.WriteString("       ts_headline('english', v.title, query, " + options + "::text) AS title_highlight,\n")
+		sql.WriteString("       ts_headline('english', v.channel, query, " + options + "::text) AS channel_highlight,\n")
+	} else {
+		sql.WriteString("       0::real AS rank, '' AS title_highlight, '' AS channel_highlight,\n")
+	}
+	sql.WriteString("       " + q.sortText() + " AS sort_text,\n")
+	if q.PlaylistID != 0 {
+		sql.WriteString("       pv.position\n")
+	} else {
+		sql.WriteString("       0 AS position\n")
+	}
+	sql.WriteString(from)
+
+	sortExpr, keyArg := q.sortKey()
+	if q.After != nil {
+		operator := ">"
+		if q.SortDesc {
+			operator = "<"
+		}
+		where = append(where, fmt.Sprintf("(%s, v.id) %s (%s, %s::bigint)",
+			sortExpr, operator, keyArg(&args, q.After), args.add(q.After.ID)))
+	}
+	sql.WriteString("WHERE " + strings.Join(where, "\n  AND ") + "\n")
+
+	direction := "ASC"
+	if q.SortDesc {
+		direction = "DESC"
+	}
+	sql.WriteString(fmt.Sprintf("ORDER BY %s %s, v.id %s", sortExpr, direction, direction))
+	if q.Limit > 0 {
+		sql.WriteString("\nLIMIT " + args.add(q.Limit))
+	}
+
+	return sql.String(), args, nil
+}
+
+// CountSQL returns the statement counting the query's videos, on every page, and its arguments.
+func (q *VideoQuery) CountSQL() (string, []interface{}) {
+	var args queryArgs
+	from, where := q.filters(&args)
+	return "SELECT count(*)\n" + from + "WHERE " + strings.Join(where, "\n  AND "), args
+}
+
+// filters returns the FROM clause and the WHERE conditions of the query's filters
+func (q *VideoQuery) filters(args *queryArgs) (string, []string) {
+	where := []string{"v.user_id = " + args.add(q.UserID)}
+	if q.ID != 0 {
+		where = append(where, "v.id = "+args.add(q.ID)+"::bigint")
+	}
+
+	from := "FROM videos v"
+	if q.PlaylistID != 0 {
+		from += "\nJOIN playlist_videos pv ON pv.video_id = v.id AND pv.playlist_id = " + args.add(q.PlaylistID) + "::bigint"
+	}
+	if q.Search != "" {
+		from += ", websearch_to_tsquery('english', " + args.add(q.Search) + "::text) AS query"
+		where = append(where, "v.search_vector @@ query")
+	}
+	from += "\n"
+
+	if len(q.Channels) > 0 {
+		where = append(where, "v.channel = ANY("+args.add(q.Channels)+"::text[])")
+	}
+	if q.ChannelID != 0 {
+		where = append(where, "v.channel_id = "+args.add(q.ChannelID)+"::bigint")
+	}
+	if tagIDs := uniqueIDs(q.TagIDs); len(tagIDs) > 0 {
+		where = append(where, fmt.Sprintf(`v.id IN (
+    SELECT video_id FROM video_tags
+    WHERE tag_id = ANY(%s::bigint[])
+    GROUP BY video_id
+    HAVING count(*) = %d
+  )`, args.add(tagIDs), len(tagIDs)))
+	}
+	if q.Unassigned {
+		where = append(where, "NOT EXISTS (SELECT 1 FROM playlist_videos pv WHERE pv.video_id = v.id)")
+	}
+	if len(q.Platforms) > 0 {
+		where = append(where, "v.platform = ANY("+args.add(q.Platforms)+"::text[])")
+	}
+	if q.Availability != "" {
+		where = append(where, "v.availability = "+args.add(q.Availability)+"::text")
+	}
+
+	if !q.CreatedAfter.IsZero() {
+		where = append(where, "v.created_at >= "+args.add(q.CreatedAfter)+"::timestamptz")
+	}
+	if !q.CreatedBefore.IsZero() {
+		where = append(where, "v.created_at < "+args.add(q.CreatedBefore)+"::timestamptz")
+	}
+	if !q.PublishedAfter.IsZero() {
+		where = append(where, "v.published_at >= "+args.add(q.PublishedAfter)+"::timestamptz")
+	}
+	if !q.PublishedBefore.IsZero() {
+		where = append(where, "v.published_at < "+args.add(q.PublishedBefore)+"::timestamptz")
+	}
+
+	return from, where
+}
+
+// sortKey returns the expression the query sorts by and a function adding a key's value of it as
+// an argument
+func (q *VideoQuery) sortKey() (string, func(*queryArgs, *VideoKey) string) {
+	switch q.Sort {
+	case VideoSortTitle, VideoSortChannel:
+		return q.sortText() + ` COLLATE "C"`, textKeyArg
+	case VideoSortRelevance:
+		return "ts_rank_cd(v.search_vector, query)", func(args *queryArgs, key *VideoKey) string {
+			return args.add(key.Rank) + "::real"
+		}
+	case VideoSortPosition:
+		return "pv.position", func(args *queryArgs, key *VideoKey) string {
+			return args.add(key.Position) + "::int"
+		}
+	default:
+		return "v.created_at", func(args *queryArgs, key *VideoKey) string {
+			return args.add(key.CreatedAt) + "::timestamptz"
+		}
+	}
+}
+
+// sortText returns the expression of the text the query sorts by, or an empty string if it sorts
+// by something else
+func (q *VideoQuery) sortText() string {
+	switch q.Sort {
+	case VideoSortTitle:
+		return "lower(v.title)"
+	case VideoSortChannel:
+		return "lower(v.channel)"
+	default:
+		return "''"
+	}
+}
+
+func textKeyArg(args *queryArgs, key *VideoKey) string {
+	return args.add(key.Text) + "::text"
+}
+
+// uniqueIDs returns the IDs without duplicates, in order
+func uniqueIDs(ids []int64) []int64 {
+	unique := make([]int64, 0, len(ids))
+	seen := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
+
+// QueryVideos lists the videos of a VideoQuery.
+func (q *Queries) QueryVideos(ctx context.Context, query *VideoQuery) ([]*VideoQueryRow, error) {
+	sql, args, err := query.SQL()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := q.db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*VideoQueryRow{}
+	for rows.Next() {
+		var i Video
+		var row VideoQueryRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.VideoID,
+			&i.NormalizedUrl,
+			&i.OriginalUrl,
+			&i.Title,
+			&i.Channel,
+			&i.UserID,
+			&i.CreatedAt,
+			&i.Platform,
+			&i.StartSeconds,
+			&i.ThumbnailUrl,
+			&i.DurationSeconds,
+			&i.PublishedAt,
+			&i.ChannelExternalID,
+			&i.MetadataStatus,
+			&i.MetadataAttempts,
+			&i.MetadataNextAttemptAt,
+			&i.MetadataError,
+			&i.ViewCount,
+			&i.ChannelHandle,
+			&i.ChannelID,
+			&i.Availability,
+			&i.AvailabilityReason,
+			&i.LastCheckedAt,
+			&i.AvailabilityNextCheckAt,
+			&i.ThumbnailHash,
+			&i.ThumbnailSourceUrl,
+			&i.ThumbnailCacheAttempts,
+			&i.ThumbnailCacheNextAttemptAt,
+			&row.Rank,
+			&row.TitleHighlight,
+			&row.ChannelHighlight,
+			&row.SortText,
+			&row.Position,
+		); err != nil {
+			return nil, err
+		}
+		row.Video = &i
+		items = append(items, &row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// CountVideos counts the videos of a VideoQuery, ignoring its page.
+func (q *Queries) CountVideos(ctx context.Context, query *VideoQuery) (int64, error) {
+	sql, args := query.CountSQL()
+	var count int64
+	err := q.db.QueryRow(ctx, sql, args...).Scan(&count)
+	return count, err
+}
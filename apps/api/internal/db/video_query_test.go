@@ -0,0 +1,249 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+var placeholderPattern = regexp.MustCompile(`\$(\d+)`)
+
+// checkPlaceholders fails unless the statement uses exactly the placeholders $1 to $len(args)
+func checkPlaceholders(t *testing.T, sql string, args []interface{}) {
+	t.Helper()
+	used := make(map[int]bool)
+	for _, match := range placeholderPattern.FindAllStringSubmatch(sql, -1) {
+		n, _ := strconv.Atoi(match[1])
+		used[n] = true
+	}
+	for n := range used {
+		if n < 1 || n > len(args) {
+			t.Errorf("placeholder $%d has no argument (%d arguments)", n, len(args))
+		}
+	}
+	for n := 1; n <= len(args); n++ {
+		if !used[n] {
+			t.Errorf("argument $%d (%v) is unused", n, args[n-1])
+		}
+	}
+}
+
+func mustContain(t *testing.T, sql string, parts ...string) {
+	t.Helper()
+	for _, part := range parts {
+		if !strings.Contains(sql, part) {
+			t.Errorf("statement does not contain %q:\n%s", part, sql)
+		}
+	}
+}
+
+func TestVideoQueryDefaults(t *testing.T) {
+	sql, args, err := (&VideoQuery{UserID: "user"}).SQL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "SELECT " + videoColumns + ",\n" +
+		"       0::real AS rank, '' AS title_highlight, '' AS channel_highlight,\n" +
+		"       '' AS sort_text,\n" +
+		"       0 AS position\n" +
+		"FROM videos v\n" +
+		"WHERE v.user_id = $1\n" +
+		"ORDER BY v.created_at ASC, v.id ASC"
+	if sql != want {
+		t.Errorf("got:\n%s\nwant:\n%s", sql, want)
+	}
+	if !reflect.DeepEqual(args, []interface{}{"user"}) {
+		t.Errorf("args = %v", args)
+	}
+}
+
+func TestVideoQueryByID(t *testing.T) {
+	sql, args, err := (&VideoQuery{UserID: "user", ID: 42}).SQL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkPlaceholders(t, sql, args)
+	mustContain(t, sql, "WHERE v.user_id = $1\n  AND v.id = $2::bigint")
+	if !reflect.DeepEqual(args, []interface{}{"user", int64(42)}) {
+		t.Errorf("args = %v", args)
+	}
+	// The search columns are large and never read
+	if strings.Contains(sql, "v.tag_names") || strings.Contains(sql, "v.search_vector") {
+		t.Errorf("statement selects the search columns:\n%s", sql)
+	}
+}
+
+func TestVideoQueryFilters(t *testing.T) {
+	createdAfter := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	publishedBefore := time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+	query := &VideoQuery{
+		UserID:          "user",
+		Channels:        []string{"Rick Astley"},
+		ChannelID:       9,
+		TagIDs:          []int64{3, 7, 3},
+		Unassigned:      true,
+		Platforms:       []string{"youtube", "vimeo"},
+		Availability:    "available",
+		CreatedAfter:    createdAfter,
+		PublishedBefore: publishedBefore,
+		SortDesc:        true,
+		Limit:           51,
+	}
+
+	sql, args, err := query.SQL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkPlaceholders(t, sql, args)
+	mustContain(t, sql,
+		"v.user_id = $1",
+		"v.channel = ANY($2::text[])",
+		"v.channel_id = $3::bigint",
+		"WHERE tag_id = ANY($4::bigint[])",
+		"HAVING count(*) = 2",
+		"NOT EXISTS (SELECT 1 FROM playlist_videos pv WHERE pv.video_id = v.id)",
+		"v.platform = ANY($5::text[])",
+		"v.availability = $6::text",
+		"v.created_at >= $7::timestamptz",
+		"v.published_at < $8::timestamptz",
+		"ORDER BY v.created_at DESC, v.id DESC\nLIMIT $9",
+	)
+	want := []interface{}{"user", []string{"Rick Astley"}, int64(9), []int64{3, 7}, []string{"youtube", "vimeo"},
+		"available", createdAfter, publishedBefore, 51}
+	if !reflect.DeepEqual(args, want) {
+		t.Errorf("args = %v, want %v", args, want)
+	}
+
+	countSQL, countArgs := query.CountSQL()
+	checkPlaceholders(t, countSQL, countArgs)
+	if !strings.HasPrefix(countSQL, "SELECT count(*)\nFROM videos v\n") {
+		t.Errorf("count statement:\n%s", countSQL)
+	}
+	if strings.Contains(countSQL, "ORDER BY") || strings.Contains(countSQL, "LIMIT") {
+		t.Errorf("count statement is paginated:\n%s", countSQL)
+	}
+	if !reflect.DeepEqual(countArgs, want[:len(want)-1]) {
+		t.Errorf("count args = %v, want %v", countArgs, want[:len(want)-1])
+	}
+}
+
+func TestVideoQuerySearch(t *testing.T) {
+	query := &VideoQuery{
+		UserID:          "user",
+		Search:          "never gonna",
+		HeadlineOptions: "HighlightAll=true",
+		Sort:            VideoSortRelevance,
+		SortDesc:        true,
+		After:           &VideoKey{Rank: 0.5, ID: 42},
+		Limit:           10,
+	}
+
+	sql, args, err := query.SQL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkPlaceholders(t, sql, args)
+	mustContain(t, sql,
+		"ts_rank_cd(v.search_vector, query) AS rank",
+		"ts_headline('english', v.title, query, $3::text) AS title_highlight",
+		"ts_headline('english', v.channel, query, $3::text) AS channel_highlight",
+		"FROM videos v, websearch_to_tsquery('english', $2::text) AS query\n",
+		"v.search_vector @@ query",
+		"(ts_rank_cd(v.search_vector, query), v.id) < ($4::real, $5::bigint)",
+		"ORDER BY ts_rank_cd(v.search_vector, query) DESC, v.id DESC\nLIMIT $6",
+	)
+	want := []interface{}{"user", "never gonna", "HighlightAll=true", float32(0.5), int64(42), 10}
+	if !reflect.DeepEqual(args, want) {
+		t.Errorf("args = %v, want %v", args, want)
+	}
+
+	countSQL, countArgs := query.CountSQL()
+	checkPlaceholders(t, countSQL, countArgs)
+	mustContain(t, countSQL, "websearch_to_tsquery('english', $2::text)", "v.search_vector @@ query")
+}
+
+func TestVideoQueryKeyset(t *testing.T) {
+	createdAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		sort     VideoSort
+		desc     bool
+		want     string
+		arg      interface{}
+		sortText string
+	}{
+		{VideoSortCreatedAt, true, "(v.created_at, v.id) < ($2::timestamptz, $3::bigint)", createdAt, "''"},
+		{VideoSortCreatedAt, false, "(v.created_at, v.id) > ($2::timestamptz, $3::bigint)", createdAt, "''"},
+		{VideoSortTitle, false, `(lower(v.title) COLLATE "C", v.id) > ($2::text, $3::bigint)`, "never gonna give you up", "lower(v.title)"},
+		{VideoSortChannel, true, `(lower(v.channel) COLLATE "C", v.id) < ($2::text, $3::bigint)`, "never gonna give you up", "lower(v.channel)"},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s desc=%v", tt.sort, tt.desc), func(t *testing.T) {
+			sql, args, err := (&VideoQuery{
+				UserID:   "user",
+				Sort:     tt.sort,
+				SortDesc: tt.desc,
+				After:    &VideoKey{CreatedAt: createdAt, Text: "never gonna give you up", ID: 7},
+			}).SQL()
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkPlaceholders(t, sql, args)
+			// The next cursor's text comes from the same expression the page is sorted by
+			mustContain(t, sql, tt.want, tt.sortText+" AS sort_text")
+			if !reflect.DeepEqual(args, []interface{}{"user", tt.arg, int64(7)}) {
+				t.Errorf("args = %v", args)
+			}
+		})
+	}
+}
+
+func TestVideoQueryPlaylist(t *testing.T) {
+	query := &VideoQuery{
+		UserID:     "user",
+		PlaylistID: 5,
+		Search:     "never gonna",
+		Sort:       VideoSortPosition,
+		After:      &VideoKey{Position: 3, ID: 42},
+		Limit:      10,
+	}
+
+	sql, args, err := query.SQL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkPlaceholders(t, sql, args)
+	// The playlist join comes right after videos, where its condition can refer to v
+	mustContain(t, sql,
+		"       pv.position\n",
+		"FROM videos v\nJOIN playlist_videos pv ON pv.video_id = v.id AND pv.playlist_id = $2::bigint, websearch_to_tsquery('english', $3::text) AS query\n",
+		"(pv.position, v.id) > ($5::int, $6::bigint)",
+		"ORDER BY pv.position ASC, v.id ASC\nLIMIT $7",
+	)
+	want := []interface{}{"user", int64(5), "never gonna", "", int32(3), int64(42), 10}
+	if !reflect.DeepEqual(args, want) {
+		t.Errorf("args = %v, want %v", args, want)
+	}
+
+	countSQL, countArgs := query.CountSQL()
+	checkPlaceholders(t, countSQL, countArgs)
+	mustContain(t, countSQL, "JOIN playlist_videos pv ON pv.video_id = v.id AND pv.playlist_id = $2::bigint")
+}
+
+func TestVideoQueryPositionWithoutPlaylist(t *testing.T) {
+	_, _, err := (&VideoQuery{UserID: "user", Sort: VideoSortPosition}).SQL()
+	if !errors.Is(err, ErrPositionWithoutPlaylist) {
+		t.Errorf("err = %v, want ErrPositionWithoutPlaylist", err)
+	}
+}
+
+func TestVideoQueryRelevanceWithoutSearch(t *testing.T) {
+	_, _, err := (&VideoQuery{UserID: "user", Sort: VideoSortRelevance}).SQL()
+	if !errors.Is(err, ErrRelevanceWithoutSearch) {
+		t.Errorf("err = %v, want ErrRelevanceWithoutSearch", err)
+	}
+}
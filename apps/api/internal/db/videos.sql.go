@@ -7,146 +7,49 @@ package db
 
 import (
 	"context"
-)
-
-const CreateVideo = `-- name: CreateVideo :one
-INSERT INTO videos (video_id, normalized_url, original_url, title, channel, user_id)
-VALUES ($1, $2, $3, $4, $5, $6)
-ON CONFLICT (normalized_url) DO NOTHING
-RETURNING id, video_id, normalized_url, original_url, title, channel, user_id, created_at
-`
-
-type CreateVideoParams struct {
-	VideoID       string `json:"video_id"`
-	NormalizedUrl string `json:"normalized_url"`
-	OriginalUrl   string `json:"original_url"`
-	Title         string `json:"title"`
-	Channel       string `json:"channel"`
-	UserID        string `json:"user_id"`
-}
-
-func (q *Queries) CreateVideo(ctx context.Context, arg *CreateVideoParams) (*Video, error) {
-	row := q.db.QueryRow(ctx, CreateVideo,
-		arg.VideoID,
-		arg.NormalizedUrl,
-		arg.OriginalUrl,
-		arg.Title,
-		arg.Channel,
-		arg.UserID,
-	)
-	var i Video
-	err := row.Scan(
-		&i.ID,
-		&i.VideoID,
-		&i.NormalizedUrl,
-		&i.OriginalUrl,
-		&i.Title,
-		&i.Channel,
-		&i.UserID,
-		&i.CreatedAt,
-	)
-	return &i, err
-}
-
-const DeleteVideo = `-- name: DeleteVideo :exec
-DELETE FROM videos
-WHERE id = $1 AND user_id = $2
-`
-
-type DeleteVideoParams struct {
-	ID     int64  `json:"id"`
-	UserID string `json:"user_id"`
-}
-
-func (q *Queries) DeleteVideo(ctx context.Context, arg *DeleteVideoParams) error {
-	_, err := q.db.Exec(ctx, DeleteVideo, arg.ID, arg.UserID)
-	return err
-}
-
-const DeleteVideos = `-- name: DeleteVideos :exec
-DELETE FROM videos
-WHERE id = ANY($1::bigint[]) AND user_id = $2
-`
-
-type DeleteVideosParams struct {
-	Column1 []int64 `json:"column_1"`
-	UserID  string  `json:"user_id"`
-}
 
-func (q *Queries) DeleteVideos(ctx context.Context, arg *DeleteVideosParams) error {
-	_, err := q.db.Exec(ctx, DeleteVideos, arg.Column1, arg.UserID)
-	return err
-}
+	"github.com/jackc/pgx/v5/pgtype"
+)
 
-const GetVideoByID = `-- name: GetVideoByID :one
-SELECT id, video_id, normalized_url, original_url, title, channel, user_id, created_at
-FROM videos
-WHERE id = $1
+const ClaimVideosForAvailabilityCheck = `-- name: ClaimVideosForAvailabilityCheck :many
+UPDATE videos
+SET availability_next_check_at = now() + make_interval(secs => $1::int)
+WHERE id IN (
+    SELECT id FROM videos
+    WHERE availability_next_check_at <= now()
+    ORDER BY availability_next_check_at
+    LIMIT $2
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, video_id, normalized_url, platform
 `
 
-func (q *Queries) GetVideoByID(ctx context.Context, id int64) (*Video, error) {
-	row := q.db.QueryRow(ctx, GetVideoByID, id)
-	var i Video
-	err := row.Scan(
-		&i.ID,
-		&i.VideoID,
-		&i.NormalizedUrl,
-		&i.OriginalUrl,
-		&i.Title,
-		&i.Channel,
-		&i.UserID,
-		&i.CreatedAt,
-	)
-	return &i, err
+type ClaimVideosForAvailabilityCheckParams struct {
+	LeaseSeconds int32 `json:"lease_seconds"`
+	Limit        int32 `json:"limit"`
 }
 
-const GetVideoByURL = `-- name: GetVideoByURL :one
-SELECT id, video_id, normalized_url, original_url, title, channel, user_id, created_at
-FROM videos
-WHERE normalized_url = $1
-`
-
-func (q *Queries) GetVideoByURL(ctx context.Context, normalizedUrl string) (*Video, error) {
-	row := q.db.QueryRow(ctx, GetVideoByURL, normalizedUrl)
-	var i Video
-	err := row.Scan(
-		&i.ID,
-		&i.VideoID,
-		&i.NormalizedUrl,
-		&i.OriginalUrl,
-		&i.Title,
-		&i.Channel,
-		&i.UserID,
-		&i.CreatedAt,
-	)
-	return &i, err
+type ClaimVideosForAvailabilityCheckRow struct {
+	ID            int64  `json:"id"`
+	VideoID       string `json:"video_id"`
+	NormalizedUrl string `json:"normalized_url"`
+	Platform      string `json:"platform"`
 }
 
-const ListVideos = `-- name: ListVideos :many
-SELECT id, video_id, normalized_url, original_url, title, channel, user_id, created_at
-FROM videos
-WHERE user_id = $1
-ORDER BY created_at DESC
-`
-
-func (q *Queries) ListVideos(ctx context.Context, userID string) ([]*Video, error) {
-	rows, err := q.db.Query(ctx, ListVideos, userID)
+func (q *Queries) ClaimVideosForAvailabilityCheck(ctx context.Context, arg *ClaimVideosForAvailabilityCheckParams) ([]*ClaimVideosForAvailabilityCheckRow, error) {
+	rows, err := q.db.Query(ctx, ClaimVideosForAvailabilityCheck, arg.LeaseSeconds, arg.Limit)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	items := []*Video{}
+	items := []*ClaimVideosForAvailabilityCheckRow{}
 	for rows.Next() {
-		var i Video
+		var i ClaimVideosForAvailabilityCheckRow
 		if err := rows.Scan(
 			&i.ID,
 			&i.VideoID,
 			&i.NormalizedUrl,
-			&i.OriginalUrl,
-			&i.Title,
-			&i.Channel,
-			&i.UserID,
-			&i.CreatedAt,
+			&i.Platform,
 		); err != nil {
 			return nil, err
 		}
@@ -158,37 +61,47 @@ func (q *Queries) ListVideos(ctx context.Context, userID string) ([]*Video, erro
 	return items, nil
 }
 
-const ListVideosFiltered = `-- name: ListVideosFiltered :many
-SELECT id, video_id, normalized_url, original_url, title, channel, user_id, created_at
-FROM videos
-WHERE user_id = $1
-  AND channel = ANY($2::text[])
-ORDER BY created_at DESC
+const ClaimVideosForEnrichment = `-- name: ClaimVideosForEnrichment :many
+UPDATE videos
+SET metadata_next_attempt_at = now() + make_interval(secs => $1::int)
+WHERE id IN (
+    SELECT id FROM videos
+    WHERE metadata_status = 'pending' AND metadata_next_attempt_at <= now()
+    ORDER BY metadata_next_attempt_at
+    LIMIT $2
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, video_id, normalized_url, platform, metadata_attempts
 `
 
-type ListVideosFilteredParams struct {
-	UserID  string   `json:"user_id"`
-	Column2 []string `json:"column_2"`
+type ClaimVideosForEnrichmentParams struct {
+	LeaseSeconds int32 `json:"lease_seconds"`
+	Limit        int32 `json:"limit"`
 }
 
-func (q *Queries) ListVideosFiltered(ctx context.Context, arg *ListVideosFilteredParams) ([]*Video, error) {
-	rows, err := q.db.Query(ctx, ListVideosFiltered, arg.UserID, arg.Column2)
+type ClaimVideosForEnrichmentRow struct {
+	ID               int64  `json:"id"`
+	VideoID          string `json:"video_id"`
+	NormalizedUrl    string `json:"normalized_url"`
+	Platform         string `json:"platform"`
+	MetadataAttempts int32  `json:"metadata_attempts"`
+}
+
+func (q *Queries) ClaimVideosForEnrichment(ctx context.Context, arg *ClaimVideosForEnrichmentParams) ([]*ClaimVideosForEnrichmentRow, error) {
+	rows, err := q.db.Query(ctx, ClaimVideosForEnrichment, arg.LeaseSeconds, arg.Limit)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	items := []*Video{}
+	items := []*ClaimVideosForEnrichmentRow{}
 	for rows.Next() {
-		var i Video
+		var i ClaimVideosForEnrichmentRow
 		if err := rows.Scan(
 			&i.ID,
 			&i.VideoID,
 			&i.NormalizedUrl,
-			&i.OriginalUrl,
-			&i.Title,
-			&i.Channel,
-			&i.UserID,
-			&i.CreatedAt,
+			&i.Platform,
+			&i.MetadataAttempts,
 		); err != nil {
 			return nil, err
 		}
@@ -200,39 +113,45 @@ func (q *Queries) ListVideosFiltered(ctx context.Context, arg *ListVideosFiltere
 	return items, nil
 }
 
-const ListVideosFilteredWithSearch = `-- name: ListVideosFilteredWithSearch :many
-SELECT id, video_id, normalized_url, original_url, title, channel, user_id, created_at
-FROM videos
-WHERE user_id = $1
-  AND channel = ANY($2::text[])
-  AND (title ILIKE $3 OR channel ILIKE $3)
-ORDER BY created_at DESC
+const ClaimVideosForThumbnailCache = `-- name: ClaimVideosForThumbnailCache :many
+UPDATE videos
+SET thumbnail_cache_next_attempt_at = now() + make_interval(secs => $1::int)
+WHERE id IN (
+    SELECT id FROM videos
+    WHERE thumbnail_url IS NOT NULL
+      AND thumbnail_source_url IS DISTINCT FROM thumbnail_url
+      AND thumbnail_cache_next_attempt_at <= now()
+    ORDER BY thumbnail_cache_next_attempt_at
+    LIMIT $2
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, thumbnail_url, thumbnail_cache_attempts
 `
 
-type ListVideosFilteredWithSearchParams struct {
-	UserID  string   `json:"user_id"`
-	Column2 []string `json:"column_2"`
-	Title   string   `json:"title"`
+type ClaimVideosForThumbnailCacheParams struct {
+	LeaseSeconds int32 `json:"lease_seconds"`
+	Limit        int32 `json:"limit"`
+}
+
+type ClaimVideosForThumbnailCacheRow struct {
+	ID                     int64   `json:"id"`
+	ThumbnailUrl           *string `json:"thumbnail_url"`
+	ThumbnailCacheAttempts int32   `json:"thumbnail_cache_attempts"`
 }
 
-func (q *Queries) ListVideosFilteredWithSearch(ctx context.Context, arg *ListVideosFilteredWithSearchParams) ([]*Video, error) {
-	rows, err := q.db.Query(ctx, ListVideosFilteredWithSearch, arg.UserID, arg.Column2, arg.Title)
+func (q *Queries) ClaimVideosForThumbnailCache(ctx context.Context, arg *ClaimVideosForThumbnailCacheParams) ([]*ClaimVideosForThumbnailCacheRow, error) {
+	rows, err := q.db.Query(ctx, ClaimVideosForThumbnailCache, arg.LeaseSeconds, arg.Limit)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	items := []*Video{}
+	items := []*ClaimVideosForThumbnailCacheRow{}
 	for rows.Next() {
-		var i Video
+		var i ClaimVideosForThumbnailCacheRow
 		if err := rows.Scan(
 			&i.ID,
-			&i.VideoID,
-			&i.NormalizedUrl,
-			&i.OriginalUrl,
-			&i.Title,
-			&i.Channel,
-			&i.UserID,
-			&i.CreatedAt,
+			&i.ThumbnailUrl,
+			&i.ThumbnailCacheAttempts,
 		); err != nil {
 			return nil, err
 		}
@@ -244,23 +163,111 @@ func (q *Queries) ListVideosFilteredWithSearch(ctx context.Context, arg *ListVid
 	return items, nil
 }
 
-const ListVideosUnassigned = `-- name: ListVideosUnassigned :many
-SELECT id, video_id, normalized_url, original_url, title, channel, user_id, created_at
-FROM videos
-WHERE user_id = $1
-  AND id NOT IN (SELECT DISTINCT video_id FROM playlist_videos)
-ORDER BY created_at DESC
+const CreateVideos = `-- name: CreateVideos :many
+INSERT INTO videos (user_id, video_id, normalized_url, original_url, title, channel, platform, start_seconds,
+    thumbnail_url, duration_seconds, published_at, view_count, channel_handle)
+SELECT $1, v.video_id, v.normalized_url, v.original_url, v.title, v.channel, v.platform, v.start_seconds,
+    NULLIF(v.thumbnail_url, ''), NULLIF(v.duration_seconds, 0), v.published_at, NULLIF(v.view_count, 0), NULLIF(v.channel_handle, '')
+FROM unnest($2::text[], $3::text[], $4::text[],
+    $5::text[], $6::text[], $7::text[], $8::int[],
+    $9::text[], $10::int[], $11::timestamptz[],
+    $12::bigint[], $13::text[])
+    AS v(video_id, normalized_url, original_url, title, channel, platform, start_seconds,
+    thumbnail_url, duration_seconds, published_at, view_count, channel_handle)
+ON CONFLICT (user_id, normalized_url, start_seconds) DO UPDATE
+SET title = COALESCE(NULLIF(videos.title, ''), EXCLUDED.title),
+    channel = COALESCE(NULLIF(videos.channel, ''), EXCLUDED.channel),
+    thumbnail_url = COALESCE(videos.thumbnail_url, EXCLUDED.thumbnail_url),
+    duration_seconds = COALESCE(videos.duration_seconds, EXCLUDED.duration_seconds),
+    published_at = COALESCE(videos.published_at, EXCLUDED.published_at),
+    view_count = GREATEST(videos.view_count, EXCLUDED.view_count),
+    channel_handle = COALESCE(videos.channel_handle, EXCLUDED.channel_handle)
+WHERE (videos.title = '' AND EXCLUDED.title <> '')
+   OR (videos.channel = '' AND EXCLUDED.channel <> '')
+   OR (videos.thumbnail_url IS NULL AND EXCLUDED.thumbnail_url IS NOT NULL)
+   OR (videos.duration_seconds IS NULL AND EXCLUDED.duration_seconds IS NOT NULL)
+   OR (videos.published_at IS NULL AND EXCLUDED.published_at IS NOT NULL)
+   OR (videos.channel_handle IS NULL AND EXCLUDED.channel_handle IS NOT NULL)
+   OR EXCLUDED.view_count > COALESCE(videos.view_count, -1)
+RETURNING id, video_id, normalized_url, original_url, title, channel, user_id, created_at, platform, start_seconds,
+    thumbnail_url, duration_seconds, published_at, channel_external_id, metadata_status, metadata_attempts, metadata_next_attempt_at, metadata_error,
+    view_count, channel_handle, channel_id, availability, availability_reason, last_checked_at, availability_next_check_at,
+    thumbnail_hash, thumbnail_source_url, thumbnail_cache_attempts, thumbnail_cache_next_attempt_at,
+    (xmax = 0) AS inserted
 `
 
-func (q *Queries) ListVideosUnassigned(ctx context.Context, userID string) ([]*Video, error) {
-	rows, err := q.db.Query(ctx, ListVideosUnassigned, userID)
+type CreateVideosParams struct {
+	UserID          string               `json:"user_id"`
+	VideoIds        []string             `json:"video_ids"`
+	NormalizedUrls  []string             `json:"normalized_urls"`
+	OriginalUrls    []string             `json:"original_urls"`
+	Titles          []string             `json:"titles"`
+	Channels        []string             `json:"channels"`
+	Platforms       []string             `json:"platforms"`
+	StartSeconds    []int32              `json:"start_seconds"`
+	ThumbnailUrls   []string             `json:"thumbnail_urls"`
+	DurationSeconds []int32              `json:"duration_seconds"`
+	PublishedAts    []pgtype.Timestamptz `json:"published_ats"`
+	ViewCounts      []int64              `json:"view_counts"`
+	ChannelHandles  []string             `json:"channel_handles"`
+}
+
+type CreateVideosRow struct {
+	ID                          int64              `json:"id"`
+	VideoID                     string             `json:"video_id"`
+	NormalizedUrl               string             `json:"normalized_url"`
+	OriginalUrl                 string             `json:"original_url"`
+	Title                       string             `json:"title"`
+	Channel                     string             `json:"channel"`
+	UserID                      string             `json:"user_id"`
+	CreatedAt                   pgtype.Timestamptz `json:"created_at"`
+	Platform                    string             `json:"platform"`
+	StartSeconds                int32              `json:"start_seconds"`
+	ThumbnailUrl                *string            `json:"thumbnail_url"`
+	DurationSeconds             *int32             `json:"duration_seconds"`
+	PublishedAt                 pgtype.Timestamptz `json:"published_at"`
+	ChannelExternalID           *string            `json:"channel_external_id"`
+	MetadataStatus              string             `json:"metadata_status"`
+	MetadataAttempts            int32              `json:"metadata_attempts"`
+	MetadataNextAttemptAt       pgtype.Timestamptz `json:"metadata_next_attempt_at"`
+	MetadataError               *string            `json:"metadata_error"`
+	ViewCount                   *int64             `json:"view_count"`
+	ChannelHandle               *string            `json:"channel_handle"`
+	ChannelID                   *int64             `json:"channel_id"`
+	Availability                string             `json:"availability"`
+	AvailabilityReason          *string            `json:"availability_reason"`
+	LastCheckedAt               pgtype.Timestamptz `json:"last_checked_at"`
+	AvailabilityNextCheckAt     pgtype.Timestamptz `json:"availability_next_check_at"`
+	ThumbnailHash               *string            `json:"thumbnail_hash"`
+	ThumbnailSourceUrl          *string            `json:"thumbnail_source_url"`
+	ThumbnailCacheAttempts      int32              `json:"thumbnail_cache_attempts"`
+	ThumbnailCacheNextAttemptAt pgtype.Timestamptz `json:"thumbnail_cache_next_attempt_at"`
+	Inserted                    bool               `json:"inserted"`
+}
+
+func (q *Queries) CreateVideos(ctx context.Context, arg *CreateVideosParams) ([]*CreateVideosRow, error) {
+	rows, err := q.db.Query(ctx, CreateVideos,
+		arg.UserID,
+		arg.VideoIds,
+		arg.NormalizedUrls,
+		arg.OriginalUrls,
+		arg.Titles,
+		arg.Channels,
+		arg.Platforms,
+		arg.StartSeconds,
+		arg.ThumbnailUrls,
+		arg.DurationSeconds,
+		arg.PublishedAts,
+		arg.ViewCounts,
+		arg.ChannelHandles,
+	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	items := []*Video{}
+	items := []*CreateVideosRow{}
 	for rows.Next() {
-		var i Video
+		var i CreateVideosRow
 		if err := rows.Scan(
 			&i.ID,
 			&i.VideoID,
@@ -270,6 +277,28 @@ func (q *Queries) ListVideosUnassigned(ctx context.Context, userID string) ([]*V
 			&i.Channel,
 			&i.UserID,
 			&i.CreatedAt,
+			&i.Platform,
+			&i.StartSeconds,
+			&i.ThumbnailUrl,
+			&i.DurationSeconds,
+			&i.PublishedAt,
+			&i.ChannelExternalID,
+			&i.MetadataStatus,
+			&i.MetadataAttempts,
+			&i.MetadataNextAttemptAt,
+			&i.MetadataError,
+			&i.ViewCount,
+			&i.ChannelHandle,
+			&i.ChannelID,
+			&i.Availability,
+			&i.AvailabilityReason,
+			&i.LastCheckedAt,
+			&i.AvailabilityNextCheckAt,
+			&i.ThumbnailHash,
+			&i.ThumbnailSourceUrl,
+			&i.ThumbnailCacheAttempts,
+			&i.ThumbnailCacheNextAttemptAt,
+			&i.Inserted,
 		); err != nil {
 			return nil, err
 		}
@@ -281,130 +310,156 @@ func (q *Queries) ListVideosUnassigned(ctx context.Context, userID string) ([]*V
 	return items, nil
 }
 
-const ListVideosUnassignedFiltered = `-- name: ListVideosUnassignedFiltered :many
-SELECT id, video_id, normalized_url, original_url, title, channel, user_id, created_at
-FROM videos
-WHERE user_id = $1
-  AND channel = ANY($2::text[])
-  AND id NOT IN (SELECT DISTINCT video_id FROM playlist_videos)
-ORDER BY created_at DESC
+const DeleteVideo = `-- name: DeleteVideo :exec
+DELETE FROM videos
+WHERE id = $1 AND user_id = $2
 `
 
-type ListVideosUnassignedFilteredParams struct {
-	UserID  string   `json:"user_id"`
-	Column2 []string `json:"column_2"`
+type DeleteVideoParams struct {
+	ID     int64  `json:"id"`
+	UserID string `json:"user_id"`
 }
 
-func (q *Queries) ListVideosUnassignedFiltered(ctx context.Context, arg *ListVideosUnassignedFilteredParams) ([]*Video, error) {
-	rows, err := q.db.Query(ctx, ListVideosUnassignedFiltered, arg.UserID, arg.Column2)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	items := []*Video{}
-	for rows.Next() {
-		var i Video
-		if err := rows.Scan(
-			&i.ID,
-			&i.VideoID,
-			&i.NormalizedUrl,
-			&i.OriginalUrl,
-			&i.Title,
-			&i.Channel,
-			&i.UserID,
-			&i.CreatedAt,
-		); err != nil {
-			return nil, err
-		}
-		items = append(items, &i)
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return items, nil
+func (q *Queries) DeleteVideo(ctx context.Context, arg *DeleteVideoParams) error {
+	_, err := q.db.Exec(ctx, DeleteVideo, arg.ID, arg.UserID)
+	return err
 }
 
-const ListVideosUnassignedFilteredWithSearch = `-- name: ListVideosUnassignedFilteredWithSearch :many
-SELECT id, video_id, normalized_url, original_url, title, channel, user_id, created_at
-FROM videos
-WHERE user_id = $1
-  AND channel = ANY($2::text[])
-  AND id NOT IN (SELECT DISTINCT video_id FROM playlist_videos)
-  AND (title ILIKE $3 OR channel ILIKE $3)
-ORDER BY created_at DESC
+const DeleteVideos = `-- name: DeleteVideos :exec
+DELETE FROM videos
+WHERE id = ANY($1::bigint[]) AND user_id = $2
 `
 
-type ListVideosUnassignedFilteredWithSearchParams struct {
-	UserID  string   `json:"user_id"`
-	Column2 []string `json:"column_2"`
-	Title   string   `json:"title"`
+type DeleteVideosParams struct {
+	Ids    []int64 `json:"ids"`
+	UserID string  `json:"user_id"`
 }
 
-func (q *Queries) ListVideosUnassignedFilteredWithSearch(ctx context.Context, arg *ListVideosUnassignedFilteredWithSearchParams) ([]*Video, error) {
-	rows, err := q.db.Query(ctx, ListVideosUnassignedFilteredWithSearch, arg.UserID, arg.Column2, arg.Title)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	items := []*Video{}
-	for rows.Next() {
-		var i Video
-		if err := rows.Scan(
-			&i.ID,
-			&i.VideoID,
-			&i.NormalizedUrl,
-			&i.OriginalUrl,
-			&i.Title,
-			&i.Channel,
-			&i.UserID,
-			&i.CreatedAt,
-		); err != nil {
-			return nil, err
-		}
-		items = append(items, &i)
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return items, nil
+func (q *Queries) DeleteVideos(ctx context.Context, arg *DeleteVideosParams) error {
+	_, err := q.db.Exec(ctx, DeleteVideos, arg.Ids, arg.UserID)
+	return err
+}
+
+const FailVideoMetadata = `-- name: FailVideoMetadata :exec
+UPDATE videos
+SET metadata_status = 'failed',
+    metadata_attempts = metadata_attempts + 1,
+    metadata_error = $2,
+    metadata_next_attempt_at = NULL
+WHERE id = $1
+`
+
+type FailVideoMetadataParams struct {
+	ID            int64   `json:"id"`
+	MetadataError *string `json:"metadata_error"`
+}
+
+func (q *Queries) FailVideoMetadata(ctx context.Context, arg *FailVideoMetadataParams) error {
+	_, err := q.db.Exec(ctx, FailVideoMetadata, arg.ID, arg.MetadataError)
+	return err
 }
 
-const ListVideosUnassignedWithSearch = `-- name: ListVideosUnassignedWithSearch :many
-SELECT id, video_id, normalized_url, original_url, title, channel, user_id, created_at
+const GetVideoForUser = `-- name: GetVideoForUser :one
+SELECT id, video_id, normalized_url, original_url, title, channel, user_id, created_at, platform, start_seconds,
+       thumbnail_url, duration_seconds, published_at, channel_external_id, metadata_status, metadata_attempts, metadata_next_attempt_at, metadata_error,
+       view_count, channel_handle, channel_id, availability, availability_reason, last_checked_at, availability_next_check_at,
+       thumbnail_hash, thumbnail_source_url, thumbnail_cache_attempts, thumbnail_cache_next_attempt_at
 FROM videos
-WHERE user_id = $1
-  AND id NOT IN (SELECT DISTINCT video_id FROM playlist_videos)
-  AND (title ILIKE $2 OR channel ILIKE $2)
-ORDER BY created_at DESC
+WHERE id = $1 AND user_id = $2
 `
 
-type ListVideosUnassignedWithSearchParams struct {
+type GetVideoForUserParams struct {
+	ID     int64  `json:"id"`
 	UserID string `json:"user_id"`
-	Title  string `json:"title"`
 }
 
-func (q *Queries) ListVideosUnassignedWithSearch(ctx context.Context, arg *ListVideosUnassignedWithSearchParams) ([]*Video, error) {
-	rows, err := q.db.Query(ctx, ListVideosUnassignedWithSearch, arg.UserID, arg.Title)
+type GetVideoForUserRow struct {
+	ID                          int64              `json:"id"`
+	VideoID                     string             `json:"video_id"`
+	NormalizedUrl               string             `json:"normalized_url"`
+	OriginalUrl                 string             `json:"original_url"`
+	Title                       string             `json:"title"`
+	Channel                     string             `json:"channel"`
+	UserID                      string             `json:"user_id"`
+	CreatedAt                   pgtype.Timestamptz `json:"created_at"`
+	Platform                    string             `json:"platform"`
+	StartSeconds                int32              `json:"start_seconds"`
+	ThumbnailUrl                *string            `json:"thumbnail_url"`
+	DurationSeconds             *int32             `json:"duration_seconds"`
+	PublishedAt                 pgtype.Timestamptz `json:"published_at"`
+	ChannelExternalID           *string            `json:"channel_external_id"`
+	MetadataStatus              string             `json:"metadata_status"`
+	MetadataAttempts            int32              `json:"metadata_attempts"`
+	MetadataNextAttemptAt       pgtype.Timestamptz `json:"metadata_next_attempt_at"`
+	MetadataError               *string            `json:"metadata_error"`
+	ViewCount                   *int64             `json:"view_count"`
+	ChannelHandle               *string            `json:"channel_handle"`
+	ChannelID                   *int64             `json:"channel_id"`
+	Availability                string             `json:"availability"`
+	AvailabilityReason          *string            `json:"availability_reason"`
+	LastCheckedAt               pgtype.Timestamptz `json:"last_checked_at"`
+	AvailabilityNextCheckAt     pgtype.Timestamptz `json:"availability_next_check_at"`
+	ThumbnailHash               *string            `json:"thumbnail_hash"`
+	ThumbnailSourceUrl          *string            `json:"thumbnail_source_url"`
+	ThumbnailCacheAttempts      int32              `json:"thumbnail_cache_attempts"`
+	ThumbnailCacheNextAttemptAt pgtype.Timestamptz `json:"thumbnail_cache_next_attempt_at"`
+}
+
+func (q *Queries) GetVideoForUser(ctx context.Context, arg *GetVideoForUserParams) (*GetVideoForUserRow, error) {
+	row := q.db.QueryRow(ctx, GetVideoForUser, arg.ID, arg.UserID)
+	var i GetVideoForUserRow
+	err := row.Scan(
+		&i.ID,
+		&i.VideoID,
+		&i.NormalizedUrl,
+		&i.OriginalUrl,
+		&i.Title,
+		&i.Channel,
+		&i.UserID,
+		&i.CreatedAt,
+		&i.Platform,
+		&i.StartSeconds,
+		&i.ThumbnailUrl,
+		&i.DurationSeconds,
+		&i.PublishedAt,
+		&i.ChannelExternalID,
+		&i.MetadataStatus,
+		&i.MetadataAttempts,
+		&i.MetadataNextAttemptAt,
+		&i.MetadataError,
+		&i.ViewCount,
+		&i.ChannelHandle,
+		&i.ChannelID,
+		&i.Availability,
+		&i.AvailabilityReason,
+		&i.LastCheckedAt,
+		&i.AvailabilityNextCheckAt,
+		&i.ThumbnailHash,
+		&i.ThumbnailSourceUrl,
+		&i.ThumbnailCacheAttempts,
+		&i.ThumbnailCacheNextAttemptAt,
+	)
+	return &i, err
+}
+
+const ListReferencedThumbnailHashes = `-- name: ListReferencedThumbnailHashes :many
+SELECT DISTINCT thumbnail_hash FROM videos
+WHERE thumbnail_hash = ANY($1::text[])
+`
+
+func (q *Queries) ListReferencedThumbnailHashes(ctx context.Context, hashes []string) ([]*string, error) {
+	rows, err := q.db.Query(ctx, ListReferencedThumbnailHashes, hashes)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	items := []*Video{}
+	items := []*string{}
 	for rows.Next() {
-		var i Video
-		if err := rows.Scan(
-			&i.ID,
-			&i.VideoID,
-			&i.NormalizedUrl,
-			&i.OriginalUrl,
-			&i.Title,
-			&i.Channel,
-			&i.UserID,
-			&i.CreatedAt,
-		); err != nil {
+		var thumbnail_hash *string
+		if err := rows.Scan(&thumbnail_hash); err != nil {
 			return nil, err
 		}
-		items = append(items, &i)
+		items = append(items, thumbnail_hash)
 	}
 	if err := rows.Err(); err != nil {
 		return nil, err
@@ -412,37 +467,36 @@ func (q *Queries) ListVideosUnassignedWithSearch(ctx context.Context, arg *ListV
 	return items, nil
 }
 
-const ListVideosWithSearch = `-- name: ListVideosWithSearch :many
-SELECT id, video_id, normalized_url, original_url, title, channel, user_id, created_at
+const ListVideoIDsByURL = `-- name: ListVideoIDsByURL :many
+SELECT id, normalized_url, start_seconds
 FROM videos
-WHERE user_id = $1
-  AND (title ILIKE $2 OR channel ILIKE $2)
-ORDER BY created_at DESC
+WHERE user_id = $1 AND normalized_url = ANY($2::text[])
 `
 
-type ListVideosWithSearchParams struct {
-	UserID string `json:"user_id"`
-	Title  string `json:"title"`
+type ListVideoIDsByURLParams struct {
+	UserID         string   `json:"user_id"`
+	NormalizedUrls []string `json:"normalized_urls"`
+}
+
+type ListVideoIDsByURLRow struct {
+	ID            int64  `json:"id"`
+	NormalizedUrl string `json:"normalized_url"`
+	StartSeconds  int32  `json:"start_seconds"`
 }
 
-func (q *Queries) ListVideosWithSearch(ctx context.Context, arg *ListVideosWithSearchParams) ([]*Video, error) {
-	rows, err := q.db.Query(ctx, ListVideosWithSearch, arg.UserID, arg.Title)
+func (q *Queries) ListVideoIDsByURL(ctx context.Context, arg *ListVideoIDsByURLParams) ([]*ListVideoIDsByURLRow, error) {
+	rows, err := q.db.Query(ctx, ListVideoIDsByURL, arg.UserID, arg.NormalizedUrls)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	items := []*Video{}
+	items := []*ListVideoIDsByURLRow{}
 	for rows.Next() {
-		var i Video
+		var i ListVideoIDsByURLRow
 		if err := rows.Scan(
 			&i.ID,
-			&i.VideoID,
 			&i.NormalizedUrl,
-			&i.OriginalUrl,
-			&i.Title,
-			&i.Channel,
-			&i.UserID,
-			&i.CreatedAt,
+			&i.StartSeconds,
 		); err != nil {
 			return nil, err
 		}
@@ -453,3 +507,151 @@ func (q *Queries) ListVideosWithSearch(ctx context.Context, arg *ListVideosWithS
 	}
 	return items, nil
 }
+
+const RetryVideoAvailabilityCheck = `-- name: RetryVideoAvailabilityCheck :exec
+UPDATE videos
+SET availability_next_check_at = now() + make_interval(secs => $1::int)
+WHERE id = $2
+`
+
+type RetryVideoAvailabilityCheckParams struct {
+	DelaySeconds int32 `json:"delay_seconds"`
+	ID           int64 `json:"id"`
+}
+
+func (q *Queries) RetryVideoAvailabilityCheck(ctx context.Context, arg *RetryVideoAvailabilityCheckParams) error {
+	_, err := q.db.Exec(ctx, RetryVideoAvailabilityCheck, arg.DelaySeconds, arg.ID)
+	return err
+}
+
+const RetryVideoMetadata = `-- name: RetryVideoMetadata :exec
+UPDATE videos
+SET metadata_attempts = metadata_attempts + 1,
+    metadata_error = $1,
+    metadata_next_attempt_at = now() + make_interval(secs => $2::int)
+WHERE id = $3
+`
+
+type RetryVideoMetadataParams struct {
+	MetadataError *string `json:"metadata_error"`
+	DelaySeconds  int32   `json:"delay_seconds"`
+	ID            int64   `json:"id"`
+}
+
+func (q *Queries) RetryVideoMetadata(ctx context.Context, arg *RetryVideoMetadataParams) error {
+	_, err := q.db.Exec(ctx, RetryVideoMetadata, arg.MetadataError, arg.DelaySeconds, arg.ID)
+	return err
+}
+
+const RetryVideoThumbnail = `-- name: RetryVideoThumbnail :exec
+UPDATE videos
+SET thumbnail_cache_attempts = thumbnail_cache_attempts + 1,
+    thumbnail_cache_next_attempt_at = now() + make_interval(secs => $1::int)
+WHERE id = $2
+`
+
+type RetryVideoThumbnailParams struct {
+	DelaySeconds int32 `json:"delay_seconds"`
+	ID           int64 `json:"id"`
+}
+
+func (q *Queries) RetryVideoThumbnail(ctx context.Context, arg *RetryVideoThumbnailParams) error {
+	_, err := q.db.Exec(ctx, RetryVideoThumbnail, arg.DelaySeconds, arg.ID)
+	return err
+}
+
+const SaveVideoMetadata = `-- name: SaveVideoMetadata :exec
+UPDATE videos
+SET thumbnail_url = COALESCE($2, thumbnail_url),
+    duration_seconds = COALESCE($3, duration_seconds),
+    published_at = COALESCE($4, published_at),
+    channel_external_id = COALESCE($5, channel_external_id),
+    metadata_status = 'enriched',
+    metadata_error = NULL,
+    metadata_next_attempt_at = NULL
+WHERE id = $1
+`
+
+type SaveVideoMetadataParams struct {
+	ID                int64              `json:"id"`
+	ThumbnailUrl      *string            `json:"thumbnail_url"`
+	DurationSeconds   *int32             `json:"duration_seconds"`
+	PublishedAt       pgtype.Timestamptz `json:"published_at"`
+	ChannelExternalID *string            `json:"channel_external_id"`
+}
+
+func (q *Queries) SaveVideoMetadata(ctx context.Context, arg *SaveVideoMetadataParams) error {
+	_, err := q.db.Exec(ctx, SaveVideoMetadata,
+		arg.ID,
+		arg.ThumbnailUrl,
+		arg.DurationSeconds,
+		arg.PublishedAt,
+		arg.ChannelExternalID,
+	)
+	return err
+}
+
+const SaveVideoThumbnail = `-- name: SaveVideoThumbnail :exec
+UPDATE videos
+SET thumbnail_hash = $2,
+    thumbnail_source_url = thumbnail_url,
+    thumbnail_cache_attempts = 0
+WHERE thumbnail_url = $1
+`
+
+type SaveVideoThumbnailParams struct {
+	ThumbnailUrl  *string `json:"thumbnail_url"`
+	ThumbnailHash *string `json:"thumbnail_hash"`
+}
+
+func (q *Queries) SaveVideoThumbnail(ctx context.Context, arg *SaveVideoThumbnailParams) error {
+	_, err := q.db.Exec(ctx, SaveVideoThumbnail, arg.ThumbnailUrl, arg.ThumbnailHash)
+	return err
+}
+
+const SetVideoAvailability = `-- name: SetVideoAvailability :exec
+UPDATE videos
+SET availability = $1,
+    availability_reason = $2,
+    last_checked_at = now(),
+    availability_next_check_at = now() + make_interval(secs => $3::int)
+WHERE platform = $4 AND video_id = $5
+`
+
+type SetVideoAvailabilityParams struct {
+	Availability       string  `json:"availability"`
+	AvailabilityReason *string `json:"availability_reason"`
+	NextCheckSeconds   int32   `json:"next_check_seconds"`
+	Platform           string  `json:"platform"`
+	VideoID            string  `json:"video_id"`
+}
+
+func (q *Queries) SetVideoAvailability(ctx context.Context, arg *SetVideoAvailabilityParams) error {
+	_, err := q.db.Exec(ctx, SetVideoAvailability,
+		arg.Availability,
+		arg.AvailabilityReason,
+		arg.NextCheckSeconds,
+		arg.Platform,
+		arg.VideoID,
+	)
+	return err
+}
+
+const UserHasThumbnail = `-- name: UserHasThumbnail :one
+SELECT EXISTS (
+    SELECT 1 FROM videos
+    WHERE user_id = $1 AND thumbnail_hash = $2
+)
+`
+
+type UserHasThumbnailParams struct {
+	UserID        string  `json:"user_id"`
+	ThumbnailHash *string `json:"thumbnail_hash"`
+}
+
+func (q *Queries) UserHasThumbnail(ctx context.Context, arg *UserHasThumbnailParams) (bool, error) {
+	row := q.db.QueryRow(ctx, UserHasThumbnail, arg.UserID, arg.ThumbnailHash)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
@@ -0,0 +1,102 @@
+package ingest
+
+import (
+	"math"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	// maxDurationSeconds caps a client-supplied duration; longer values are clamped to it
+	maxDurationSeconds = 7 * 24 * 60 * 60
+	// maxThumbnailURLLength is the longest thumbnail URL that is kept
+	maxThumbnailURLLength = 2048
+	// uploadDateSkew is how far in the future an upload date may be, to allow for time zones
+	uploadDateSkew = 24 * time.Hour
+)
+
+// channelHandlePattern matches @handles: letters, digits, underscores, hyphens, periods and middle dots
+var channelHandlePattern = regexp.MustCompile(`^@[\p{L}\p{N}_.·-]{1,100}$`)
+
+// minUploadDate is the earliest upload date that is kept
+var minUploadDate = time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// applyDetails copies the optional details a client read off the video's page onto the result
+// Values that are out of range or malformed are dropped rather than failing the video, so a
+// change to the page the client reads from can't stop videos from being saved.
+func applyDetails(processed *ProcessedVideoInfo, video VideoInfo) {
+	processed.DurationSeconds = clampDuration(video.DurationSeconds)
+	processed.ThumbnailURL = cleanThumbnailURL(video.ThumbnailURL)
+	processed.ViewCount = clampViewCount(video.ViewCount)
+	processed.PublishedAt = parseUploadDate(video.UploadDate, time.Now())
+	processed.ChannelHandle = cleanChannelHandle(video.ChannelHandle)
+}
+
+// clampDuration rounds a duration to whole seconds, clamped to maxDurationSeconds
+// Returns 0, meaning unknown, for values that aren't positive
+func clampDuration(seconds float64) int32 {
+	if math.IsNaN(seconds) || seconds < 0.5 {
+		return 0
+	}
+	return int32(math.Round(math.Min(seconds, maxDurationSeconds)))
+}
+
+// clampViewCount rounds a view count down to a whole number, clamped to the int64 range
+// Returns 0, meaning unknown, for values that aren't positive
+func clampViewCount(views float64) int64 {
+	if math.IsNaN(views) || views < 1 {
+		return 0
+	}
+	if views >= math.MaxInt64 {
+		return math.MaxInt64
+	}
+	return int64(views)
+}
+
+// cleanThumbnailURL returns the thumbnail URL if it is an absolute http(s) URL
+func cleanThumbnailURL(rawURL string) string {
+	rawURL = strings.TrimSpace(rawURL)
+	if rawURL == "" || len(rawURL) > maxThumbnailURLLength {
+		return ""
+	}
+	parsed, err := url.Parse(rawURL)
+	if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" {
+		return ""
+	}
+	return parsed.String()
+}
+
+// parseUploadDate parses an RFC 3339 timestamp or a YYYY-MM-DD date
+// Dates before minUploadDate or after now are dropped.
+func parseUploadDate(value string, now time.Time) time.Time {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		if t, err = time.Parse(time.DateOnly, value); err != nil {
+			return time.Time{}
+		}
+	}
+	if t.Before(minUploadDate) || t.After(now.Add(uploadDateSkew)) {
+		return time.Time{}
+	}
+	return t.UTC()
+}
+
+// cleanChannelHandle returns the handle with a single leading @, or "" if it isn't a valid handle
+func cleanChannelHandle(handle string) string {
+	handle = strings.TrimSpace(handle)
+	if handle == "" {
+		return ""
+	}
+	handle = "@" + strings.TrimLeft(handle, "@")
+	if !channelHandlePattern.MatchString(handle) {
+		return ""
+	}
+	return handle
+}
@@ -0,0 +1,100 @@
+package ingest
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClampDuration(t *testing.T) {
+	cases := map[float64]int32{
+		212.6:        213,
+		60:           60,
+		0.4:          0,
+		0:            0,
+		-5:           0,
+		math.NaN():   0,
+		math.Inf(1):  maxDurationSeconds,
+		1e12:         maxDurationSeconds,
+		math.Inf(-1): 0,
+	}
+	for seconds, want := range cases {
+		if got := clampDuration(seconds); got != want {
+			t.Errorf("clampDuration(%v) = %d, want %d", seconds, got, want)
+		}
+	}
+}
+
+func TestClampViewCount(t *testing.T) {
+	cases := map[float64]int64{
+		1234567:     1234567,
+		12.9:        12,
+		0:           0,
+		-1:          0,
+		math.NaN():  0,
+		1e30:        math.MaxInt64,
+		math.Inf(1): math.MaxInt64,
+	}
+	for views, want := range cases {
+		if got := clampViewCount(views); got != want {
+			t.Errorf("clampViewCount(%v) = %d, want %d", views, got, want)
+		}
+	}
+}
+
+func TestCleanThumbnailURL(t *testing.T) {
+	cases := map[string]string{
+		"https://i.ytimg.com/vi/dQw4w9WgXcQ/hqdefault.jpg": "https://i.ytimg.com/vi/dQw4w9WgXcQ/hqdefault.jpg",
+		" http://example.com/a.jpg ":                       "http://example.com/a.jpg",
+		"javascript:alert(1)":                              "",
+		"data:image/png;base64,AAAA":                       "",
+		"/vi/dQw4w9WgXcQ/hqdefault.jpg":                    "",
+		"https://":                                         "",
+		"https://example.com/" + strings.Repeat("a", 2048): "",
+		"": "",
+	}
+	for rawURL, want := range cases {
+		if got := cleanThumbnailURL(rawURL); got != want {
+			t.Errorf("cleanThumbnailURL(%q) = %q, want %q", rawURL, got, want)
+		}
+	}
+}
+
+func TestParseUploadDate(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	cases := map[string]time.Time{
+		"2009-10-25":                time.Date(2009, 10, 25, 0, 0, 0, 0, time.UTC),
+		"2009-10-25T06:57:33Z":      time.Date(2009, 10, 25, 6, 57, 33, 0, time.UTC),
+		"2009-10-25T08:57:33+02:00": time.Date(2009, 10, 25, 6, 57, 33, 0, time.UTC),
+		"2025-06-02":                time.Date(2025, 6, 2, 0, 0, 0, 0, time.UTC),
+		"2025-06-03":                {},
+		"1969-12-31":                {},
+		"Oct 25, 2009":              {},
+		"":                          {},
+	}
+	for value, want := range cases {
+		if got := parseUploadDate(value, now); !got.Equal(want) {
+			t.Errorf("parseUploadDate(%q) = %s, want %s", value, got, want)
+		}
+	}
+}
+
+func TestCleanChannelHandle(t *testing.T) {
+	cases := map[string]string{
+		"@RickAstleyYT": "@RickAstleyYT",
+		"RickAstleyYT":  "@RickAstleyYT",
+		"@@rick.astley": "@rick.astley",
+		" @rick_astley": "@rick_astley",
+		"@Zoë-Keating":  "@Zoë-Keating",
+		"@rick astley":  "",
+		"@":             "",
+		"@<script>":     "",
+		"":              "",
+	}
+	for handle, want := range cases {
+		if got := cleanChannelHandle(handle); got != want {
+			t.Errorf("cleanChannelHandle(%q) = %q, want %q", handle, got, want)
+		}
+	}
+}
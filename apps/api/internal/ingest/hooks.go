@@ -0,0 +1,154 @@
+package ingest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ekkolyth/ekko-playlist/api/internal/logging"
+	"github.com/ekkolyth/ekko-playlist/api/internal/lua"
+)
+
+// hookTagColor is the color given to tags that hooks create
+const hookTagColor = "blue"
+
+// maxHookTags caps how many tags hooks may attach to a single video
+const maxHookTags = 20
+
+// HookChanges records what the ingestion hooks did to a video
+type HookChanges struct {
+	// Applied lists the hooks that changed, tagged or rejected the video, in the order they ran
+	Applied         []string `json:"applied"`
+	OriginalTitle   string   `json:"originalTitle,omitempty"`
+	OriginalChannel string   `json:"originalChannel,omitempty"`
+	RejectedBy      string   `json:"rejectedBy,omitempty"`
+	RejectReason    string   `json:"rejectReason,omitempty"`
+}
+
+// applyHooks runs the ingestion hooks over a normalized video
+// Each hook's on_ingest(video) receives the video as changed by the hooks before it and
+// returns nil to leave it alone, or a table with any of:
+//
+//	title   string  replaces the title
+//	channel string  replaces the channel
+//	tags    list    tag names to attach when the video is saved
+//	reject  bool    rejects the video; later hooks don't run
+//	reason  string  why the video was rejected
+//
+// A hook that fails is logged and skipped so a broken hook never blocks ingestion
+func (s *Service) applyHooks(ctx context.Context, video *ProcessedVideoInfo) {
+	hooks := s.luaService.Hooks()
+	if len(hooks) == 0 {
+		return
+	}
+
+	originalTitle := video.Title
+	originalChannel := video.Channel
+	changes := &HookChanges{}
+
+	for _, hook := range hooks {
+		result, err := s.luaService.Call(ctx, hook, lua.HookFunc, map[string]interface{}{
+			"channel":       video.Channel,
+			"title":         video.Title,
+			"url":           video.OriginalURL,
+			"normalizedUrl": video.NormalizedURL,
+			"platform":      video.Platform,
+			"videoId":       video.VideoID,
+			"kind":          video.Kind,
+			"startSeconds":  video.StartSeconds,
+			"tags":          append([]string{}, video.Tags...),
+		})
+		if err != nil {
+			logging.Info("Hooks: %s failed for %s, skipping: %s", hook, video.NormalizedURL, err.Error())
+			continue
+		}
+
+		output, ok := result.(map[string]interface{})
+		if !ok {
+			if result != nil {
+				logging.Info("Hooks: %s returned %T instead of a table, ignoring", hook, result)
+			}
+			continue
+		}
+
+		applied := false
+		if title, ok := output["title"].(string); ok && title != video.Title {
+			video.Title = title
+			applied = true
+		}
+		if channel, ok := output["channel"].(string); ok && channel != video.Channel {
+			video.Channel = channel
+			applied = true
+		}
+		if tags := hookTags(output["tags"]); len(tags) > 0 {
+			before := len(video.Tags)
+			video.Tags = mergeTags(video.Tags, tags)
+			applied = applied || len(video.Tags) != before
+		}
+
+		if reject, _ := output["reject"].(bool); reject {
+			reason, _ := output["reason"].(string)
+			changes.Applied = append(changes.Applied, hook)
+			changes.RejectedBy = hook
+			changes.RejectReason = reason
+
+			video.IsValid = false
+			video.Error = fmt.Sprintf("Rejected by %s", hook)
+			if reason != "" {
+				video.Error += ": " + reason
+			}
+			break
+		}
+
+		if applied {
+			changes.Applied = append(changes.Applied, hook)
+		}
+	}
+
+	if len(changes.Applied) == 0 {
+		return
+	}
+	if video.Title != originalTitle {
+		changes.OriginalTitle = originalTitle
+	}
+	if video.Channel != originalChannel {
+		changes.OriginalChannel = originalChannel
+	}
+	video.Hooks = changes
+}
+
+// hookTags reads the tag names a hook returned
+// An empty Lua table converts to a map rather than a list, so anything but a list is ignored
+func hookTags(value interface{}) []string {
+	list, ok := value.([]interface{})
+	if !ok {
+		return nil
+	}
+
+	tags := make([]string, 0, len(list))
+	for _, item := range list {
+		if tag, ok := item.(string); ok {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// mergeTags adds the trimmed, non-empty names in tags to existing, skipping duplicates
+// At most maxHookTags names are kept
+func mergeTags(existing, tags []string) []string {
+	seen := make(map[string]bool, len(existing)+len(tags))
+	for _, tag := range existing {
+		seen[tag] = true
+	}
+
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[tag] || len(existing) >= maxHookTags {
+			continue
+		}
+		seen[tag] = true
+		existing = append(existing, tag)
+	}
+	return existing
+}
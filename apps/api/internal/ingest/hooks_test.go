@@ -0,0 +1,117 @@
+package ingest
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/ekkolyth/ekko-playlist/api/internal/lua"
+)
+
+const hookTestURL = "https://www.youtube.com/watch?v=dQw4w9WgXcQ"
+
+// newHookService returns an ingestion service running the given hook scripts, keyed by name
+func newHookService(t *testing.T, hooks map[string]string) *Service {
+	t.Helper()
+	luaService, err := lua.NewService()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { luaService.Close() })
+	if err := luaService.SetDatabaseScripts(hooks); err != nil {
+		t.Fatal(err)
+	}
+	return NewService(luaService, nil)
+}
+
+func TestHooksRewriteAndTag(t *testing.T) {
+	s := newHookService(t, map[string]string{
+		"hooks/10-tag.lua": `function on_ingest(video)
+			return { tags = { "music", " music ", "80s", "" } }
+		end`,
+		// Later hooks see the changes of earlier ones
+		"hooks/20-title.lua": `function on_ingest(video)
+			local tags = video.tags
+			table.insert(tags, "tagged " .. #video.tags)
+			return { title = video.title .. " (Remastered)", channel = "Rick Astley", tags = tags }
+		end`,
+		"hooks/30-noop.lua": `function on_ingest(video) return nil end`,
+	})
+
+	video := s.Normalize(context.Background(), "", VideoInfo{URL: hookTestURL, Title: "Never Gonna Give You Up", Channel: "RickAstleyVEVO"})
+	if !video.IsValid {
+		t.Fatalf("video is invalid: %s", video.Error)
+	}
+	if video.Title != "Never Gonna Give You Up (Remastered)" || video.Channel != "Rick Astley" {
+		t.Errorf("title = %q, channel = %q", video.Title, video.Channel)
+	}
+	if want := []string{"music", "80s", "tagged 2"}; !reflect.DeepEqual(video.Tags, want) {
+		t.Errorf("tags = %q, want %q", video.Tags, want)
+	}
+
+	want := &HookChanges{
+		Applied:         []string{"hooks/10-tag.lua", "hooks/20-title.lua"},
+		OriginalTitle:   "Never Gonna Give You Up",
+		OriginalChannel: "RickAstleyVEVO",
+	}
+	if !reflect.DeepEqual(video.Hooks, want) {
+		t.Errorf("hooks = %+v, want %+v", video.Hooks, want)
+	}
+}
+
+func TestHooksReject(t *testing.T) {
+	s := newHookService(t, map[string]string{
+		"hooks/10-reject.lua": `function on_ingest(video)
+			if video.platform == "youtube" then return { reject = true, reason = "no youtube" } end
+		end`,
+		"hooks/20-tag.lua": `function on_ingest(video) return { tags = { "never" } } end`,
+	})
+
+	video := s.Normalize(context.Background(), "", VideoInfo{URL: hookTestURL, Title: "Title"})
+	if video.IsValid || video.Status != VideoStatusInvalid {
+		t.Fatalf("isValid = %t, status = %q, want a rejected video", video.IsValid, video.Status)
+	}
+	if video.Error != "Rejected by hooks/10-reject.lua: no youtube" {
+		t.Errorf("error = %q", video.Error)
+	}
+	if len(video.Tags) != 0 {
+		t.Errorf("tags = %q, hooks after a rejection ran", video.Tags)
+	}
+	if video.Hooks == nil || video.Hooks.RejectedBy != "hooks/10-reject.lua" || video.Hooks.RejectReason != "no youtube" {
+		t.Errorf("hooks = %+v", video.Hooks)
+	}
+}
+
+func TestHooksSkipFailures(t *testing.T) {
+	s := newHookService(t, map[string]string{
+		"hooks/10-error.lua":  `function on_ingest(video) error("broken") end`,
+		"hooks/20-string.lua": `function on_ingest(video) return "not a table" end`,
+		"hooks/30-tag.lua":    `function on_ingest(video) return { tags = { "kept" } } end`,
+	})
+
+	video := s.Normalize(context.Background(), "", VideoInfo{URL: hookTestURL, Title: "Title"})
+	if !video.IsValid {
+		t.Fatalf("video is invalid: %s", video.Error)
+	}
+	if !reflect.DeepEqual(video.Tags, []string{"kept"}) {
+		t.Errorf("tags = %q", video.Tags)
+	}
+	if video.Hooks == nil || !reflect.DeepEqual(video.Hooks.Applied, []string{"hooks/30-tag.lua"}) {
+		t.Errorf("hooks = %+v", video.Hooks)
+	}
+}
+
+func TestMergeTagsLimit(t *testing.T) {
+	var tags []string
+	for i := 0; i < maxHookTags+5; i++ {
+		tags = append(tags, fmt.Sprintf("tag %d", i))
+	}
+	merged := mergeTags([]string{"tag 0"}, tags)
+	if len(merged) != maxHookTags {
+		t.Fatalf("len(merged) = %d, want %d", len(merged), maxHookTags)
+	}
+	if merged[0] != "tag 0" || merged[1] != "tag 1" {
+		t.Errorf("merged = %q", merged)
+	}
+}
@@ -0,0 +1,432 @@
+package ingest
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/ekkolyth/ekko-playlist/api/internal/db"
+	"github.com/ekkolyth/ekko-playlist/api/internal/logging"
+	"github.com/ekkolyth/ekko-playlist/api/internal/lua"
+)
+
+// VideoInfo is a video as submitted by a client, before normalization
+// The optional details are what the client could read off the video's page; they are validated
+// by Normalize and fill in what a library video is missing when it is saved again.
+type VideoInfo struct {
+	Channel string `json:"channel"`
+	URL     string `json:"url"`
+	Title   string `json:"title"`
+
+	DurationSeconds float64 `json:"durationSeconds,omitempty"`
+	ThumbnailURL    string  `json:"thumbnailUrl,omitempty"`
+	ViewCount       float64 `json:"viewCount,omitempty"`
+	UploadDate      string  `json:"uploadDate,omitempty"`
+	ChannelHandle   string  `json:"channelHandle,omitempty"`
+}
+
+// Ingestion outcomes reported in ProcessedVideoInfo.Status
+const (
+	// VideoStatusCreated means the video was added to the library
+	VideoStatusCreated = "created"
+	// VideoStatusDuplicate means the video was already in the library
+	VideoStatusDuplicate = "duplicate"
+	// VideoStatusInvalid means the URL could not be normalized or a hook rejected the video
+	VideoStatusInvalid = "invalid"
+	// VideoStatusError means the video could not be saved
+	VideoStatusError = "error"
+)
+
+// ProcessedVideoInfo is the ingestion outcome for a single VideoInfo
+// Status is empty for valid videos until Save records whether they were stored; ID is then
+// the library video's database ID. StartSeconds is the timestamp the URL started at, if any;
+// the same video saved at different timestamps is stored as separate library videos.
+type ProcessedVideoInfo struct {
+	Channel       string       `json:"channel"`
+	OriginalURL   string       `json:"originalUrl"`
+	NormalizedURL string       `json:"normalizedUrl"`
+	Title         string       `json:"title"`
+	Platform      string       `json:"platform,omitempty"`
+	VideoID       string       `json:"videoId,omitempty"`
+	Kind          string       `json:"kind,omitempty"`
+	StartSeconds  int32        `json:"startSeconds,omitempty"`
+	Tags          []string     `json:"tags,omitempty"`
+	Hooks         *HookChanges `json:"hooks,omitempty"`
+	IsValid       bool         `json:"isValid"`
+	Status        string       `json:"status,omitempty"`
+	ID            int64        `json:"id,omitempty"`
+	Error         string       `json:"error,omitempty"`
+
+	// Details the client supplied, after validation; zero values are unknown
+	DurationSeconds int32     `json:"durationSeconds,omitempty"`
+	ThumbnailURL    string    `json:"thumbnailUrl,omitempty"`
+	ViewCount       int64     `json:"viewCount,omitempty"`
+	PublishedAt     time.Time `json:"publishedAt,omitzero"`
+	ChannelHandle   string    `json:"channelHandle,omitempty"`
+}
+
+// normalizeWorkers bounds how many videos NormalizeAll normalizes at once
+var normalizeWorkers = runtime.GOMAXPROCS(0)
+
+// Service normalizes submitted videos and stores them in a user's library
+type Service struct {
+	luaService *lua.Service
+	dbService  *db.Service
+}
+
+// NewService creates a new ingestion service
+func NewService(luaService *lua.Service, dbService *db.Service) *Service {
+	return &Service{
+		luaService: luaService,
+		dbService:  dbService,
+	}
+}
+
+// Normalize runs a video's URL through the Lua normalizer for its platform, then runs
+// valid videos through the ingestion hooks, which can read userID's tags with ekko.user_tags()
+// Normalization failures and hook rejections are reported on the result rather than returned as errors
+func (s *Service) Normalize(ctx context.Context, userID string, video VideoInfo) ProcessedVideoInfo {
+	return s.normalize(s.hookContext(ctx, userID), video)
+}
+
+// NormalizeAll normalizes videos like Normalize, on up to normalizeWorkers goroutines
+// Results are returned in the same order as videos
+func (s *Service) NormalizeAll(ctx context.Context, userID string, videos []VideoInfo) []ProcessedVideoInfo {
+	ctx = s.hookContext(ctx, userID)
+	results := make([]ProcessedVideoInfo, len(videos))
+
+	workers := min(normalizeWorkers, len(videos))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range next {
+				results[idx] = s.normalize(ctx, videos[idx])
+			}
+		}()
+	}
+
+	for idx := range videos {
+		next <- idx
+	}
+	close(next)
+	wg.Wait()
+
+	return results
+}
+
+// hookContext makes userID's tags available to ingestion hooks
+func (s *Service) hookContext(ctx context.Context, userID string) context.Context {
+	if userID == "" {
+		return ctx
+	}
+	return lua.WithUserTags(ctx, s.userTags(userID))
+}
+
+func (s *Service) normalize(ctx context.Context, video VideoInfo) ProcessedVideoInfo {
+	result, err := s.luaService.NormalizeURL(ctx, video.URL)
+	if err != nil {
+		return ProcessedVideoInfo{
+			Channel:       video.Channel,
+			OriginalURL:   video.URL,
+			NormalizedURL: "",
+			Title:         video.Title,
+			IsValid:       false,
+			Status:        VideoStatusError,
+			Error:         "Failed to normalize URL: " + err.Error(),
+		}
+	}
+
+	// Extract values from Lua result
+	isValid := false
+	normalizedURL := ""
+	platform := ""
+	videoID := ""
+	kind := ""
+	startSeconds := int32(0)
+	errorMsg := ""
+
+	if val, ok := result["isValid"].(bool); ok {
+		isValid = val
+	}
+
+	if val, ok := result["normalizedUrl"].(string); ok {
+		normalizedURL = val
+	}
+
+	if val, ok := result["platform"].(string); ok {
+		platform = val
+	}
+
+	if val, ok := result["videoId"].(string); ok {
+		videoID = val
+	}
+
+	if val, ok := result["kind"].(string); ok {
+		kind = val
+	}
+
+	if val, ok := result["startSeconds"].(float64); ok && val > 0 && val <= math.MaxInt32 {
+		startSeconds = int32(val)
+	}
+
+	if val, ok := result["error"].(string); ok {
+		errorMsg = val
+	}
+
+	processed := ProcessedVideoInfo{
+		Channel:       video.Channel,
+		OriginalURL:   video.URL,
+		NormalizedURL: normalizedURL,
+		Title:         video.Title,
+		Platform:      platform,
+		VideoID:       videoID,
+		Kind:          kind,
+		StartSeconds:  startSeconds,
+		IsValid:       isValid,
+		Error:         errorMsg,
+	}
+	applyDetails(&processed, video)
+
+	if processed.IsValid {
+		s.applyHooks(ctx, &processed)
+	}
+	if !processed.IsValid {
+		processed.Status = VideoStatusInvalid
+	}
+
+	return processed
+}
+
+// userTags returns a lua.UserTagsFunc listing the names of userID's tags
+func (s *Service) userTags(userID string) lua.UserTagsFunc {
+	return func(ctx context.Context) ([]string, error) {
+		tags, err := s.dbService.Queries.ListTags(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+
+		names := make([]string, 0, len(tags))
+		for _, tag := range tags {
+			names = append(names, tag.Name)
+		}
+		return names, nil
+	}
+}
+
+// videoKey identifies a library video: the same video at another start timestamp is a separate video
+type videoKey struct {
+	normalizedURL string
+	startSeconds  int32
+}
+
+// Save stores the valid videos in the user's library with a single batch insert
+// Each video's outcome is recorded on it: Status becomes VideoStatusCreated or
+// VideoStatusDuplicate with ID set to the library video's ID, or VideoStatusError.
+// Duplicates get the details they were missing (title, channel, thumbnail, duration, upload date,
+// channel handle) filled in from the batch, and keep the highest view count.
+// Tags added by hooks are created as needed and attached to the new videos.
+// If the transaction fails every video it would have saved is marked VideoStatusError
+// and the error is returned.
+func (s *Service) Save(ctx context.Context, userID string, videos []ProcessedVideoInfo) error {
+	// Collect the videos to insert, once per URL and timestamp; repeats within the batch share the outcome
+	pending := make(map[videoKey][]int, len(videos))
+	batch := make([]int, 0, len(videos))
+	for i := range videos {
+		video := &videos[i]
+		if !video.IsValid || video.Status != "" {
+			continue
+		}
+		if video.NormalizedURL == "" || video.VideoID == "" || video.Platform == "" {
+			logging.Info("Warning: Normalizer returned no video ID or platform for URL: %s", video.OriginalURL)
+			video.Status = VideoStatusError
+			video.Error = "Normalizer returned no video ID or platform"
+			continue
+		}
+		key := videoKey{video.NormalizedURL, video.StartSeconds}
+		if _, ok := pending[key]; !ok {
+			batch = append(batch, i)
+		}
+		pending[key] = append(pending[key], i)
+	}
+
+	if len(batch) == 0 {
+		return nil
+	}
+
+	// Unknown details are sent as zero values, which CreateVideos stores as NULL
+	params := &db.CreateVideosParams{
+		UserID:          userID,
+		VideoIds:        make([]string, len(batch)),
+		NormalizedUrls:  make([]string, len(batch)),
+		OriginalUrls:    make([]string, len(batch)),
+		Titles:          make([]string, len(batch)),
+		Channels:        make([]string, len(batch)),
+		Platforms:       make([]string, len(batch)),
+		StartSeconds:    make([]int32, len(batch)),
+		ThumbnailUrls:   make([]string, len(batch)),
+		DurationSeconds: make([]int32, len(batch)),
+		PublishedAts:    make([]pgtype.Timestamptz, len(batch)),
+		ViewCounts:      make([]int64, len(batch)),
+		ChannelHandles:  make([]string, len(batch)),
+	}
+	for n, i := range batch {
+		params.VideoIds[n] = videos[i].VideoID
+		params.NormalizedUrls[n] = videos[i].NormalizedURL
+		params.OriginalUrls[n] = videos[i].OriginalURL
+		params.Titles[n] = videos[i].Title
+		params.Channels[n] = videos[i].Channel
+		params.Platforms[n] = videos[i].Platform
+		params.StartSeconds[n] = videos[i].StartSeconds
+		params.ThumbnailUrls[n] = videos[i].ThumbnailURL
+		params.DurationSeconds[n] = videos[i].DurationSeconds
+		params.PublishedAts[n] = pgtype.Timestamptz{Time: videos[i].PublishedAt, Valid: !videos[i].PublishedAt.IsZero()}
+		params.ViewCounts[n] = videos[i].ViewCount
+		params.ChannelHandles[n] = videos[i].ChannelHandle
+	}
+
+	inserted := make(map[videoKey]int64, len(batch))
+	existing := make(map[videoKey]int64)
+	filled := 0
+	err := s.dbService.DB.WithTx(ctx, func(q *db.Queries) error {
+		// Videos already in the library are only returned if the batch filled in some of their details
+		rows, err := q.CreateVideos(ctx, params)
+		if err != nil {
+			return fmt.Errorf("failed to insert videos: %w", err)
+		}
+		for _, video := range rows {
+			key := videoKey{video.NormalizedUrl, video.StartSeconds}
+			if video.Inserted {
+				inserted[key] = video.ID
+			} else {
+				existing[key] = video.ID
+				filled++
+			}
+		}
+
+		if len(inserted)+len(existing) < len(batch) {
+			rows, err := q.ListVideoIDsByURL(ctx, &db.ListVideoIDsByURLParams{
+				UserID:         userID,
+				NormalizedUrls: params.NormalizedUrls,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to look up existing videos: %w", err)
+			}
+			for _, row := range rows {
+				key := videoKey{row.NormalizedUrl, row.StartSeconds}
+				if _, ok := inserted[key]; !ok {
+					existing[key] = row.ID
+				}
+			}
+		}
+
+		if err := assignChannels(ctx, q, userID, inserted, existing); err != nil {
+			return err
+		}
+
+		tagIDs := make(map[string]int64)
+		for _, i := range batch {
+			id, ok := inserted[videoKey{videos[i].NormalizedURL, videos[i].StartSeconds}]
+			if !ok || len(videos[i].Tags) == 0 {
+				continue
+			}
+			if err := attachTags(ctx, q, userID, id, videos[i].Tags, tagIDs); err != nil {
+				return fmt.Errorf("failed to tag video '%s': %w", videos[i].Title, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		for _, indexes := range pending {
+			for _, i := range indexes {
+				videos[i].Status = VideoStatusError
+				videos[i].Error = "Failed to save video"
+			}
+		}
+		return err
+	}
+
+	created, duplicates := 0, 0
+	for key, indexes := range pending {
+		for n, i := range indexes {
+			video := &videos[i]
+			if id, ok := inserted[key]; ok && n == 0 {
+				video.Status = VideoStatusCreated
+				video.ID = id
+				created++
+			} else if ok {
+				video.Status = VideoStatusDuplicate
+				video.ID = id
+				duplicates++
+			} else if id, ok := existing[key]; ok {
+				video.Status = VideoStatusDuplicate
+				video.ID = id
+				duplicates++
+			} else {
+				// Conflicted with a video that was deleted before it could be looked up
+				video.Status = VideoStatusError
+				video.Error = "Failed to save video"
+			}
+		}
+	}
+
+	logging.Info("DB: Saved %d new videos for user %s, %d duplicates (%d with details filled in)", created, userID, duplicates, filled)
+	return nil
+}
+
+// assignChannels links the saved videos that have no channel yet to the user's channel with the
+// same handle, or failing that the same name, creating channels that don't exist yet
+func assignChannels(ctx context.Context, q *db.Queries, userID string, inserted, existing map[videoKey]int64) error {
+	ids := make([]int64, 0, len(inserted)+len(existing))
+	for _, id := range inserted {
+		ids = append(ids, id)
+	}
+	for _, id := range existing {
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	if err := q.CreateChannelsForVideos(ctx, &db.CreateChannelsForVideosParams{UserID: userID, VideoIds: ids}); err != nil {
+		return fmt.Errorf("failed to create channels: %w", err)
+	}
+	if err := q.AssignVideoChannels(ctx, &db.AssignVideoChannelsParams{UserID: userID, VideoIds: ids}); err != nil {
+		return fmt.Errorf("failed to assign channels: %w", err)
+	}
+	return nil
+}
+
+// attachTags tags a saved video, creating any of the user's tags that don't exist yet
+// tagIDs caches tag IDs by name for the rest of the transaction
+func attachTags(ctx context.Context, q *db.Queries, userID string, videoID int64, tags []string, tagIDs map[string]int64) error {
+	ids := make([]int64, 0, len(tags))
+	for _, name := range tags {
+		id, ok := tagIDs[name]
+		if !ok {
+			tag, err := q.EnsureTag(ctx, &db.EnsureTagParams{
+				UserID: userID,
+				Name:   name,
+				Color:  hookTagColor,
+			})
+			if err != nil {
+				return err
+			}
+			id = tag.ID
+			tagIDs[name] = id
+		}
+		ids = append(ids, id)
+	}
+
+	return q.AddVideoTags(ctx, &db.AddVideoTagsParams{
+		VideoIds: []int64{videoID},
+		TagIds:   ids,
+	})
+}
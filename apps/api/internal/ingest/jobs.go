@@ -0,0 +1,294 @@
+package ingest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/ekkolyth/ekko-playlist/api/internal/db"
+	"github.com/ekkolyth/ekko-playlist/api/internal/logging"
+)
+
+// Job statuses, mirrored by the jobs_status_check constraint
+const (
+	JobStatusQueued    = "queued"
+	JobStatusRunning   = "running"
+	JobStatusCompleted = "completed"
+	JobStatusFailed    = "failed"
+)
+
+const (
+	// jobChunkSize is how many videos are normalized and saved before progress is recorded
+	jobChunkSize = 500
+	// jobChunkTimeout bounds the work on a single chunk
+	jobChunkTimeout = time.Minute
+	// jobLease is how long a running job can go without recording progress before it's considered
+	// abandoned by a stopped or crashed worker and claimed again. It outlasts a chunk and its update.
+	jobLease = 3 * jobChunkTimeout
+	// jobPollInterval is how often idle workers look for jobs queued elsewhere or abandoned
+	jobPollInterval = 5 * time.Second
+)
+
+// WorkerPool runs queued ingestion jobs in the background
+type WorkerPool struct {
+	service *Service
+	workers int
+	notify  chan struct{}
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+
+	// Chunks in flight run under chunkCtx, which outlives the workers' context so a chunk can
+	// finish after shutdown begins, and is cancelled by abort once the shutdown deadline passes
+	chunkCtx context.Context
+	abort    context.CancelFunc
+}
+
+// NewWorkerPool creates a worker pool with the given number of workers
+func NewWorkerPool(service *Service, workers int) *WorkerPool {
+	if workers < 1 {
+		workers = 1
+	}
+	return &WorkerPool{
+		service: service,
+		workers: workers,
+		notify:  make(chan struct{}, workers),
+	}
+}
+
+// Start starts the workers
+// Workers claim queued jobs and, once their lease has run out, jobs abandoned by a previous
+// process, so jobs interrupted by a crash are resumed whenever the API restarts.
+func (p *WorkerPool) Start(ctx context.Context) {
+	p.chunkCtx, p.abort = context.WithCancel(context.WithoutCancel(ctx))
+	ctx, p.cancel = context.WithCancel(ctx)
+
+	for i := 0; i < p.workers; i++ {
+		p.wg.Add(1)
+		go p.worker(ctx, i+1)
+	}
+	logging.Info("Jobs: Started %d ingestion workers", p.workers)
+}
+
+// Stop signals the workers to stop and waits for them to finish their current chunk
+// If ctx ends first, the chunks in flight are cancelled and not recorded. Jobs that were
+// interrupted are requeued and resumed on the next start.
+func (p *WorkerPool) Stop(ctx context.Context) error {
+	if p.cancel == nil {
+		return nil
+	}
+	p.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		p.abort()
+		logging.Info("Jobs: All ingestion workers stopped")
+		return nil
+	case <-ctx.Done():
+		// Give the cancelled chunks a moment to requeue their jobs; a job left running is
+		// claimed again once its lease runs out
+		p.abort()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+		}
+		return fmt.Errorf("timed out waiting for ingestion workers: %w", ctx.Err())
+	}
+}
+
+// Enqueue stores a new ingestion job for the user and wakes an idle worker
+func (p *WorkerPool) Enqueue(ctx context.Context, userID string, videos []VideoInfo) (*db.Job, error) {
+	return p.enqueue(ctx, userID, videos, nil, nil)
+}
+
+// EnqueuePlaylist is Enqueue for the videos of a fetched playlist, keeping its Warning on the job
+// With a non-nil playlistID the saved videos are also added to that ekko playlist, positioned
+// in the order they were fetched.
+func (p *WorkerPool) EnqueuePlaylist(ctx context.Context, userID string, fetched *FetchedPlaylist, playlistID *int64) (*db.Job, error) {
+	var warning *string
+	if fetched.Warning != "" {
+		warning = &fetched.Warning
+	}
+	return p.enqueue(ctx, userID, fetched.Videos, playlistID, warning)
+}
+
+func (p *WorkerPool) enqueue(ctx context.Context, userID string, videos []VideoInfo, playlistID *int64, warning *string) (*db.Job, error) {
+	payload, err := json.Marshal(videos)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode job payload: %w", err)
+	}
+
+	job, err := p.service.dbService.Queries.CreateJob(ctx, &db.CreateJobParams{
+		UserID:     userID,
+		Payload:    payload,
+		Total:      int32(len(videos)),
+		PlaylistID: playlistID,
+		Warning:    warning,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create job: %w", err)
+	}
+
+	select {
+	case p.notify <- struct{}{}:
+	default:
+		// All workers are already awake
+	}
+
+	return job, nil
+}
+
+func (p *WorkerPool) worker(ctx context.Context, n int) {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(jobPollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Drain the queue before going idle
+		for ctx.Err() == nil {
+			job, err := p.service.dbService.Queries.ClaimNextJob(ctx, int32(jobLease/time.Second))
+			if err != nil {
+				// pgx.ErrNoRows means the queue is empty
+				if !errors.Is(err, pgx.ErrNoRows) && ctx.Err() == nil {
+					logging.Info("Jobs: Worker %d failed to claim a job: %s", n, err.Error())
+				}
+				break
+			}
+			logging.Info("Jobs: Worker %d claimed job %s (%d/%d processed)", n, job.ID.String(), job.Processed, job.Total)
+			p.runJob(ctx, job)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-p.notify:
+		case <-ticker.C:
+		}
+	}
+}
+
+// runJob processes a claimed job chunk by chunk, recording progress after each chunk
+func (p *WorkerPool) runJob(ctx context.Context, job *db.Job) {
+	var videos []VideoInfo
+	if err := json.Unmarshal(job.Payload, &videos); err != nil {
+		p.failJob(job.ID, fmt.Sprintf("invalid job payload: %s", err.Error()))
+		return
+	}
+
+	results := make([]ProcessedVideoInfo, 0, len(videos))
+	if err := json.Unmarshal(job.Results, &results); err != nil {
+		p.failJob(job.ID, fmt.Sprintf("invalid job results: %s", err.Error()))
+		return
+	}
+
+	processed := int(job.Processed)
+	for processed < len(videos) {
+		if ctx.Err() != nil {
+			p.requeueJob(job.ID)
+			return
+		}
+
+		end := min(processed+jobChunkSize, len(videos))
+		// A chunk that fails to save still has its results recorded, with their error statuses
+		chunkResults, chunkErr := p.runChunk(job, videos[processed:end], processed)
+		if p.chunkCtx.Err() != nil {
+			// Shutdown cut the chunk short; it's redone when the job is resumed
+			p.requeueJob(job.ID)
+			return
+		}
+		results = append(results, chunkResults...)
+		processed = end
+
+		encoded, err := json.Marshal(results)
+		if err != nil {
+			p.failJob(job.ID, fmt.Sprintf("failed to encode job results: %s", err.Error()))
+			return
+		}
+
+		// Progress is recorded even if shutdown has begun, so the chunk isn't redone
+		updateCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+		err = p.service.dbService.Queries.UpdateJobProgress(updateCtx, &db.UpdateJobProgressParams{
+			ID:        job.ID,
+			Processed: int32(processed),
+			Results:   encoded,
+		})
+		cancel()
+		if err != nil {
+			logging.Info("Jobs: Failed to record progress for job %s: %s", job.ID.String(), err.Error())
+			p.requeueJob(job.ID)
+			return
+		}
+
+		if chunkErr != nil {
+			p.failJob(job.ID, chunkErr.Error())
+			return
+		}
+	}
+
+	completeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+	if err := p.service.dbService.Queries.CompleteJob(completeCtx, job.ID); err != nil {
+		logging.Info("Jobs: Failed to mark job %s completed: %s", job.ID.String(), err.Error())
+		return
+	}
+	logging.Info("Jobs: Job %s completed (%d videos)", job.ID.String(), len(videos))
+}
+
+// runChunk normalizes and saves a slice of a job's videos, starting at offset in the job
+// If the job has a playlist, the saved videos are added to it at their offset.
+// The results are returned even if saving fails. A chunk in flight is allowed to finish
+// after shutdown begins, until Stop's deadline.
+func (p *WorkerPool) runChunk(job *db.Job, videos []VideoInfo, offset int) ([]ProcessedVideoInfo, error) {
+	chunkCtx, cancel := context.WithTimeout(p.chunkCtx, jobChunkTimeout)
+	defer cancel()
+
+	results := p.service.NormalizeAll(chunkCtx, job.UserID, videos)
+
+	if err := p.service.Save(chunkCtx, job.UserID, results); err != nil {
+		return results, fmt.Errorf("failed to save videos: %w", err)
+	}
+
+	if job.PlaylistID != nil {
+		if err := p.service.addToPlaylist(chunkCtx, *job.PlaylistID, results, offset); err != nil {
+			return results, fmt.Errorf("failed to add videos to playlist: %w", err)
+		}
+	}
+
+	return results, nil
+}
+
+func (p *WorkerPool) failJob(id pgtype.UUID, reason string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	logging.Info("Jobs: Job %s failed: %s", id.String(), reason)
+	if err := p.service.dbService.Queries.FailJob(ctx, &db.FailJobParams{
+		ID:    id,
+		Error: &reason,
+	}); err != nil {
+		logging.Info("Jobs: Failed to mark job %s failed: %s", id.String(), err.Error())
+	}
+}
+
+func (p *WorkerPool) requeueJob(id pgtype.UUID) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := p.service.dbService.Queries.RequeueJob(ctx, id); err != nil {
+		logging.Info("Jobs: Failed to requeue job %s: %s", id.String(), err.Error())
+		return
+	}
+	logging.Info("Jobs: Job %s requeued", id.String())
+}
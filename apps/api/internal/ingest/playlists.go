@@ -0,0 +1,125 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/ekkolyth/ekko-playlist/api/internal/db"
+)
+
+// MaxPlaylistMembers caps how many videos are read from a fetched playlist (YouTube's own limit)
+const MaxPlaylistMembers = 5000
+
+// maxPlaylistNameAttempts bounds the "Name (n)" suffixes tried when a playlist name is taken
+const maxPlaylistNameAttempts = 20
+
+// ErrPlaylistNotFound is returned by a PlaylistFetcher for playlists that don't exist or are private
+var ErrPlaylistNotFound = errors.New("playlist not found")
+
+// playlistIDPattern matches YouTube playlist IDs
+var playlistIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// FetchedPlaylist is a remote playlist and its member videos, in playlist order
+// Warning is set when Videos may not be the whole playlist, and is kept with the job it's queued as.
+type FetchedPlaylist struct {
+	ID      string
+	Title   string
+	Videos  []VideoInfo
+	Warning string
+}
+
+// PlaylistFetcher reads the members of a remote playlist
+type PlaylistFetcher interface {
+	// FetchPlaylist returns the playlist's videos in order, at most MaxPlaylistMembers of them
+	// It returns ErrPlaylistNotFound if the playlist doesn't exist or can't be read
+	FetchPlaylist(ctx context.Context, playlistID string) (*FetchedPlaylist, error)
+}
+
+// YouTubePlaylistID returns the playlist ID of a youtube.com/playlist?list=... URL
+// Watch URLs that carry a list= parameter are videos, not playlists, and are not matched
+func YouTubePlaylistID(rawURL string) (string, bool) {
+	rawURL = strings.TrimSpace(rawURL)
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "https://" + rawURL
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", false
+	}
+
+	host := strings.ToLower(u.Hostname())
+	if host != "youtube.com" && !strings.HasSuffix(host, ".youtube.com") {
+		return "", false
+	}
+	if strings.TrimSuffix(u.Path, "/") != "/playlist" {
+		return "", false
+	}
+
+	id := u.Query().Get("list")
+	if !playlistIDPattern.MatchString(id) {
+		return "", false
+	}
+	return id, true
+}
+
+// CreatePlaylist creates an ekko playlist named name for the user
+// If the name is taken, "name (2)", "name (3)" and so on are tried instead
+func (s *Service) CreatePlaylist(ctx context.Context, userID, name string) (*db.Playlist, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = "Imported playlist"
+	}
+
+	candidate := name
+	for attempt := 1; attempt <= maxPlaylistNameAttempts; attempt++ {
+		if attempt > 1 {
+			candidate = fmt.Sprintf("%s (%d)", name, attempt)
+		}
+
+		playlist, err := s.dbService.Queries.CreatePlaylist(ctx, &db.CreatePlaylistParams{
+			UserID: userID,
+			Name:   candidate,
+		})
+		if err == nil {
+			return playlist, nil
+		}
+
+		var pgErr *pgconn.PgError
+		if !errors.As(err, &pgErr) || pgErr.Code != "23505" {
+			return nil, err
+		}
+	}
+
+	return nil, fmt.Errorf("no free playlist name like '%s'", name)
+}
+
+// addToPlaylist adds the saved videos in results to an ekko playlist
+// A result's position in the playlist is offset plus its index in results
+func (s *Service) addToPlaylist(ctx context.Context, playlistID int64, results []ProcessedVideoInfo, offset int) error {
+	videoIDs := make([]int64, 0, len(results))
+	positions := make([]int32, 0, len(results))
+	for i, result := range results {
+		if result.ID == 0 {
+			continue
+		}
+		videoIDs = append(videoIDs, result.ID)
+		positions = append(positions, int32(offset+i))
+	}
+
+	if len(videoIDs) == 0 {
+		return nil
+	}
+
+	return s.dbService.Queries.AddVideosToPlaylist(ctx, &db.AddVideosToPlaylistParams{
+		PlaylistID: playlistID,
+		VideoIds:   videoIDs,
+		Positions:  positions,
+	})
+}
@@ -0,0 +1,238 @@
+package ingest
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const (
+	// youtubeAPIURL is the YouTube Data API v3 base URL
+	youtubeAPIURL = "https://www.googleapis.com/youtube/v3"
+	// youtubeFeedURL serves a playlist's Atom feed, which lists only its first youtubeFeedSize videos
+	youtubeFeedURL = "https://www.youtube.com/feeds/videos.xml"
+	// youtubeFeedSize is the most videos a playlist feed lists
+	youtubeFeedSize = 15
+	// youtubeWatchURL is the URL of a video given its ID
+	youtubeWatchURL = "https://www.youtube.com/watch?v="
+	// youtubePageSize is the most playlist items the Data API returns per request
+	youtubePageSize = 50
+	// maxYouTubeResponse caps a single API or feed response
+	maxYouTubeResponse = 10 << 20
+)
+
+// YouTubePlaylistFetcher reads YouTube playlists through the Data API when it has an API key,
+// and through the public playlist feed otherwise
+type YouTubePlaylistFetcher struct {
+	apiKey  string
+	client  *http.Client
+	apiURL  string
+	feedURL string
+}
+
+// NewYouTubePlaylistFetcher creates a fetcher; apiKey may be empty to use the playlist feed
+func NewYouTubePlaylistFetcher(apiKey string) *YouTubePlaylistFetcher {
+	return &YouTubePlaylistFetcher{
+		apiKey:  apiKey,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		apiURL:  youtubeAPIURL,
+		feedURL: youtubeFeedURL,
+	}
+}
+
+// FetchPlaylist returns a YouTube playlist's videos in order
+// Deleted and private videos are skipped. Without an API key only the first youtubeFeedSize videos
+// can be read, and a full feed is returned with a Warning.
+func (f *YouTubePlaylistFetcher) FetchPlaylist(ctx context.Context, playlistID string) (*FetchedPlaylist, error) {
+	if f.apiKey == "" {
+		return f.fetchFeed(ctx, playlistID)
+	}
+	return f.fetchAPI(ctx, playlistID)
+}
+
+// youtubePlaylistsResponse is the part of a Data API playlists.list response that is used
+type youtubePlaylistsResponse struct {
+	Items []struct {
+		Snippet struct {
+			Title string `json:"title"`
+		} `json:"snippet"`
+	} `json:"items"`
+}
+
+// youtubePlaylistItemsResponse is the part of a Data API playlistItems.list response that is used
+type youtubePlaylistItemsResponse struct {
+	NextPageToken string `json:"nextPageToken"`
+	Items         []struct {
+		Snippet struct {
+			Title                  string `json:"title"`
+			VideoOwnerChannelTitle string `json:"videoOwnerChannelTitle"`
+			ResourceID             struct {
+				VideoID string `json:"videoId"`
+			} `json:"resourceId"`
+		} `json:"snippet"`
+	} `json:"items"`
+}
+
+func (f *YouTubePlaylistFetcher) fetchAPI(ctx context.Context, playlistID string) (*FetchedPlaylist, error) {
+	var playlists youtubePlaylistsResponse
+	if err := f.getJSON(ctx, "/playlists", url.Values{
+		"part": {"snippet"},
+		"id":   {playlistID},
+	}, &playlists); err != nil {
+		return nil, err
+	}
+	if len(playlists.Items) == 0 {
+		return nil, ErrPlaylistNotFound
+	}
+
+	playlist := &FetchedPlaylist{
+		ID:    playlistID,
+		Title: playlists.Items[0].Snippet.Title,
+	}
+
+	pageToken := ""
+	for len(playlist.Videos) < MaxPlaylistMembers {
+		params := url.Values{
+			"part":       {"snippet"},
+			"playlistId": {playlistID},
+			"maxResults": {fmt.Sprint(youtubePageSize)},
+		}
+		if pageToken != "" {
+			params.Set("pageToken", pageToken)
+		}
+
+		var page youtubePlaylistItemsResponse
+		if err := f.getJSON(ctx, "/playlistItems", params, &page); err != nil {
+			return nil, err
+		}
+
+		for _, item := range page.Items {
+			// Deleted and private videos have no owner channel
+			if item.Snippet.ResourceID.VideoID == "" || item.Snippet.VideoOwnerChannelTitle == "" {
+				continue
+			}
+			if len(playlist.Videos) == MaxPlaylistMembers {
+				break
+			}
+			playlist.Videos = append(playlist.Videos, VideoInfo{
+				URL:     youtubeWatchURL + item.Snippet.ResourceID.VideoID,
+				Title:   item.Snippet.Title,
+				Channel: item.Snippet.VideoOwnerChannelTitle,
+			})
+		}
+
+		if page.NextPageToken == "" {
+			break
+		}
+		pageToken = page.NextPageToken
+	}
+
+	return playlist, nil
+}
+
+// getJSON calls a Data API endpoint and decodes its response
+func (f *YouTubePlaylistFetcher) getJSON(ctx context.Context, endpoint string, params url.Values, out interface{}) error {
+	params.Set("key", f.apiKey)
+	body, err := f.get(ctx, f.apiURL+endpoint+"?"+params.Encode())
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	if err := json.NewDecoder(io.LimitReader(body, maxYouTubeResponse)).Decode(out); err != nil {
+		return fmt.Errorf("invalid YouTube API response: %w", err)
+	}
+	return nil
+}
+
+// youtubeFeed is the part of a playlist's Atom feed that is used
+type youtubeFeed struct {
+	Title   string `xml:"title"`
+	Entries []struct {
+		VideoID string `xml:"http://www.youtube.com/xml/schemas/2015 videoId"`
+		Title   string `xml:"title"`
+		Author  struct {
+			Name string `xml:"name"`
+		} `xml:"author"`
+	} `xml:"entry"`
+}
+
+func (f *YouTubePlaylistFetcher) fetchFeed(ctx context.Context, playlistID string) (*FetchedPlaylist, error) {
+	body, err := f.get(ctx, f.feedURL+"?"+url.Values{"playlist_id": {playlistID}}.Encode())
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	var feed youtubeFeed
+	if err := xml.NewDecoder(io.LimitReader(body, maxYouTubeResponse)).Decode(&feed); err != nil {
+		return nil, fmt.Errorf("invalid YouTube playlist feed: %w", err)
+	}
+
+	playlist := &FetchedPlaylist{
+		ID:    playlistID,
+		Title: feed.Title,
+	}
+	// A full feed has most likely cut the playlist short
+	if len(feed.Entries) >= youtubeFeedSize {
+		playlist.Warning = fmt.Sprintf("Only the first %d videos of the playlist were read; a YouTube API key is needed to read longer playlists", youtubeFeedSize)
+	}
+	for _, entry := range feed.Entries {
+		if entry.VideoID == "" {
+			continue
+		}
+		playlist.Videos = append(playlist.Videos, VideoInfo{
+			URL:     youtubeWatchURL + entry.VideoID,
+			Title:   entry.Title,
+			Channel: entry.Author.Name,
+		})
+	}
+	return playlist, nil
+}
+
+// get fetches a URL, mapping 404 (and the Data API's 403 for private playlists) to ErrPlaylistNotFound
+func (f *YouTubePlaylistFetcher) get(ctx context.Context, rawURL string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach YouTube: %w", err)
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		return resp.Body, nil
+	case resp.StatusCode == http.StatusNotFound:
+		resp.Body.Close()
+		return nil, ErrPlaylistNotFound
+	case resp.StatusCode == http.StatusForbidden && f.apiKey != "":
+		// playlistItems.list answers 403 playlistItemsNotAccessible for private playlists, but also
+		// for quota and key errors, so the body decides
+		defer resp.Body.Close()
+		var apiErr struct {
+			Error struct {
+				Errors []struct {
+					Reason string `json:"reason"`
+				} `json:"errors"`
+			} `json:"error"`
+		}
+		_ = json.NewDecoder(io.LimitReader(resp.Body, maxYouTubeResponse)).Decode(&apiErr)
+		for _, e := range apiErr.Error.Errors {
+			if e.Reason == "playlistItemsNotAccessible" {
+				return nil, ErrPlaylistNotFound
+			}
+		}
+		return nil, fmt.Errorf("YouTube returned %s", resp.Status)
+	default:
+		resp.Body.Close()
+		return nil, fmt.Errorf("YouTube returned %s", resp.Status)
+	}
+}
@@ -0,0 +1,224 @@
+package ingest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestYouTubePlaylistID(t *testing.T) {
+	cases := []struct {
+		url  string
+		id   string
+		isOK bool
+	}{
+		{"https://www.youtube.com/playlist?list=PLx0sYbCqOb8TBPRdmBHs5Iftvv9TPboYG", "PLx0sYbCqOb8TBPRdmBHs5Iftvv9TPboYG", true},
+		{"youtube.com/playlist?list=PL0-_a", "PL0-_a", true},
+		{"https://m.youtube.com/playlist/?list=PL1&si=x", "PL1", true},
+		{"https://music.youtube.com/playlist?list=OLAK5uy_k", "OLAK5uy_k", true},
+		{"https://www.youtube.com/watch?v=dQw4w9WgXcQ&list=PL1", "", false},
+		{"https://www.youtube.com/playlist", "", false},
+		{"https://www.youtube.com/playlist?list=PL%201", "", false},
+		{"https://youtu.be/dQw4w9WgXcQ?list=PL1", "", false},
+		{"https://example.com/playlist?list=PL1", "", false},
+	}
+
+	for _, c := range cases {
+		id, ok := YouTubePlaylistID(c.url)
+		if id != c.id || ok != c.isOK {
+			t.Errorf("YouTubePlaylistID(%q) = %q, %t; want %q, %t", c.url, id, ok, c.id, c.isOK)
+		}
+	}
+}
+
+// newTestFetcher returns a fetcher whose API and feed requests go to handler
+func newTestFetcher(t *testing.T, apiKey string, handler http.HandlerFunc) *YouTubePlaylistFetcher {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	f := NewYouTubePlaylistFetcher(apiKey)
+	f.apiURL = server.URL + "/youtube/v3"
+	f.feedURL = server.URL + "/feeds/videos.xml"
+	return f
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func playlistItem(videoID, title, channel string) map[string]interface{} {
+	return map[string]interface{}{
+		"snippet": map[string]interface{}{
+			"title":                  title,
+			"videoOwnerChannelTitle": channel,
+			"resourceId":             map[string]string{"kind": "youtube#video", "videoId": videoID},
+		},
+	}
+}
+
+func TestYouTubePlaylistFetcherAPI(t *testing.T) {
+	f := newTestFetcher(t, "test-key", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if query.Get("key") != "test-key" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "missing key"})
+			return
+		}
+
+		switch r.URL.Path {
+		case "/youtube/v3/playlists":
+			if query.Get("id") != "PL1" {
+				writeJSON(w, http.StatusOK, map[string]interface{}{"items": []interface{}{}})
+				return
+			}
+			writeJSON(w, http.StatusOK, map[string]interface{}{
+				"items": []interface{}{map[string]interface{}{"snippet": map[string]string{"title": "Conference Talks"}}},
+			})
+		case "/youtube/v3/playlistItems":
+			if query.Get("playlistId") != "PL1" || query.Get("maxResults") != "50" {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "bad request"})
+				return
+			}
+			if query.Get("pageToken") == "" {
+				writeJSON(w, http.StatusOK, map[string]interface{}{
+					"nextPageToken": "page2",
+					"items": []interface{}{
+						playlistItem("aaaaaaaaaaa", "First", "Channel A"),
+						playlistItem("bbbbbbbbbbb", "Private video", ""),
+					},
+				})
+				return
+			}
+			writeJSON(w, http.StatusOK, map[string]interface{}{
+				"items": []interface{}{playlistItem("ccccccccccc", "Second", "Channel C")},
+			})
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	playlist, err := f.FetchPlaylist(context.Background(), "PL1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &FetchedPlaylist{
+		ID:    "PL1",
+		Title: "Conference Talks",
+		Videos: []VideoInfo{
+			{URL: "https://www.youtube.com/watch?v=aaaaaaaaaaa", Title: "First", Channel: "Channel A"},
+			{URL: "https://www.youtube.com/watch?v=ccccccccccc", Title: "Second", Channel: "Channel C"},
+		},
+	}
+	if !reflect.DeepEqual(playlist, want) {
+		t.Errorf("FetchPlaylist = %+v, want %+v", playlist, want)
+	}
+
+	if _, err := f.FetchPlaylist(context.Background(), "PL404"); !errors.Is(err, ErrPlaylistNotFound) {
+		t.Errorf("FetchPlaylist(missing) error = %v, want ErrPlaylistNotFound", err)
+	}
+}
+
+func TestYouTubePlaylistFetcherAPIErrors(t *testing.T) {
+	reason := ""
+	f := newTestFetcher(t, "test-key", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/youtube/v3/playlists" {
+			writeJSON(w, http.StatusOK, map[string]interface{}{
+				"items": []interface{}{map[string]interface{}{"snippet": map[string]string{"title": "Private"}}},
+			})
+			return
+		}
+		writeJSON(w, http.StatusForbidden, map[string]interface{}{
+			"error": map[string]interface{}{"errors": []interface{}{map[string]string{"reason": reason}}},
+		})
+	})
+
+	reason = "playlistItemsNotAccessible"
+	if _, err := f.FetchPlaylist(context.Background(), "PL1"); !errors.Is(err, ErrPlaylistNotFound) {
+		t.Errorf("private playlist error = %v, want ErrPlaylistNotFound", err)
+	}
+
+	reason = "quotaExceeded"
+	if _, err := f.FetchPlaylist(context.Background(), "PL1"); err == nil || errors.Is(err, ErrPlaylistNotFound) {
+		t.Errorf("quota error = %v, want a fetch error", err)
+	}
+}
+
+func TestYouTubePlaylistFetcherFeed(t *testing.T) {
+	f := newTestFetcher(t, "", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/feeds/videos.xml" || r.URL.Query().Get("playlist_id") != "PL1" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/atom+xml")
+		_, _ = w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?>
+<feed xmlns:yt="http://www.youtube.com/xml/schemas/2015" xmlns="http://www.w3.org/2005/Atom">
+  <yt:playlistId>PL1</yt:playlistId>
+  <title>Conference Talks</title>
+  <author><name>Playlist Owner</name></author>
+  <entry>
+    <yt:videoId>aaaaaaaaaaa</yt:videoId>
+    <title>First</title>
+    <author><name>Channel A</name></author>
+  </entry>
+  <entry>
+    <yt:videoId>ccccccccccc</yt:videoId>
+    <title>Second &amp; Last</title>
+    <author><name>Channel C</name></author>
+  </entry>
+</feed>`))
+	})
+
+	playlist, err := f.FetchPlaylist(context.Background(), "PL1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &FetchedPlaylist{
+		ID:    "PL1",
+		Title: "Conference Talks",
+		Videos: []VideoInfo{
+			{URL: "https://www.youtube.com/watch?v=aaaaaaaaaaa", Title: "First", Channel: "Channel A"},
+			{URL: "https://www.youtube.com/watch?v=ccccccccccc", Title: "Second & Last", Channel: "Channel C"},
+		},
+	}
+	if !reflect.DeepEqual(playlist, want) {
+		t.Errorf("FetchPlaylist = %+v, want %+v", playlist, want)
+	}
+
+	if _, err := f.FetchPlaylist(context.Background(), "PL404"); !errors.Is(err, ErrPlaylistNotFound) {
+		t.Errorf("FetchPlaylist(missing) error = %v, want ErrPlaylistNotFound", err)
+	}
+}
+
+func TestYouTubePlaylistFetcherFullFeed(t *testing.T) {
+	var feed strings.Builder
+	feed.WriteString(`<feed xmlns:yt="http://www.youtube.com/xml/schemas/2015" xmlns="http://www.w3.org/2005/Atom"><title>Long</title>`)
+	for i := 0; i < youtubeFeedSize; i++ {
+		feed.WriteString(fmt.Sprintf(`<entry><yt:videoId>video%06d</yt:videoId><title>Video %d</title></entry>`, i, i))
+	}
+	feed.WriteString(`</feed>`)
+
+	f := newTestFetcher(t, "", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(feed.String()))
+	})
+
+	playlist, err := f.FetchPlaylist(context.Background(), "PL1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(playlist.Videos) != youtubeFeedSize {
+		t.Errorf("got %d videos, want %d", len(playlist.Videos), youtubeFeedSize)
+	}
+	// The playlist may be longer than the feed, which has to be reported
+	if playlist.Warning == "" {
+		t.Error("a full feed has no warning")
+	}
+}
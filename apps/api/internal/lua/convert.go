@@ -0,0 +1,234 @@
+package lua
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// maxConvertDepth bounds table nesting when converting values
+const maxConvertDepth = 32
+
+// goToLuaValue converts a Go value to a Lua value
+// Slices and arrays become Lua arrays (1-based), maps with string keys become tables.
+// Structs and other types are converted through their JSON encoding, so json tags apply.
+func goToLuaValue(L *lua.LState, v interface{}) (lua.LValue, error) {
+	return goToLuaValueDepth(L, v, 0)
+}
+
+func goToLuaValueDepth(L *lua.LState, v interface{}, depth int) (lua.LValue, error) {
+	if depth > maxConvertDepth {
+		return lua.LNil, fmt.Errorf("argument nested more than %d levels deep", maxConvertDepth)
+	}
+
+	switch val := v.(type) {
+	case nil:
+		return lua.LNil, nil
+	case lua.LValue:
+		return val, nil
+	case string:
+		return lua.LString(val), nil
+	case bool:
+		return lua.LBool(val), nil
+	case json.Number:
+		f, err := val.Float64()
+		if err != nil {
+			return lua.LNil, err
+		}
+		return lua.LNumber(f), nil
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return lua.LNumber(rv.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return lua.LNumber(rv.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return lua.LNumber(rv.Float()), nil
+	case reflect.String:
+		return lua.LString(rv.String()), nil
+	case reflect.Bool:
+		return lua.LBool(rv.Bool()), nil
+	case reflect.Pointer, reflect.Interface:
+		if rv.IsNil() {
+			return lua.LNil, nil
+		}
+		return goToLuaValueDepth(L, rv.Elem().Interface(), depth)
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			return lua.LNil, nil
+		}
+		table := L.CreateTable(rv.Len(), 0)
+		for i := 0; i < rv.Len(); i++ {
+			item, err := goToLuaValueDepth(L, rv.Index(i).Interface(), depth+1)
+			if err != nil {
+				return lua.LNil, err
+			}
+			table.RawSetInt(i+1, item)
+		}
+		return table, nil
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			break
+		}
+		if rv.IsNil() {
+			return lua.LNil, nil
+		}
+		table := L.CreateTable(0, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			item, err := goToLuaValueDepth(L, iter.Value().Interface(), depth+1)
+			if err != nil {
+				return lua.LNil, err
+			}
+			table.RawSetString(iter.Key().String(), item)
+		}
+		return table, nil
+	}
+
+	// Fall back to the JSON representation (structs, maps with non-string keys, etc.)
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return lua.LNil, fmt.Errorf("unsupported argument type %T: %w", v, err)
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		return lua.LNil, fmt.Errorf("unsupported argument type %T: %w", v, err)
+	}
+	return goToLuaValueDepth(L, decoded, depth)
+}
+
+// luaValueToGoValue converts a Lua value to a Go value
+// Numbers become float64. Tables whose keys are exactly 1..n become []interface{};
+// all other tables, including empty ones, become map[string]interface{}.
+// Results larger than maxResultSize and tables that contain themselves are rejected.
+func luaValueToGoValue(lv lua.LValue) (interface{}, error) {
+	c := &resultConverter{remaining: maxResultSize, visiting: make(map[*lua.LTable]bool)}
+	return c.convert(lv, 0)
+}
+
+// maxResultSize caps the size of a converted value, counted as the bytes of its strings and
+// keys plus one per value, so that tables referencing the same table many times can't expand
+// into a huge result
+const maxResultSize = 4 << 20
+
+// errResultTooLarge is returned when a converted value exceeds maxResultSize
+var errResultTooLarge = fmt.Errorf("result exceeds %d bytes", maxResultSize)
+
+// errCyclicResult is returned when a table of a result contains itself
+var errCyclicResult = errors.New("result contains a cyclic table")
+
+// resultConverter converts a Lua value, counting its size against a budget
+type resultConverter struct {
+	remaining int
+	// visiting holds the tables being converted, the current one and its ancestors
+	visiting map[*lua.LTable]bool
+}
+
+// spend charges n bytes of the result to the budget
+func (c *resultConverter) spend(n int) error {
+	c.remaining -= n
+	if c.remaining < 0 {
+		return errResultTooLarge
+	}
+	return nil
+}
+
+func (c *resultConverter) convert(lv lua.LValue, depth int) (interface{}, error) {
+	if err := c.spend(1); err != nil {
+		return nil, err
+	}
+
+	switch v := lv.(type) {
+	case *lua.LNilType:
+		return nil, nil
+	case lua.LBool:
+		return bool(v), nil
+	case lua.LString:
+		if err := c.spend(len(v)); err != nil {
+			return nil, err
+		}
+		return string(v), nil
+	case lua.LNumber:
+		return float64(v), nil
+	case *lua.LTable:
+		if depth >= maxConvertDepth {
+			return nil, fmt.Errorf("result nested more than %d levels deep", maxConvertDepth)
+		}
+		if c.visiting[v] {
+			return nil, errCyclicResult
+		}
+		c.visiting[v] = true
+		defer delete(c.visiting, v)
+
+		if isLuaArray(v) {
+			n := v.Len()
+			result := make([]interface{}, 0, n)
+			for i := 1; i <= n; i++ {
+				item, err := c.convert(v.RawGetInt(i), depth+1)
+				if err != nil {
+					return nil, err
+				}
+				result = append(result, item)
+			}
+			return result, nil
+		}
+
+		result := make(map[string]interface{})
+		var convErr error
+		v.ForEach(func(key lua.LValue, value lua.LValue) {
+			if convErr != nil {
+				return
+			}
+			name := key.String()
+			if convErr = c.spend(len(name)); convErr != nil {
+				return
+			}
+			item, err := c.convert(value, depth+1)
+			if err != nil {
+				convErr = err
+				return
+			}
+			result[name] = item
+		})
+		if convErr != nil {
+			return nil, convErr
+		}
+		return result, nil
+	default:
+		return lv.String(), nil
+	}
+}
+
+// isLuaArray reports whether a table is a non-empty sequence with keys exactly 1..n
+func isLuaArray(table *lua.LTable) bool {
+	n := table.Len()
+	if n == 0 {
+		return false
+	}
+
+	count := 0
+	array := true
+	table.ForEach(func(key lua.LValue, _ lua.LValue) {
+		count++
+		if idx, ok := key.(lua.LNumber); !ok || float64(idx) != float64(int(idx)) || int(idx) < 1 || int(idx) > n {
+			array = false
+		}
+	})
+	return array && count == n
+}
+
+// toResultMap converts a script result to a map
+// Non-table results are wrapped as {"value": result}
+func toResultMap(result interface{}) map[string]interface{} {
+	if m, ok := result.(map[string]interface{}); ok {
+		return m
+	}
+	return map[string]interface{}{
+		"value": result,
+	}
+}
@@ -0,0 +1,125 @@
+package lua
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestConvertNestedValues(t *testing.T) {
+	s, err := newService().WithScript("echo.lua", `function echo(value)
+		value.seen = true
+		return value
+	end`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	type tag struct {
+		Name  string `json:"name"`
+		Color string `json:"color,omitempty"`
+	}
+	arg := map[string]interface{}{
+		"title": "Never Gonna Give You Up",
+		"views": 42,
+		"tags":  []tag{{Name: "music", Color: "red"}, {Name: "80s"}},
+		"ids":   []int64{1, 2, 3},
+		"empty": map[string]interface{}{},
+		"owner": &struct {
+			ID string `json:"id"`
+		}{ID: "user"},
+	}
+
+	result, err := s.Call(context.Background(), "echo.lua", "echo", arg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"title": "Never Gonna Give You Up",
+		"views": float64(42),
+		"tags": []interface{}{
+			map[string]interface{}{"name": "music", "color": "red"},
+			map[string]interface{}{"name": "80s"},
+		},
+		"ids":   []interface{}{float64(1), float64(2), float64(3)},
+		"empty": map[string]interface{}{},
+		"owner": map[string]interface{}{"id": "user"},
+		"seen":  true,
+	}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("result = %#v, want %#v", result, want)
+	}
+}
+
+func TestConvertCyclicTable(t *testing.T) {
+	v := newVM()
+	defer v.close()
+
+	_, err, _ := run(t, context.Background(), v, "cyclic", `function run()
+		local t = { name = "loop", children = {} }
+		t.children[1] = t
+		return t
+	end`)
+	if !errors.Is(err, errCyclicResult) {
+		t.Fatalf("err = %v, want errCyclicResult", err)
+	}
+}
+
+func TestConvertSharedTable(t *testing.T) {
+	v := newVM()
+	defer v.close()
+
+	// A table referenced twice without containing itself is not a cycle
+	result, err, _ := run(t, context.Background(), v, "shared", `function run()
+		local shared = { "x" }
+		return { a = shared, b = { shared } }
+	end`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"a": []interface{}{"x"},
+		"b": []interface{}{[]interface{}{"x"}},
+	}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("result = %#v, want %#v", result, want)
+	}
+}
+
+func TestConvertLimits(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{"depth", `function run()
+			local t = {}
+			for i = 1, 40 do t = { t } end
+			return t
+		end`, "nested more than"},
+		{"size", `function run()
+			local s, t = string.rep("x", 1024 * 1024), {}
+			for i = 1, 8 do t[i] = s end
+			return t
+		end`, errResultTooLarge.Error()},
+		{"repeated references", `function run()
+			local t = { string.rep("x", 1024) }
+			for i = 1, 20 do t = { t, t } end
+			return t
+		end`, errResultTooLarge.Error()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newVM()
+			defer v.close()
+
+			_, err, _ := run(t, context.Background(), v, tt.name, tt.source)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,323 @@
+package lua
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+
+	lua "github.com/yuin/gopher-lua"
+
+	"github.com/ekkolyth/ekko-playlist/api/internal/logging"
+)
+
+// Limits applied to host functions, on top of the VM's own limits
+const (
+	// maxHostInputLength caps the strings host functions accept and produce
+	maxHostInputLength = 1 << 20
+	// maxPatternLength caps the length of ekko.re patterns
+	maxPatternLength = 1024
+	// maxLogLength caps a single ekko.log message
+	maxLogLength = 1024
+	// hostBytesPerInstruction is how many bytes of input cost one instruction of the budget
+	hostBytesPerInstruction = 16
+	// logCost is the instruction cost of one ekko.log call, which bounds log lines per execution
+	logCost = 1000
+	// maxCachedPatterns bounds the compiled ekko.re pattern cache
+	maxCachedPatterns = 256
+)
+
+// hostModuleName is the global the host module is registered as
+const hostModuleName = "ekko"
+
+// UserTagsFunc lists the tag names of the user a script runs for
+type UserTagsFunc func(ctx context.Context) ([]string, error)
+
+type userTagsKey struct{}
+
+// userTags memoizes a UserTagsFunc for the lifetime of a context
+type userTags struct {
+	fn   UserTagsFunc
+	once sync.Once
+	tags []string
+	err  error
+}
+
+// WithUserTags makes ekko.user_tags() return fn's tags for scripts called with the returned context
+// fn is called at most once, the first time a script asks for the tags
+func WithUserTags(ctx context.Context, fn UserTagsFunc) context.Context {
+	return context.WithValue(ctx, userTagsKey{}, &userTags{fn: fn})
+}
+
+// openHostModule registers the ekko host module:
+//
+//	ekko.url.parse(url)          -> { scheme, host, port, path, query, rawQuery, fragment } or nil, err
+//	ekko.re.match(pattern, s)    -> captures (or the whole match) like string.match, using RE2 syntax
+//	ekko.json.encode(value)      -> string or nil, err
+//	ekko.json.decode(s)          -> value or nil, err
+//	ekko.log(...)                -> logs its arguments
+//	ekko.user_tags()             -> list of the current user's tag names
+//
+// Host functions run in Go, so each charges the VM's instruction budget in proportion to
+// its input and checks the call's context before doing any work
+func openHostModule(L *lua.LState) {
+	module := L.NewTable()
+
+	urlModule := L.NewTable()
+	urlModule.RawSetString("parse", L.NewFunction(hostURLParse))
+	module.RawSetString("url", urlModule)
+
+	reModule := L.NewTable()
+	reModule.RawSetString("match", L.NewFunction(hostReMatch))
+	module.RawSetString("re", reModule)
+
+	jsonModule := L.NewTable()
+	jsonModule.RawSetString("encode", L.NewFunction(hostJSONEncode))
+	jsonModule.RawSetString("decode", L.NewFunction(hostJSONDecode))
+	module.RawSetString("json", jsonModule)
+
+	module.RawSetString("log", L.NewFunction(hostLog))
+	module.RawSetString("user_tags", L.NewFunction(hostUserTags))
+
+	L.SetGlobal(hostModuleName, module)
+}
+
+// hostEnter checks the call's context and charges cost instructions to the VM's budget
+// It raises a Lua error, stopping the script, if the call is cancelled or out of budget
+func hostEnter(L *lua.LState, cost int) context.Context {
+	ctx := L.Context()
+	if ctx == nil {
+		return context.Background()
+	}
+
+	budget, ok := ctx.(*budgetContext)
+	if ok && !budget.charge(cost) {
+		L.RaiseError("%s", errInstructionBudget.Error())
+	}
+	if err := ctx.Err(); err != nil {
+		L.RaiseError("%s", err.Error())
+	}
+
+	// Host functions must not spend the budget themselves (e.g. a database driver polling Done)
+	if ok {
+		return budget.Context
+	}
+	return ctx
+}
+
+// hostCost is the instruction cost of processing n bytes in a host function
+func hostCost(n int) int {
+	return 1 + n/hostBytesPerInstruction
+}
+
+// checkHostInput raises a Lua error if s is too long for a host function
+func checkHostInput(L *lua.LState, s string, what string) {
+	if len(s) > maxHostInputLength {
+		L.RaiseError("%s exceeds %d bytes", what, maxHostInputLength)
+	}
+}
+
+// hostURLParse implements ekko.url.parse(url)
+// URLs without a scheme are parsed as https, as clients often submit them that way
+func hostURLParse(L *lua.LState) int {
+	rawURL := L.CheckString(1)
+	checkHostInput(L, rawURL, "url")
+	hostEnter(L, hostCost(len(rawURL)))
+
+	trimmed := strings.TrimSpace(rawURL)
+	if !strings.Contains(trimmed, "://") {
+		trimmed = "https://" + trimmed
+	}
+
+	u, err := url.Parse(trimmed)
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString("invalid url: " + err.Error()))
+		return 2
+	}
+
+	query := L.NewTable()
+	for key, values := range u.Query() {
+		if len(values) > 0 {
+			query.RawSetString(key, lua.LString(values[0]))
+		}
+	}
+
+	result := L.NewTable()
+	result.RawSetString("scheme", lua.LString(strings.ToLower(u.Scheme)))
+	result.RawSetString("host", lua.LString(strings.ToLower(u.Hostname())))
+	result.RawSetString("port", lua.LString(u.Port()))
+	result.RawSetString("path", lua.LString(u.Path))
+	result.RawSetString("query", query)
+	result.RawSetString("rawQuery", lua.LString(u.RawQuery))
+	result.RawSetString("fragment", lua.LString(u.Fragment))
+	L.Push(result)
+	return 1
+}
+
+// patternCache holds compiled ekko.re patterns shared by every VM
+var patternCache = struct {
+	sync.Mutex
+	patterns map[string]*regexp.Regexp
+}{patterns: make(map[string]*regexp.Regexp)}
+
+// compilePattern compiles an RE2 pattern, reusing earlier compilations
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	patternCache.Lock()
+	defer patternCache.Unlock()
+
+	if re, ok := patternCache.patterns[pattern]; ok {
+		return re, nil
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if len(patternCache.patterns) >= maxCachedPatterns {
+		patternCache.patterns = make(map[string]*regexp.Regexp)
+	}
+	patternCache.patterns[pattern] = re
+	return re, nil
+}
+
+// hostReMatch implements ekko.re.match(pattern, s)
+// Patterns use Go's RE2 syntax, which matches in linear time. Like string.match it returns
+// the captures, or the whole match if the pattern has none, or nil if s doesn't match.
+// Optional groups that didn't participate in the match are returned as nil.
+func hostReMatch(L *lua.LState) int {
+	pattern := L.CheckString(1)
+	s := L.CheckString(2)
+	if len(pattern) > maxPatternLength {
+		L.RaiseError("pattern exceeds %d bytes", maxPatternLength)
+	}
+	checkHostInput(L, s, "string")
+	hostEnter(L, hostCost(len(pattern)+len(s)))
+
+	re, err := compilePattern(pattern)
+	if err != nil {
+		L.RaiseError("invalid pattern: %s", err.Error())
+	}
+
+	match := re.FindStringSubmatchIndex(s)
+	if match == nil {
+		L.Push(lua.LNil)
+		return 1
+	}
+
+	if re.NumSubexp() == 0 {
+		L.Push(lua.LString(s[match[0]:match[1]]))
+		return 1
+	}
+
+	for i := 1; i <= re.NumSubexp(); i++ {
+		start, end := match[2*i], match[2*i+1]
+		if start < 0 {
+			L.Push(lua.LNil)
+			continue
+		}
+		L.Push(lua.LString(s[start:end]))
+	}
+	return re.NumSubexp()
+}
+
+// hostJSONEncode implements ekko.json.encode(value)
+// Tables with keys 1..n encode as arrays, other tables (including empty ones) as objects
+func hostJSONEncode(L *lua.LState) int {
+	value := L.CheckAny(1)
+	hostEnter(L, 1)
+
+	decoded, err := luaValueToGoValue(value)
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+
+	encoded, err := json.Marshal(decoded)
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+	if len(encoded) > maxHostInputLength {
+		L.RaiseError("encoded json exceeds %d bytes", maxHostInputLength)
+	}
+	hostEnter(L, hostCost(len(encoded)))
+
+	L.Push(lua.LString(encoded))
+	return 1
+}
+
+// hostJSONDecode implements ekko.json.decode(s)
+// null decodes to nil and numbers to Lua numbers
+func hostJSONDecode(L *lua.LState) int {
+	s := L.CheckString(1)
+	checkHostInput(L, s, "json")
+	hostEnter(L, hostCost(len(s)))
+
+	var decoded interface{}
+	if err := json.Unmarshal([]byte(s), &decoded); err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString("invalid json: " + err.Error()))
+		return 2
+	}
+
+	value, err := goToLuaValue(L, decoded)
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+
+	L.Push(value)
+	return 1
+}
+
+// hostLog implements ekko.log(...)
+// Arguments are converted with tostring and joined with spaces; long messages are truncated
+func hostLog(L *lua.LState) int {
+	hostEnter(L, logCost)
+
+	parts := make([]string, 0, L.GetTop())
+	for i := 1; i <= L.GetTop(); i++ {
+		parts = append(parts, L.ToStringMeta(L.Get(i)).String())
+	}
+
+	message := strings.Join(parts, " ")
+	if len(message) > maxLogLength {
+		message = message[:maxLogLength] + "..."
+	}
+
+	logging.Info("Lua: %s %s", L.Where(1), message)
+	return 0
+}
+
+// hostUserTags implements ekko.user_tags()
+// Scripts called without WithUserTags (e.g. normalizers) see an empty list
+func hostUserTags(L *lua.LState) int {
+	ctx := hostEnter(L, 1)
+
+	result := L.NewTable()
+	provider, ok := ctx.Value(userTagsKey{}).(*userTags)
+	if !ok {
+		L.Push(result)
+		return 1
+	}
+
+	provider.once.Do(func() {
+		provider.tags, provider.err = provider.fn(ctx)
+	})
+	if provider.err != nil {
+		L.RaiseError("failed to list user tags: %s", provider.err.Error())
+	}
+
+	hostEnter(L, len(provider.tags))
+	for i, tag := range provider.tags {
+		result.RawSetInt(i+1, lua.LString(tag))
+	}
+	L.Push(result)
+	return 1
+}
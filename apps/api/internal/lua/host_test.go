@@ -0,0 +1,125 @@
+package lua
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestHostURLParse(t *testing.T) {
+	v := newVM()
+	defer v.close()
+
+	result, err, _ := run(t, context.Background(), v, "url", `function run()
+		local u = ekko.url.parse("WWW.YouTube.com:8080/watch?v=abc&t=42#frag")
+		local bad, msg = ekko.url.parse("https://exa mple.com/%zz")
+		return { u.scheme, u.host, u.port, u.path, u.query.v, u.query.t, u.rawQuery, u.fragment,
+			tostring(bad), msg ~= nil }
+	end`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []interface{}{"https", "www.youtube.com", "8080", "/watch", "abc", "42", "v=abc&t=42", "frag", "nil", true}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("result = %#v, want %#v", result, want)
+	}
+}
+
+func TestHostReMatch(t *testing.T) {
+	v := newVM()
+	defer v.close()
+
+	result, err, _ := run(t, context.Background(), v, "re", `function run()
+		local id = ekko.re.match("[A-Za-z0-9_-]{11}", "v=dQw4w9WgXcQ&t=1")
+		local user, host = ekko.re.match("^(\\w+)@([\\w.]+)$", "rick@example.com")
+		local a, b = ekko.re.match("(x)?(y)", "y")
+		local none = ekko.re.match("\\d+", "abc")
+		return { id, user, host, tostring(a), b, tostring(none) }
+	end`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []interface{}{"dQw4w9WgXcQ", "rick", "example.com", "nil", "y", "nil"}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("result = %#v, want %#v", result, want)
+	}
+
+	_, err, _ = run(t, context.Background(), v, "bad pattern", `function run() return ekko.re.match("(", "x") end`)
+	if err == nil || !strings.Contains(err.Error(), "invalid pattern") {
+		t.Errorf("err = %v, want an invalid pattern error", err)
+	}
+}
+
+func TestHostJSON(t *testing.T) {
+	v := newVM()
+	defer v.close()
+
+	result, err, _ := run(t, context.Background(), v, "json", `function run()
+		local value = ekko.json.decode('{"title":"x","tags":["a","b"],"views":3,"extra":null}')
+		local bad, msg = ekko.json.decode("{")
+		return {
+			title = value.title, tags = value.tags, views = value.views, extra = tostring(value.extra),
+			encoded = ekko.json.encode({ tags = value.tags }),
+			bad = tostring(bad), invalid = msg:find("invalid json") ~= nil,
+		}
+	end`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"title": "x", "tags": []interface{}{"a", "b"}, "views": float64(3), "extra": "nil",
+		"encoded": `{"tags":["a","b"]}`, "bad": "nil", "invalid": true,
+	}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("result = %#v, want %#v", result, want)
+	}
+}
+
+func TestHostUserTags(t *testing.T) {
+	v := newVM()
+	defer v.close()
+
+	// Without WithUserTags scripts see no tags
+	source := `function run() local a, b = ekko.user_tags(), ekko.user_tags() return #a + #b end`
+	result, err, _ := run(t, context.Background(), v, "no tags", source)
+	if err != nil || result != float64(0) {
+		t.Fatalf("result = %v, err = %v, want 0", result, err)
+	}
+
+	calls := 0
+	ctx := WithUserTags(context.Background(), func(context.Context) ([]string, error) {
+		calls++
+		return []string{"music", "80s"}, nil
+	})
+	result, err, _ = run(t, ctx, v, "tags", source)
+	if err != nil || result != float64(4) {
+		t.Fatalf("result = %v, err = %v, want 4", result, err)
+	}
+	if calls != 1 {
+		t.Errorf("tags listed %d times, want once", calls)
+	}
+
+	ctx = WithUserTags(context.Background(), func(context.Context) ([]string, error) {
+		return nil, errors.New("database down")
+	})
+	_, err, _ = run(t, ctx, v, "tags error", source)
+	if err == nil || !strings.Contains(err.Error(), "database down") {
+		t.Errorf("err = %v, want the tags error", err)
+	}
+}
+
+func TestHostChargesBudget(t *testing.T) {
+	v := newVM()
+	defer v.close()
+
+	// Each call is a single instruction for the VM but scans a megabyte
+	_, err, ctxErr := run(t, context.Background(), v, "expensive", `function run()
+		local s = string.rep("a", 1024 * 1024 - 1)
+		for i = 1, 1000 do ekko.re.match("b", s) end
+	end`)
+	if err == nil || !errors.Is(ctxErr, errInstructionBudget) {
+		t.Fatalf("err = %v, context err = %v, want the instruction budget exceeded", err, ctxErr)
+	}
+}
@@ -0,0 +1,146 @@
+package lua
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// normalizerFixture is one case in testdata/normalizers/<platform>.json
+// IsValid defaults to true; valid cases must produce the given video ID, kind and start
+// timestamp, and normalize to NormalizedURL if it is set
+type normalizerFixture struct {
+	URL           string `json:"url"`
+	IsValid       *bool  `json:"isValid"`
+	VideoID       string `json:"videoId"`
+	Kind          string `json:"kind"`
+	NormalizedURL string `json:"normalizedUrl"`
+	StartSeconds  int    `json:"startSeconds"`
+}
+
+// normalizedURLs are the URLs valid fixtures normalize to when they don't set one
+var normalizedURLs = map[string]string{
+	"youtube": "https://www.youtube.com/watch?v=",
+}
+
+// TestNormalizerFixtures runs every embedded normalizer against its fixtures, both directly
+// and through NormalizeURL's host dispatch
+func TestNormalizerFixtures(t *testing.T) {
+	s, err := NewService()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	s.SetInstances("peertube", []string{"framatube.org", "peertube.example.com"})
+
+	files, err := filepath.Glob(filepath.Join("testdata", "normalizers", "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no normalizer fixtures found")
+	}
+
+	for _, file := range files {
+		platform := strings.TrimSuffix(filepath.Base(file), ".json")
+		script := normalizersDir + platform + ".lua"
+
+		t.Run(platform, func(t *testing.T) {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var fixtures []normalizerFixture
+			if err := json.Unmarshal(data, &fixtures); err != nil {
+				t.Fatalf("invalid fixtures: %v", err)
+			}
+
+			ctx := context.Background()
+			for _, fixture := range fixtures {
+				result, err := s.normalize(ctx, script, fixture.URL)
+				if err != nil {
+					t.Errorf("%q: %v", fixture.URL, err)
+					continue
+				}
+				checkFixture(t, platform, fixture, result)
+
+				if fixture.IsValid == nil || *fixture.IsValid {
+					dispatched, err := s.NormalizeURL(ctx, fixture.URL)
+					if err != nil {
+						t.Errorf("%q: NormalizeURL: %v", fixture.URL, err)
+						continue
+					}
+					if got, _ := dispatched["platform"].(string); got != platform {
+						t.Errorf("%q: NormalizeURL dispatched to %q, want %q", fixture.URL, got, platform)
+					}
+				}
+			}
+		})
+	}
+}
+
+// TestNormalizeURLInstances checks that federated scripts only handle their configured instances
+func TestNormalizeURLInstances(t *testing.T) {
+	s, err := NewService()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	s.SetInstances("peertube", []string{" FramaTube.org ", ""})
+
+	tests := map[string]string{
+		"https://framatube.org/w/kkGMgK9ZtnKfYAgnEtQxbv":           "peertube",
+		"https://user@framatube.org:8443/w/kkGMgK9ZtnKfYAgnEtQxbv": "peertube",
+		// Neither unknown hosts nor subdomains of an instance are PeerTube instances
+		"https://example.com/w/kkGMgK9ZtnKfYAgnEtQxbv":          "",
+		"https://videos.framatube.org/w/kkGMgK9ZtnKfYAgnEtQxbv": "",
+	}
+	ctx := context.Background()
+	for url, want := range tests {
+		result, err := s.NormalizeURL(ctx, url)
+		if err != nil {
+			t.Fatalf("%q: %v", url, err)
+		}
+		if got, _ := result["platform"].(string); got != want {
+			t.Errorf("%q: platform = %q, want %q", url, got, want)
+		}
+	}
+}
+
+func checkFixture(t *testing.T, platform string, fixture normalizerFixture, result map[string]interface{}) {
+	t.Helper()
+
+	isValid, _ := result["isValid"].(bool)
+	wantValid := fixture.IsValid == nil || *fixture.IsValid
+	if isValid != wantValid {
+		t.Errorf("%q: isValid = %t, want %t (error: %v)", fixture.URL, isValid, wantValid, result["error"])
+		return
+	}
+	if !wantValid {
+		if msg, _ := result["error"].(string); msg == "" {
+			t.Errorf("%q: invalid result has no error message", fixture.URL)
+		}
+		return
+	}
+
+	wantURL := fixture.NormalizedURL
+	if wantURL == "" {
+		wantURL = normalizedURLs[platform] + fixture.VideoID
+	}
+
+	if got, _ := result["videoId"].(string); got != fixture.VideoID {
+		t.Errorf("%q: videoId = %q, want %q", fixture.URL, got, fixture.VideoID)
+	}
+	if got, _ := result["normalizedUrl"].(string); got != wantURL {
+		t.Errorf("%q: normalizedUrl = %q, want %q", fixture.URL, got, wantURL)
+	}
+	if got, _ := result["kind"].(string); fixture.Kind != "" && got != fixture.Kind {
+		t.Errorf("%q: kind = %q, want %q", fixture.URL, got, fixture.Kind)
+	}
+	if got, _ := result["startSeconds"].(float64); int(got) != fixture.StartSeconds {
+		t.Errorf("%q: startSeconds = %v, want %d", fixture.URL, got, fixture.StartSeconds)
+	}
+}
@@ -0,0 +1,457 @@
+package lua
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	lua "github.com/yuin/gopher-lua"
+	"github.com/yuin/gopher-lua/ast"
+)
+
+// Limits applied to every VM that runs a script
+const (
+	// callStackSize bounds recursion depth
+	callStackSize = 120
+	// registrySize is the initial size of the VM's value stack
+	registrySize = 1024 * 20
+	// registryMaxSize caps how far the value stack may grow
+	registryMaxSize = 1024 * 80
+	// registryGrowStep is how much the value stack grows at a time
+	registryGrowStep = 32
+	// maxInstructions is the instruction budget for a single script execution
+	maxInstructions = 1_000_000
+	// maxExecutionTime bounds a single script execution, independent of the caller's deadline
+	maxExecutionTime = 2 * time.Second
+	// maxStringLength caps the size of strings a script builds, with .. or the string library
+	maxStringLength = 1 << 20
+	// maxFormatWidth caps the width and precision of string.format directives, as Lua 5.1 does
+	maxFormatWidth = 99
+)
+
+// errInstructionBudget is returned when a script runs more than maxInstructions instructions
+var errInstructionBudget = errors.New("instruction budget exceeded")
+
+// safeLibs are the standard libraries opened in sandboxed VMs
+// io, os, package (require), debug, channel and coroutine are never opened
+var safeLibs = []struct {
+	name string
+	open lua.LGFunction
+}{
+	{lua.BaseLibName, lua.OpenBase},
+	{lua.TabLibName, lua.OpenTable},
+	{lua.StringLibName, lua.OpenString},
+	{lua.MathLibName, lua.OpenMath},
+}
+
+// unsafeGlobals are base library functions removed from sandboxed VMs
+// They load code from the filesystem, the module loader or strings outside the script registry,
+// or (getfenv, setfenv) reach the VM's shared globals through another function's environment
+var unsafeGlobals = []string{"dofile", "loadfile", "load", "loadstring", "require", "module", "_printregs",
+	"getfenv", "setfenv"}
+
+// newSandboxedState creates a VM with only the safe standard libraries, the ekko host module
+// and bounded stacks
+// Scripts don't run against these globals directly but against their own copy, see newScriptEnv.
+func newSandboxedState() *lua.LState {
+	L := lua.NewState(lua.Options{
+		CallStackSize:    callStackSize,
+		RegistrySize:     registrySize,
+		RegistryMaxSize:  registryMaxSize,
+		RegistryGrowStep: registryGrowStep,
+		SkipOpenLibs:     true,
+	})
+
+	for _, lib := range safeLibs {
+		L.Push(L.NewFunction(lib.open))
+		L.Push(lua.LString(lib.name))
+		L.Call(1, 0)
+	}
+
+	for _, name := range unsafeGlobals {
+		L.SetGlobal(name, lua.LNil)
+	}
+
+	// String library functions whose result can be much larger than their arguments are
+	// capped at maxStringLength, before they build the result
+	stringLib := L.GetGlobal(lua.StringLibName).(*lua.LTable)
+	stringLib.RawSetString("rep", L.NewFunction(safeStringRep))
+	stringLib.RawSetString("format", L.NewFunction(safeStringFormat(stringLib.RawGetString("format").(*lua.LFunction))))
+	stringLib.RawSetString("gsub", L.NewFunction(safeStringGsub(stringLib.RawGetString("gsub").(*lua.LFunction))))
+	stringLib.RawSetString(concatMethod, L.NewFunction(safeConcat))
+	tableLib := L.GetGlobal(lua.TabLibName).(*lua.LTable)
+	tableLib.RawSetString("concat", L.NewFunction(safeTableConcat(tableLib.RawGetString("concat").(*lua.LFunction))))
+
+	// Strings share a metatable whose __index is the original string library; getmetatable("")
+	// would hand it to scripts
+	if stringMeta, ok := L.GetMetatable(lua.LString("")).(*lua.LTable); ok {
+		stringMeta.RawSetString("__metatable", lua.LFalse)
+	}
+
+	openHostModule(L)
+
+	return L
+}
+
+// newScriptEnv returns a new environment for a script: a deep copy of the VM's globals, with _G
+// pointing to the copy
+// Library tables (string, table, math, ekko) are copied too, so a script that replaces
+// string.match or ekko.log only replaces it for itself, not for the other scripts in the VM.
+func newScriptEnv(L *lua.LState) *lua.LTable {
+	return copyTable(L, L.Get(lua.GlobalsIndex).(*lua.LTable), make(map[*lua.LTable]*lua.LTable))
+}
+
+// copyTable deep-copies a table; copies maps the tables copied so far to their copy
+func copyTable(L *lua.LState, table *lua.LTable, copies map[*lua.LTable]*lua.LTable) *lua.LTable {
+	if copied, ok := copies[table]; ok {
+		return copied
+	}
+	copied := L.NewTable()
+	copies[table] = copied
+	table.ForEach(func(key, value lua.LValue) {
+		if nested, ok := value.(*lua.LTable); ok {
+			value = copyTable(L, nested, copies)
+		}
+		copied.RawSet(key, value)
+	})
+	return copied
+}
+
+// checkStringLength raises a Lua error if a result of n bytes would exceed maxStringLength
+func checkStringLength(L *lua.LState, n int, what string) {
+	if n > maxStringLength {
+		L.RaiseError("%s result exceeds %d bytes", what, maxStringLength)
+	}
+}
+
+// safeStringRep is string.rep with the result size capped at maxStringLength
+func safeStringRep(L *lua.LState) int {
+	str := L.CheckString(1)
+	n := L.CheckInt(2)
+	if n <= 0 || str == "" {
+		L.Push(lua.LString(""))
+		return 1
+	}
+	if n > maxStringLength/len(str) {
+		checkStringLength(L, maxStringLength+1, "string.rep")
+	}
+	L.Push(lua.LString(strings.Repeat(str, n)))
+	return 1
+}
+
+// formatDirective matches a string.format directive's flags, width and precision
+var formatDirective = regexp.MustCompile(`%[-+ #0]*(\d*)(?:\.(\d*))?`)
+
+// safeStringFormat wraps string.format, rejecting widths and precisions over maxFormatWidth
+// and results that could exceed maxStringLength
+func safeStringFormat(format *lua.LFunction) lua.LGFunction {
+	return func(L *lua.LState) int {
+		str := L.CheckString(1)
+		size := len(str)
+		for _, directive := range formatDirective.FindAllStringSubmatch(strings.ReplaceAll(str, "%%", ""), -1) {
+			if len(directive[1]) > 2 || len(directive[2]) > 2 {
+				L.RaiseError("invalid format (width or precision too long)")
+			}
+			size += maxFormatWidth
+		}
+		for i := 2; i <= L.GetTop(); i++ {
+			size += len(L.Get(i).String())
+		}
+		checkStringLength(L, size, "string.format")
+		return format.GFunction(L)
+	}
+}
+
+// safeStringGsub wraps string.gsub, capping its result at maxStringLength
+// String replacements are bounded before replacing; table and function replacements are
+// wrapped to count the size of the values they return.
+func safeStringGsub(gsub *lua.LFunction) lua.LGFunction {
+	return func(L *lua.LState) int {
+		str := L.CheckString(1)
+		L.CheckString(2)
+		matches := L.OptInt(4, len(str)+1)
+		if matches < 0 || matches > len(str)+1 {
+			matches = len(str) + 1
+		}
+
+		switch repl := L.Get(3).(type) {
+		case lua.LString:
+			// Each replacement is at most the string with every %n capture expanded to str
+			replacement := len(repl) + strings.Count(string(repl), "%")*len(str)
+			checkStringLength(L, len(str)+matches*replacement, "string.gsub")
+		case *lua.LTable, *lua.LFunction:
+			size := len(str)
+			L.Replace(3, L.NewFunction(func(L *lua.LState) int {
+				args := make([]lua.LValue, L.GetTop())
+				for i := range args {
+					args[i] = L.Get(i + 1)
+				}
+				var value lua.LValue
+				if table, ok := repl.(*lua.LTable); ok {
+					value = L.GetTable(table, args[0])
+				} else {
+					L.Push(repl)
+					for _, arg := range args {
+						L.Push(arg)
+					}
+					L.Call(len(args), 1)
+					value = L.Get(-1)
+				}
+				if lua.LVCanConvToString(value) {
+					size += len(lua.LVAsString(value))
+					checkStringLength(L, size, "string.gsub")
+				}
+				L.Push(value)
+				return 1
+			}))
+		}
+		return gsub.GFunction(L)
+	}
+}
+
+// safeTableConcat wraps table.concat, capping its result at maxStringLength
+func safeTableConcat(concat *lua.LFunction) lua.LGFunction {
+	return func(L *lua.LState) int {
+		table := L.CheckTable(1)
+		sep := L.OptString(2, "")
+		first := max(L.OptInt(3, 1), 1)
+		last := min(L.OptInt(4, table.Len()), table.Len())
+		size := 0
+		for i := first; i <= last; i++ {
+			size += len(lua.LVAsString(table.RawGetInt(i))) + len(sep)
+			checkStringLength(L, size, "table.concat")
+		}
+		return concat.GFunction(L)
+	}
+}
+
+// concatMethod is the string library method scripts' .. operators are compiled to call, see
+// rewriteConcat
+// It isn't a valid identifier, so scripts can only reach it through the string library copy in
+// their own environment, never the original string methods resolve through.
+const concatMethod = "(..)"
+
+// safeConcat is the .. operator with its result capped at maxStringLength
+// It is called as a method on a constant string, so its operands start at the second argument.
+// Like the VM, it folds right to left: runs of strings and numbers are joined at once, other
+// values go through their __concat metamethod.
+func safeConcat(L *lua.LState) int {
+	operands := make([]lua.LValue, 0, L.GetTop()-1)
+	for i := 2; i <= L.GetTop(); i++ {
+		operands = append(operands, L.Get(i))
+	}
+
+	rhs := operands[len(operands)-1]
+	for i := len(operands) - 2; i >= 0; {
+		lhs := operands[i]
+		if !lua.LVCanConvToString(lhs) || !lua.LVCanConvToString(rhs) {
+			op := L.GetMetaField(lhs, "__concat")
+			if op == lua.LNil {
+				op = L.GetMetaField(rhs, "__concat")
+			}
+			if op.Type() != lua.LTFunction {
+				L.RaiseError("cannot perform concat operation between %v and %v", lhs.Type().String(), rhs.Type().String())
+			}
+			L.Push(op)
+			L.Push(lhs)
+			L.Push(rhs)
+			L.Call(2, 1)
+			rhs = L.Get(-1)
+			L.Pop(1)
+			i--
+			continue
+		}
+
+		size := len(lua.LVAsString(rhs))
+		first := i
+		for first >= 0 && lua.LVCanConvToString(operands[first]) {
+			size += len(lua.LVAsString(operands[first]))
+			first--
+		}
+		checkStringLength(L, size, "concatenation")
+		parts := make([]string, 0, i-first+1)
+		for _, operand := range operands[first+1 : i+1] {
+			parts = append(parts, lua.LVAsString(operand))
+		}
+		rhs = lua.LString(strings.Join(append(parts, lua.LVAsString(rhs)), ""))
+		i = first
+	}
+
+	L.Push(rhs)
+	return 1
+}
+
+// rewriteConcat replaces every .. operator in a parsed script with a call to safeConcat
+// Concatenation otherwise runs inside the VM, out of reach of the string length cap. A chain
+// a .. b .. c becomes a single call (""):[concatMethod](a, b, c), so it is joined once as the VM
+// would. The string metatable's __index is the original string library, which scripts can't
+// replace (see newScriptEnv), so the call always reaches safeConcat.
+func rewriteConcat(stmts []ast.Stmt) {
+	for _, stmt := range stmts {
+		rewriteConcatStmt(stmt)
+	}
+}
+
+func rewriteConcatStmt(stmt ast.Stmt) {
+	switch stmt := stmt.(type) {
+	case *ast.AssignStmt:
+		rewriteConcatExprs(stmt.Lhs)
+		rewriteConcatExprs(stmt.Rhs)
+	case *ast.LocalAssignStmt:
+		rewriteConcatExprs(stmt.Exprs)
+	case *ast.FuncCallStmt:
+		stmt.Expr = rewriteConcatExpr(stmt.Expr)
+	case *ast.DoBlockStmt:
+		rewriteConcat(stmt.Stmts)
+	case *ast.WhileStmt:
+		stmt.Condition = rewriteConcatExpr(stmt.Condition)
+		rewriteConcat(stmt.Stmts)
+	case *ast.RepeatStmt:
+		stmt.Condition = rewriteConcatExpr(stmt.Condition)
+		rewriteConcat(stmt.Stmts)
+	case *ast.IfStmt:
+		stmt.Condition = rewriteConcatExpr(stmt.Condition)
+		rewriteConcat(stmt.Then)
+		rewriteConcat(stmt.Else)
+	case *ast.NumberForStmt:
+		stmt.Init = rewriteConcatExpr(stmt.Init)
+		stmt.Limit = rewriteConcatExpr(stmt.Limit)
+		if stmt.Step != nil {
+			stmt.Step = rewriteConcatExpr(stmt.Step)
+		}
+		rewriteConcat(stmt.Stmts)
+	case *ast.GenericForStmt:
+		rewriteConcatExprs(stmt.Exprs)
+		rewriteConcat(stmt.Stmts)
+	case *ast.FuncDefStmt:
+		rewriteConcat(stmt.Func.Stmts)
+	case *ast.ReturnStmt:
+		rewriteConcatExprs(stmt.Exprs)
+	}
+}
+
+func rewriteConcatExprs(exprs []ast.Expr) {
+	for i, expr := range exprs {
+		exprs[i] = rewriteConcatExpr(expr)
+	}
+}
+
+func rewriteConcatExpr(expr ast.Expr) ast.Expr {
+	switch expr := expr.(type) {
+	case *ast.StringConcatOpExpr:
+		// .. is right associative, so a chain nests on the right
+		var operands []ast.Expr
+		var next ast.Expr = expr
+		for concat, ok := next.(*ast.StringConcatOpExpr); ok; concat, ok = next.(*ast.StringConcatOpExpr) {
+			operands = append(operands, concat.Lhs)
+			next = concat.Rhs
+		}
+		operands = append(operands, next)
+		rewriteConcatExprs(operands)
+		// An operand is a single value; a call or ... as the last argument would pass them all
+		switch last := operands[len(operands)-1].(type) {
+		case *ast.FuncCallExpr:
+			last.AdjustRet = true
+		case *ast.Comma3Expr:
+			last.AdjustRet = true
+		}
+
+		receiver := &ast.StringExpr{}
+		receiver.SetLine(expr.Line())
+		receiver.SetLastLine(expr.Line())
+		call := &ast.FuncCallExpr{Receiver: receiver, Method: concatMethod, Args: operands}
+		call.SetLine(expr.Line())
+		call.SetLastLine(expr.LastLine())
+		return call
+	case *ast.AttrGetExpr:
+		expr.Object = rewriteConcatExpr(expr.Object)
+		expr.Key = rewriteConcatExpr(expr.Key)
+	case *ast.TableExpr:
+		for _, field := range expr.Fields {
+			if field.Key != nil {
+				field.Key = rewriteConcatExpr(field.Key)
+			}
+			field.Value = rewriteConcatExpr(field.Value)
+		}
+	case *ast.FuncCallExpr:
+		if expr.Func != nil {
+			expr.Func = rewriteConcatExpr(expr.Func)
+		}
+		if expr.Receiver != nil {
+			expr.Receiver = rewriteConcatExpr(expr.Receiver)
+		}
+		rewriteConcatExprs(expr.Args)
+	case *ast.LogicalOpExpr:
+		expr.Lhs = rewriteConcatExpr(expr.Lhs)
+		expr.Rhs = rewriteConcatExpr(expr.Rhs)
+	case *ast.RelationalOpExpr:
+		expr.Lhs = rewriteConcatExpr(expr.Lhs)
+		expr.Rhs = rewriteConcatExpr(expr.Rhs)
+	case *ast.ArithmeticOpExpr:
+		expr.Lhs = rewriteConcatExpr(expr.Lhs)
+		expr.Rhs = rewriteConcatExpr(expr.Rhs)
+	case *ast.UnaryMinusOpExpr:
+		expr.Expr = rewriteConcatExpr(expr.Expr)
+	case *ast.UnaryNotOpExpr:
+		expr.Expr = rewriteConcatExpr(expr.Expr)
+	case *ast.UnaryLenOpExpr:
+		expr.Expr = rewriteConcatExpr(expr.Expr)
+	case *ast.FunctionExpr:
+		rewriteConcat(expr.Stmts)
+	}
+	return expr
+}
+
+// budgetContext counts VM instructions against a budget
+// gopher-lua has no instruction hook, but a VM with a context checks Done() before every
+// instruction, so counting those calls gives an exact instruction count.
+// A budgetContext must only be used by the single VM it was created for.
+type budgetContext struct {
+	context.Context
+	remaining int
+	exhausted chan struct{}
+}
+
+// withInstructionBudget returns a context that is done once the VM has run budget instructions
+func withInstructionBudget(ctx context.Context, budget int) *budgetContext {
+	return &budgetContext{
+		Context:   ctx,
+		remaining: budget,
+		exhausted: make(chan struct{}),
+	}
+}
+
+func (c *budgetContext) Done() <-chan struct{} {
+	if c.remaining <= 0 {
+		return c.exhausted
+	}
+	c.remaining--
+	if c.remaining == 0 {
+		close(c.exhausted)
+	}
+	return c.Context.Done()
+}
+
+// charge spends n instructions of the budget on work done outside the VM, e.g. by host functions
+// It reports false if the budget ran out
+func (c *budgetContext) charge(n int) bool {
+	if c.remaining <= 0 {
+		return false
+	}
+	c.remaining -= n
+	if c.remaining <= 0 {
+		c.remaining = 0
+		close(c.exhausted)
+		return false
+	}
+	return true
+}
+
+func (c *budgetContext) Err() error {
+	if c.remaining <= 0 {
+		return errInstructionBudget
+	}
+	return c.Context.Err()
+}
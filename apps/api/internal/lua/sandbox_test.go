@@ -0,0 +1,210 @@
+package lua
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// run loads source into v as a script named name and calls its run function
+// It returns the result and the context's error, which tells why a stopped script stopped.
+func run(t *testing.T, ctx context.Context, v *vm, name, source string) (interface{}, error, error) {
+	t.Helper()
+	script, err := compileScript(name, source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	budget := withInstructionBudget(ctx, maxInstructions)
+	v.L.SetContext(budget)
+	defer v.L.RemoveContext()
+	result, err := v.call(script, "run", nil)
+	return result, err, budget.Err()
+}
+
+func TestSandboxInstructionBudget(t *testing.T) {
+	v := newVM()
+	defer v.close()
+
+	_, err, ctxErr := run(t, context.Background(), v, "loop", `function run() while true do end end`)
+	if err == nil || !errors.Is(ctxErr, errInstructionBudget) {
+		t.Fatalf("err = %v, context err = %v, want the instruction budget exceeded", err, ctxErr)
+	}
+}
+
+func TestSandboxTimeout(t *testing.T) {
+	v := newVM()
+	defer v.close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	<-ctx.Done()
+
+	_, err, ctxErr := run(t, ctx, v, "loop", `function run() while true do end end`)
+	if err == nil || !errors.Is(ctxErr, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, context err = %v, want the deadline exceeded", err, ctxErr)
+	}
+}
+
+func TestSandboxStringLength(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+	}{
+		{"concat", `function run() local s = "x" while true do s = s .. s end end`},
+		{"concat in table", `function run() local t = {"x"} while true do t[1] = t[1] .. t[1] .. t[1] end end`},
+		{"concat upvalue", `local s = "x" function run() while true do s = s .. s end end`},
+		{"rep", `function run() return string.rep("x", 2 * 1024 * 1024) end`},
+		{"format width", `function run() return string.format("%999999d", 1) end`},
+		{"format", `function run() local s = string.rep("x", 1024 * 1024) return string.format("%s%s", s, s) end`},
+		{"gsub string", `function run() local s = string.rep("x", 2048) return s:gsub(".", s) end`},
+		{"gsub function", `function run() local s = string.rep("x", 2048) return s:gsub(".", function() return s end) end`},
+		{"gsub table", `function run() local s = string.rep("x", 2048) return s:gsub(".", { x = s }) end`},
+		{"table.concat", `function run()
+			local s, t = string.rep("x", 1024 * 1024), {}
+			for i = 1, 4 do t[i] = s end
+			return table.concat(t)
+		end`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newVM()
+			defer v.close()
+
+			_, err, _ := run(t, context.Background(), v, tt.name, tt.source)
+			if err == nil {
+				t.Fatal("expected the script to fail")
+			}
+			if !strings.Contains(err.Error(), "exceeds") && !strings.Contains(err.Error(), "too long") {
+				t.Fatalf("err = %v, want a string length error", err)
+			}
+		})
+	}
+}
+
+func TestSandboxStringsUnderLimit(t *testing.T) {
+	v := newVM()
+	defer v.close()
+
+	result, err, _ := run(t, context.Background(), v, "ok", `function run()
+		local s = string.rep("ab", 1000) .. "c"
+		return #s + #string.format("%5s|%-5d", "x", 1) + #("abc"):gsub("b", "%0%0") + #table.concat({"a", "b"}, ",")
+	end`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != float64(2001+11+4+3) {
+		t.Fatalf("result = %v", result)
+	}
+}
+
+func TestSandboxConcat(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   interface{}
+	}{
+		{"chain", `function run() local a = "b" return "a" .. a .. "c" .. 1 .. 2.5 end`, "abc12.5"},
+		{"parenthesized", `function run() return ("a" .. "b") .. ("c" .. "d") end`, "abcd"},
+		{"single value", `local function two() return "x", "y" end
+			function run() return "a" .. two() end`, "ax"},
+		{"varargs", `local function join(...) return "a" .. ... end
+			function run() return join("b", "c") end`, "ab"},
+		{"metamethod", `function run()
+			local mt = { __concat = function(a, b)
+				return (type(a) == "table" and a.v or a) .. (type(b) == "table" and b.v or b)
+			end }
+			local t = setmetatable({ v = "t" }, mt)
+			return "a" .. t .. "b" .. "c"
+		end`, "atbc"},
+		{"own copy replaced", `function run()
+			string["(..)"] = nil
+			_G.string = nil
+			return "a" .. "b"
+		end`, "ab"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newVM()
+			defer v.close()
+
+			result, err, _ := run(t, context.Background(), v, tt.name, tt.source)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result != tt.want {
+				t.Fatalf("result = %v, want %v", result, tt.want)
+			}
+		})
+	}
+
+	v := newVM()
+	defer v.close()
+	if _, err, _ := run(t, context.Background(), v, "nil", `function run() return "a" .. nil end`); err == nil ||
+		!strings.Contains(err.Error(), "cannot perform concat operation between string and nil") {
+		t.Fatalf("err = %v, want a concat error", err)
+	}
+}
+
+func TestSandboxScriptsDontShareLibraries(t *testing.T) {
+	v := newVM()
+	defer v.close()
+
+	// The first script replaces library functions every way it can reach them
+	if _, err, _ := run(t, context.Background(), v, "evil", `function run()
+		string.match = function() return "evil" end
+		rawset(table, "insert", nil)
+		_G.math.floor = nil
+		ekko.log = nil
+		ekko.url.parse = nil
+		tostring = nil
+		return getmetatable("") == false and getfenv == nil and setfenv == nil
+	end`); err != nil {
+		t.Fatal(err)
+	}
+
+	// A script loaded later in the same VM still has the originals
+	result, err, _ := run(t, context.Background(), v, "victim", `function run()
+		local t = {}
+		table.insert(t, string.match("abc", "b"))
+		table.insert(t, ("abc"):match("c"))
+		table.insert(t, tostring(math.floor(1.5)))
+		table.insert(t, type(ekko.log))
+		table.insert(t, type(ekko.url.parse))
+		return t
+	end`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []interface{}{"b", "c", "1", "function", "function"}
+	got, _ := result.([]interface{})
+	if len(got) != len(want) {
+		t.Fatalf("result = %v, want %v", result, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("result = %v, want %v", result, want)
+		}
+	}
+}
+
+func TestSandboxUnsafeGlobals(t *testing.T) {
+	v := newVM()
+	defer v.close()
+
+	result, err, _ := run(t, context.Background(), v, "globals", `function run()
+		local found = {}
+		for _, name in ipairs({"io", "os", "debug", "package", "require", "load", "loadstring",
+			"dofile", "loadfile", "getfenv", "setfenv"}) do
+			if _G[name] ~= nil then table.insert(found, name) end
+		end
+		return found
+	end`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found, ok := result.(map[string]interface{}); !ok || len(found) != 0 {
+		t.Fatalf("unsafe globals available: %v", result)
+	}
+}
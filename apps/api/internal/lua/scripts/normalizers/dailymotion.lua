@@ -0,0 +1,51 @@
+-- Dailymotion URL Normalization Script
+-- Supports dailymotion.com/video/ID, dailymotion.com/embed/video/ID and dai.ly/ID
+-- Normalizes them to: https://www.dailymotion.com/video/VIDEO_ID
+
+platform = "dailymotion"
+hosts = { "dailymotion.com", "dai.ly" }
+
+-- Checked when the script is loaded; a reload that breaks an example is rejected
+examples = {
+    { url = "https://www.dailymotion.com/video/x7tgad0", normalizedUrl = "https://www.dailymotion.com/video/x7tgad0" },
+    { url = "https://dai.ly/x7tgad0", normalizedUrl = "https://www.dailymotion.com/video/x7tgad0" },
+    { url = "https://www.dailymotion.com/somechannel", isValid = false },
+}
+
+local function invalid(message)
+    return {
+        isValid = false,
+        platform = platform,
+        videoId = nil,
+        normalizedUrl = nil,
+        error = message
+    }
+end
+
+function normalize_url(url)
+    if not url or type(url) ~= "string" or url == "" then
+        return invalid("Invalid URL: URL must be a non-empty string")
+    end
+
+    local trimmed_url = url:match("^%s*(.-)%s*$")
+    local rest = trimmed_url:gsub("^%a[%w+.-]*://", "")
+    local host = (rest:match("^([^/?#]*)") or ""):lower()
+    local path = rest:match("^[^/?#]*(/[^?#]*)") or "/"
+
+    -- Video IDs are alphanumeric; older URLs append "_title-slug" after the ID
+    local video_id = path:match("^/video/(%w+)") or path:match("^/embed/video/(%w+)")
+    if not video_id and host:match("dai%.ly$") then
+        video_id = path:match("^/(%w+)")
+    end
+
+    if not video_id then
+        return invalid("Invalid URL: Could not extract video ID from Dailymotion URL")
+    end
+
+    return {
+        isValid = true,
+        platform = platform,
+        videoId = video_id,
+        normalizedUrl = "https://www.dailymotion.com/video/" .. video_id
+    }
+end
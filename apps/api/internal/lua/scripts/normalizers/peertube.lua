@@ -0,0 +1,66 @@
+-- PeerTube URL Normalization Script
+-- PeerTube is federated, so there is no fixed host list: this script handles the instances
+-- configured with PEERTUBE_INSTANCES. It recognizes the PeerTube watch and embed paths:
+-- /w/SHORT_ID, /videos/watch/UUID and /videos/embed/UUID
+-- Normalizes them to: https://HOST/w/VIDEO_ID, without userinfo or a default port
+
+platform = "peertube"
+hosts = {}
+federated = true
+
+-- Checked when the script is loaded; a reload that breaks an example is rejected
+examples = {
+    { url = "https://framatube.org/videos/watch/9c9de5e8-0a1e-484a-b099-e80766180a6d", normalizedUrl = "https://framatube.org/w/9c9de5e8-0a1e-484a-b099-e80766180a6d" },
+    { url = "https://user@FramaTube.org:443/w/kkGMgK9ZtnKfYAgnEtQxbv", normalizedUrl = "https://framatube.org/w/kkGMgK9ZtnKfYAgnEtQxbv" },
+    { url = "https://framatube.org/w/p/abc123", isValid = false },
+}
+
+-- Ports a URL can leave out, by scheme
+local default_ports = { http = "80", https = "443" }
+
+local function invalid(message)
+    return {
+        isValid = false,
+        platform = platform,
+        videoId = nil,
+        normalizedUrl = nil,
+        error = message
+    }
+end
+
+function normalize_url(url)
+    if not url or type(url) ~= "string" or url == "" then
+        return invalid("Invalid URL: URL must be a non-empty string")
+    end
+
+    local parsed = ekko.url.parse(url)
+    if not parsed then
+        return invalid("Invalid URL: Could not parse URL")
+    end
+    if parsed.host == "" then
+        return invalid("Invalid URL: Missing host")
+    end
+
+    -- The same video must normalize to one URL however its host was written
+    local host = parsed.host
+    if parsed.port ~= "" and parsed.port ~= default_ports[parsed.scheme] then
+        host = host .. ":" .. parsed.port
+    end
+
+    -- /w/p/ID is a playlist, so the short ID must be the whole segment
+    local path = parsed.path
+    local video_id = path:match("^/w/([%w-]+)/?$")
+        or path:match("^/videos/watch/([%x-]+)/?$")
+        or path:match("^/videos/embed/([%x-]+)/?$")
+
+    if not video_id then
+        return invalid("Invalid URL: Not a PeerTube video URL")
+    end
+
+    return {
+        isValid = true,
+        platform = platform,
+        videoId = video_id,
+        normalizedUrl = "https://" .. host .. "/w/" .. video_id
+    }
+end
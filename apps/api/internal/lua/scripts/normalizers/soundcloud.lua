@@ -0,0 +1,69 @@
+-- SoundCloud URL Normalization Script
+-- Supports track URLs: soundcloud.com/ARTIST/TRACK (including m.soundcloud.com)
+-- Normalizes them to: https://soundcloud.com/ARTIST/TRACK
+
+platform = "soundcloud"
+hosts = { "soundcloud.com" }
+
+-- Checked when the script is loaded; a reload that breaks an example is rejected
+examples = {
+    { url = "https://soundcloud.com/artist/track-name?in=x", normalizedUrl = "https://soundcloud.com/artist/track-name" },
+    { url = "https://soundcloud.com/artist/sets/album", isValid = false },
+}
+
+-- First path segments that are site pages rather than artists
+local reserved_artists = {
+    charts = true, discover = true, feed = true, messages = true, notifications = true,
+    pages = true, people = true, search = true, settings = true, stream = true,
+    upload = true, you = true
+}
+
+-- Second path segments that are artist pages rather than tracks
+local reserved_tracks = {
+    albums = true, comments = true, followers = true, following = true, likes = true,
+    ["popular-tracks"] = true, reposts = true, sets = true, tracks = true
+}
+
+local function invalid(message)
+    return {
+        isValid = false,
+        platform = platform,
+        videoId = nil,
+        normalizedUrl = nil,
+        error = message
+    }
+end
+
+function normalize_url(url)
+    if not url or type(url) ~= "string" or url == "" then
+        return invalid("Invalid URL: URL must be a non-empty string")
+    end
+
+    local trimmed_url = url:match("^%s*(.-)%s*$")
+    local rest = trimmed_url:gsub("^%a[%w+.-]*://", "")
+    local host = (rest:match("^([^/?#]*)") or ""):lower()
+    local path = rest:match("^[^/?#]*(/[^?#]*)") or "/"
+
+    if host:match("^on%.") then
+        return invalid("Invalid URL: SoundCloud short links are not supported, use the track URL")
+    end
+
+    local artist, track = path:match("^/([%w_-]+)/([%w_-]+)/?$")
+    if not artist then
+        return invalid("Invalid URL: Could not extract track from SoundCloud URL")
+    end
+
+    artist = artist:lower()
+    track = track:lower()
+    if reserved_artists[artist] or reserved_tracks[track] then
+        return invalid("Invalid URL: Not a SoundCloud track URL")
+    end
+
+    local track_id = artist .. "/" .. track
+    return {
+        isValid = true,
+        platform = platform,
+        videoId = track_id,
+        normalizedUrl = "https://soundcloud.com/" .. track_id
+    }
+end
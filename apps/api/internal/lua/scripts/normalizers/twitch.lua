@@ -0,0 +1,68 @@
+-- Twitch URL Normalization Script
+-- Supports VODs (twitch.tv/videos/ID) and clips (clips.twitch.tv/SLUG, twitch.tv/CHANNEL/clip/SLUG)
+-- Normalizes them to: https://www.twitch.tv/videos/VIDEO_ID or https://clips.twitch.tv/CLIP_SLUG
+
+platform = "twitch"
+hosts = { "twitch.tv" }
+
+-- Checked when the script is loaded; a reload that breaks an example is rejected
+examples = {
+    { url = "https://www.twitch.tv/videos/1234567890", normalizedUrl = "https://www.twitch.tv/videos/1234567890" },
+    { url = "https://www.twitch.tv/somechannel/clip/FunnyClipSlug", normalizedUrl = "https://clips.twitch.tv/FunnyClipSlug" },
+    { url = "https://www.twitch.tv/somechannel", isValid = false },
+}
+
+local function invalid(message)
+    return {
+        isValid = false,
+        platform = platform,
+        videoId = nil,
+        normalizedUrl = nil,
+        error = message
+    }
+end
+
+function normalize_url(url)
+    if not url or type(url) ~= "string" or url == "" then
+        return invalid("Invalid URL: URL must be a non-empty string")
+    end
+
+    local trimmed_url = url:match("^%s*(.-)%s*$")
+    local rest = trimmed_url:gsub("^%a[%w+.-]*://", "")
+    local host = (rest:match("^([^/?#]*)") or ""):lower()
+    local path = rest:match("^[^/?#]*(/[^?#]*)") or "/"
+
+    -- Pattern 1: twitch.tv/videos/VIDEO_ID
+    local vod_match = path:match("^/videos/(%d+)")
+    if vod_match then
+        return {
+            isValid = true,
+            platform = platform,
+            videoId = vod_match,
+            normalizedUrl = "https://www.twitch.tv/videos/" .. vod_match
+        }
+    end
+
+    -- Pattern 2: twitch.tv/CHANNEL/clip/CLIP_SLUG
+    local clip_match = path:match("^/[^/]+/clip/([%w_-]+)")
+
+    -- Pattern 3: clips.twitch.tv/CLIP_SLUG or clips.twitch.tv/embed?clip=CLIP_SLUG
+    if not clip_match and host:match("^clips%.") then
+        if path:match("^/embed") then
+            clip_match = trimmed_url:match("[?&]clip=([%w_-]+)")
+        else
+            clip_match = path:match("^/([%w_-]+)/?$")
+        end
+    end
+
+    if clip_match then
+        return {
+            isValid = true,
+            platform = platform,
+            videoId = clip_match,
+            normalizedUrl = "https://clips.twitch.tv/" .. clip_match
+        }
+    end
+
+    return invalid("Invalid URL: Could not extract a VOD or clip from Twitch URL")
+end
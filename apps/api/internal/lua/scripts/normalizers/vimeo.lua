@@ -0,0 +1,80 @@
+-- Vimeo URL Normalization Script
+-- Supports vimeo.com/ID, vimeo.com/channels/NAME/ID, vimeo.com/groups/NAME/videos/ID,
+-- vimeo.com/showcase/ID/video/ID and player.vimeo.com/video/ID
+-- Normalizes them to: https://vimeo.com/VIDEO_ID
+
+platform = "vimeo"
+hosts = { "vimeo.com" }
+
+-- Checked when the script is loaded; a reload that breaks an example is rejected
+examples = {
+    { url = "https://vimeo.com/76979871", normalizedUrl = "https://vimeo.com/76979871" },
+    { url = "https://player.vimeo.com/video/76979871", normalizedUrl = "https://vimeo.com/76979871" },
+    { url = "https://vimeo.com/channels/staffpicks", isValid = false },
+}
+
+local function invalid(message)
+    return {
+        isValid = false,
+        platform = platform,
+        videoId = nil,
+        normalizedUrl = nil,
+        error = message
+    }
+end
+
+local function extract_video_id(path)
+    -- Pattern 1: vimeo.com/VIDEO_ID (optionally followed by a private link hash)
+    local direct_match = path:match("^/(%d+)/?[%w]*$")
+    if direct_match then
+        return direct_match
+    end
+
+    -- Pattern 2: player.vimeo.com/video/VIDEO_ID
+    local player_match = path:match("^/video/(%d+)")
+    if player_match then
+        return player_match
+    end
+
+    -- Pattern 3: vimeo.com/channels/NAME/VIDEO_ID
+    local channel_match = path:match("^/channels/[^/]+/(%d+)")
+    if channel_match then
+        return channel_match
+    end
+
+    -- Pattern 4: vimeo.com/groups/NAME/videos/VIDEO_ID
+    local group_match = path:match("^/groups/[^/]+/videos/(%d+)")
+    if group_match then
+        return group_match
+    end
+
+    -- Pattern 5: vimeo.com/showcase/ID/video/VIDEO_ID
+    local showcase_match = path:match("^/showcase/%d+/video/(%d+)")
+    if showcase_match then
+        return showcase_match
+    end
+
+    return nil
+end
+
+function normalize_url(url)
+    if not url or type(url) ~= "string" or url == "" then
+        return invalid("Invalid URL: URL must be a non-empty string")
+    end
+
+    local trimmed_url = url:match("^%s*(.-)%s*$")
+    local rest = trimmed_url:gsub("^%a[%w+.-]*://", "")
+    local path = rest:match("^[^/?#]*(/[^?#]*)") or "/"
+
+    local video_id = extract_video_id(path)
+    if not video_id then
+        return invalid("Invalid URL: Could not extract video ID from Vimeo URL")
+    end
+
+    return {
+        isValid = true,
+        platform = platform,
+        videoId = video_id,
+        normalizedUrl = "https://vimeo.com/" .. video_id
+    }
+end
@@ -0,0 +1,172 @@
+-- YouTube URL Normalization Script
+-- Supports watch?v=, youtu.be/, /shorts/, /live/, /embed/, /v/ and /e/ URLs on www., m. and
+-- music.youtube.com, youtube-nocookie.com embeds and attribution_link redirects
+-- Normalizes them to: https://www.youtube.com/watch?v=VIDEO_ID and reports the URL's kind
+-- (video, short or live) separately, so every form of a video dedupes to the same URL
+-- Start timestamps (t=, start=, time_continue=) are returned separately as startSeconds
+
+platform = "youtube"
+hosts = { "youtube.com", "youtu.be", "youtube-nocookie.com" }
+
+-- Checked when the script is loaded; a reload that breaks an example is rejected
+-- The full fixture suite is in internal/lua/testdata/normalizers/youtube.json
+examples = {
+    { url = "https://www.youtube.com/watch?v=dQw4w9WgXcQ&t=42", normalizedUrl = "https://www.youtube.com/watch?v=dQw4w9WgXcQ", startSeconds = 42 },
+    { url = "https://youtu.be/dQw4w9WgXcQ?t=1h2m3s", normalizedUrl = "https://www.youtube.com/watch?v=dQw4w9WgXcQ", startSeconds = 3723 },
+    { url = "https://www.youtube.com/shorts/dQw4w9WgXcQ", normalizedUrl = "https://www.youtube.com/watch?v=dQw4w9WgXcQ" },
+    { url = "https://www.youtube.com/playlist?list=PL0", isValid = false },
+    { url = "https://www.youtube.com/RickAstleyV", isValid = false },
+}
+
+-- Path prefixes followed by a video ID, and the kind of video they link to
+local path_kinds = {
+    { prefix = "shorts", kind = "short" },
+    { prefix = "live", kind = "live" },
+    { prefix = "embed", kind = "video" },
+    { prefix = "v", kind = "video" },
+    { prefix = "e", kind = "video" },
+    { prefix = "watch", kind = "video" },
+}
+
+-- attribution_link redirects are followed at most this many times
+local max_redirects = 2
+
+local function invalid(message)
+    return {
+        isValid = false,
+        platform = platform,
+        videoId = nil,
+        normalizedUrl = nil,
+        error = message
+    }
+end
+
+-- Path segments in video ID position that are pages rather than videos
+-- (/embed/videoseries?list= embeds a playlist)
+local reserved_ids = { videoseries = true }
+
+-- YouTube video IDs are exactly 11 characters of [A-Za-z0-9_-]
+local function valid_video_id(id)
+    return id ~= nil and #id == 11 and id:match("^[%w_-]+$") ~= nil and not reserved_ids[id]
+end
+
+-- Reports whether host is domain or one of its subdomains, the same rule the hosts list is dispatched by
+local function on_domain(host, domain)
+    return host == domain or host:sub(-(#domain + 1)) == "." .. domain
+end
+
+local function is_youtube_host(host)
+    return on_domain(host, "youtube.com") or on_domain(host, "youtube-nocookie.com")
+end
+
+-- Parses a timestamp given as plain seconds ("90", "90s") or as hours, minutes and seconds ("1h2m3s", "2m")
+local function parse_timestamp(value)
+    if value:match("^%d+$") then
+        return tonumber(value)
+    end
+
+    local units = { h = 3600, m = 60, s = 1 }
+    local seconds = 0
+    local rest = value
+    while rest ~= "" do
+        local amount, unit, remainder = rest:match("^(%d+)([hms])(.*)$")
+        if not amount then
+            return nil
+        end
+        seconds = seconds + tonumber(amount) * units[unit]
+        rest = remainder
+    end
+    return seconds
+end
+
+-- Reads the start timestamp from the query string, or from a #t= fragment; nil if there is none
+local function extract_start_seconds(parsed)
+    local fragment = "&" .. parsed.fragment
+    for _, name in ipairs({ "t", "start", "time_continue" }) do
+        local value = parsed.query[name] or fragment:match("&" .. name .. "=([^&]*)")
+        if value then
+            local seconds = parse_timestamp(value:lower())
+            if seconds and seconds > 0 then
+                return seconds
+            end
+        end
+    end
+    return nil
+end
+
+-- Returns the video ID and kind a parsed URL links to, or nil
+local function extract_video(parsed)
+    if on_domain(parsed.host, "youtu.be") then
+        local id = parsed.path:match("^/([^/]+)/?$")
+        if valid_video_id(id) then
+            return id, "video"
+        end
+        return nil
+    end
+
+    if parsed.path == "/watch" or parsed.path == "/watch/" then
+        local id = parsed.query.v or parsed.query.vi
+        if valid_video_id(id) then
+            return id, "video"
+        end
+        return nil
+    end
+
+    local prefix, id = parsed.path:match("^/([%a_]+)/([^/]+)/?$")
+    if not prefix then
+        return nil
+    end
+    for _, entry in ipairs(path_kinds) do
+        if entry.prefix == prefix then
+            if valid_video_id(id) then
+                return id, entry.kind
+            end
+            return nil
+        end
+    end
+    return nil
+end
+
+local function normalize_youtube_url(url, redirects)
+    -- Validate input
+    if not url or type(url) ~= "string" or url == "" then
+        return invalid("Invalid URL: URL must be a non-empty string")
+    end
+
+    local parsed = ekko.url.parse(url)
+    if not parsed then
+        return invalid("Invalid URL: Could not parse URL")
+    end
+
+    if not on_domain(parsed.host, "youtu.be") and not is_youtube_host(parsed.host) then
+        return invalid("Invalid URL: Not a YouTube URL")
+    end
+
+    -- attribution_link?u=/watch%3Fv%3DID carries the real URL, relative to youtube.com
+    if parsed.path == "/attribution_link" then
+        local target = parsed.query.u
+        if not target or target:sub(1, 1) ~= "/" or redirects >= max_redirects then
+            return invalid("Invalid URL: Could not extract video ID from YouTube attribution link")
+        end
+        return normalize_youtube_url("https://www.youtube.com" .. target, redirects + 1)
+    end
+
+    local video_id, kind = extract_video(parsed)
+    if not video_id then
+        return invalid("Invalid URL: Could not extract video ID from YouTube URL")
+    end
+
+    return {
+        isValid = true,
+        platform = platform,
+        videoId = video_id,
+        kind = kind,
+        normalizedUrl = "https://www.youtube.com/watch?v=" .. video_id,
+        startSeconds = extract_start_seconds(parsed)
+    }
+end
+
+-- Main function called by Go
+function normalize_url(url)
+    return normalize_youtube_url(url, 0)
+end
@@ -3,256 +3,624 @@ package lua
 import (
 	"context"
 	"embed"
+	"encoding/json"
 	"fmt"
+	"io/fs"
+	"net/url"
+	"path"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
 	"sync"
 
 	lua "github.com/yuin/gopher-lua"
 )
 
-//go:embed scripts/*.lua
+//go:embed scripts
 var scriptsFS embed.FS
 
+// normalizersDir holds one URL normalizer script per platform
+const normalizersDir = "normalizers/"
+
+// hooksDir holds ingestion hook scripts, each defining on_ingest(video)
+const hooksDir = "hooks/"
+
+// HookFunc is the function every ingestion hook script defines
+const HookFunc = "on_ingest"
+
+// normalizer describes a platform's URL normalization script
+// Each script declares the platform it handles and the hosts it accepts
+// Federated scripts have no fixed hosts and handle the instances configured with SetInstances
+type normalizer struct {
+	script    string
+	platform  string
+	hosts     []string
+	federated bool
+}
+
+// Script layers, applied over the embedded scripts in this order
+const (
+	layerDirectory = iota
+	layerDatabase
+	layerCount
+)
+
+// Script origins, as reported by Scripts
+const (
+	OriginEmbedded  = "embedded"
+	OriginDirectory = "directory"
+	OriginDatabase  = "database"
+)
+
+var layerOrigins = [layerCount]string{OriginDirectory, OriginDatabase}
+
+// scriptNamePattern matches script names: slash-separated lowercase path segments ending in .lua
+var scriptNamePattern = regexp.MustCompile(`^[a-z0-9_-]+(/[a-z0-9_-]+)*\.lua$`)
+
+// ValidScriptName reports whether name can be used as a script name, e.g. "normalizers/youtube.lua"
+func ValidScriptName(name string) bool {
+	return scriptNamePattern.MatchString(name)
+}
+
+// ScriptInfo describes a loaded script
+type ScriptInfo struct {
+	Name   string `json:"name"`
+	Origin string `json:"origin"`
+}
+
 // Service manages Lua VM instances and script execution
 type Service struct {
-	vmPool    sync.Pool
-	scripts   map[string]string
-	scriptsMu sync.RWMutex
+	vmPool      sync.Pool
+	scripts     map[string]*compiledScript
+	sources     map[string]string
+	origins     map[string]string
+	normalizers []normalizer
+	hooks       []string
+	// instances are the configured hosts of federated platforms, by platform
+	instances map[string][]string
+	// generation changes whenever the scripts are replaced, retiring VMs loaded with older scripts
+	generation uint64
+	scriptsMu  sync.RWMutex
+
+	// layers holds the scripts overriding the embedded ones; layersMu serializes reloads
+	layers   [layerCount]map[string]string
+	layersMu sync.Mutex
+
+	// stopWatch stops the LUA_SCRIPTS_DIR watcher, if one is running
+	stopWatch context.CancelFunc
 }
 
 // NewService creates a new Lua service with embedded scripts
 func NewService() (*Service, error) {
+	s := newService()
+
+	// Load embedded scripts
+	if err := s.loadScripts(s.layers); err != nil {
+		return nil, fmt.Errorf("failed to load scripts: %w", err)
+	}
+
+	return s, nil
+}
+
+func newService() *Service {
 	s := &Service{
-		scripts: make(map[string]string),
+		scripts: make(map[string]*compiledScript),
 	}
 
-	// Initialize VM pool
+	// Initialize VM pool with sandboxed VMs that have every script preloaded
 	s.vmPool = sync.Pool{
 		New: func() interface{} {
-			return lua.NewState()
+			return s.newWarmVM()
 		},
 	}
 
-	// Load embedded scripts
-	if err := s.loadScripts(); err != nil {
-		return nil, fmt.Errorf("failed to load scripts: %w", err)
+	return s
+}
+
+// SetDatabaseScripts replaces the scripts managed through the database
+// They override embedded scripts and LUA_SCRIPTS_DIR scripts of the same name.
+// On error the currently loaded scripts are left untouched.
+func (s *Service) SetDatabaseScripts(sources map[string]string) error {
+	return s.setLayer(layerDatabase, sources)
+}
+
+// WithScript returns a standalone copy of the service with one script replaced by source
+// The copy is compiled and self-tested like a reload, so it can be used to try out a
+// script before activating it. It shares no state with s.
+func (s *Service) WithScript(scriptName, source string) (*Service, error) {
+	s.layersMu.Lock()
+	layers := s.layers
+	s.layersMu.Unlock()
+
+	candidate := make(map[string]string, len(layers[layerDatabase])+1)
+	for name, content := range layers[layerDatabase] {
+		candidate[name] = content
 	}
+	candidate[scriptName] = source
+	layers[layerDatabase] = candidate
 
-	return s, nil
+	preview := newService()
+	if err := preview.loadScripts(layers); err != nil {
+		return nil, err
+	}
+	return preview, nil
 }
 
-// loadScripts loads all Lua scripts from the embedded filesystem
-func (s *Service) loadScripts() error {
-	entries, err := scriptsFS.ReadDir("scripts")
+// Scripts lists the loaded scripts and where each one came from
+func (s *Service) Scripts() []ScriptInfo {
+	s.scriptsMu.RLock()
+	defer s.scriptsMu.RUnlock()
+
+	scripts := make([]ScriptInfo, 0, len(s.origins))
+	for name, origin := range s.origins {
+		scripts = append(scripts, ScriptInfo{Name: name, Origin: origin})
+	}
+	sort.Slice(scripts, func(i, j int) bool {
+		return scripts[i].Name < scripts[j].Name
+	})
+	return scripts
+}
+
+// Hooks lists the loaded ingestion hook scripts in the order they run
+func (s *Service) Hooks() []string {
+	s.scriptsMu.RLock()
+	defer s.scriptsMu.RUnlock()
+
+	return append([]string(nil), s.hooks...)
+}
+
+// Source returns the source of a loaded script
+func (s *Service) Source(scriptName string) (string, bool) {
+	s.scriptsMu.RLock()
+	defer s.scriptsMu.RUnlock()
+
+	source, ok := s.sources[scriptName]
+	return source, ok
+}
+
+// setLayer replaces one layer of script overrides and reloads
+func (s *Service) setLayer(layer int, sources map[string]string) error {
+	s.layersMu.Lock()
+	defer s.layersMu.Unlock()
+
+	layers := s.layers
+	layers[layer] = sources
+	return s.loadScripts(layers)
+}
+
+// loadScripts compiles and self-tests the embedded scripts overlaid with each layer in turn,
+// then swaps them in. A layer's scripts replace scripts of the same name in earlier layers.
+// On error the currently loaded scripts are left untouched.
+// Callers other than constructors must hold layersMu.
+func (s *Service) loadScripts(layers [layerCount]map[string]string) error {
+	sources, err := embeddedSources()
 	if err != nil {
-		return fmt.Errorf("failed to read scripts directory: %w", err)
+		return err
+	}
+	origins := make(map[string]string, len(sources))
+	for scriptName := range sources {
+		origins[scriptName] = OriginEmbedded
+	}
+	for layer, overlay := range layers {
+		for scriptName, scriptContent := range overlay {
+			sources[scriptName] = scriptContent
+			origins[scriptName] = layerOrigins[layer]
+		}
 	}
 
-	for _, entry := range entries {
-		if entry.IsDir() {
-			continue
+	scripts := make(map[string]*compiledScript, len(sources))
+	for scriptName, scriptContent := range sources {
+		script, err := compileScript(scriptName, scriptContent)
+		if err != nil {
+			return err
 		}
+		scripts[scriptName] = script
+	}
+
+	normalizers, err := loadNormalizers(scripts)
+	if err != nil {
+		return err
+	}
+
+	hooks, err := loadHooks(scripts)
+	if err != nil {
+		return err
+	}
 
-		scriptName := entry.Name()
-		scriptPath := "scripts/" + scriptName
+	s.scriptsMu.Lock()
+	s.scripts = scripts
+	s.sources = sources
+	s.origins = origins
+	s.normalizers = normalizers
+	s.hooks = hooks
+	s.generation++
+	s.scriptsMu.Unlock()
+
+	s.layers = layers
+	return nil
+}
+
+// embeddedSources reads all Lua scripts from the embedded filesystem
+// Scripts are keyed by their path relative to scripts/, e.g. "normalizers/youtube.lua"
+func embeddedSources() (map[string]string, error) {
+	root, err := fs.Sub(scriptsFS, "scripts")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scripts directory: %w", err)
+	}
+	return readSources(root)
+}
+
+// readSources reads all .lua files in fsys, keyed by their slash-separated path
+func readSources(fsys fs.FS) (map[string]string, error) {
+	sources := make(map[string]string)
+	err := fs.WalkDir(fsys, ".", func(scriptName string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || path.Ext(scriptName) != ".lua" {
+			return nil
+		}
 
-		scriptContent, err := scriptsFS.ReadFile(scriptPath)
+		scriptContent, err := fs.ReadFile(fsys, scriptName)
 		if err != nil {
 			return fmt.Errorf("failed to read script %s: %w", scriptName, err)
 		}
 
-		s.scriptsMu.Lock()
-		s.scripts[scriptName] = string(scriptContent)
-		s.scriptsMu.Unlock()
+		sources[scriptName] = string(scriptContent)
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
-
-	return nil
+	return sources, nil
 }
 
-// ExecuteScript executes a Lua script by name with the given arguments
-// Returns the result as a map[string]interface{} or an error
-func (s *Service) ExecuteScript(ctx context.Context, scriptName string, args map[string]interface{}) (map[string]interface{}, error) {
-	// Get script content
+// newWarmVM creates a VM with the current scripts preloaded
+func (s *Service) newWarmVM() *vm {
 	s.scriptsMu.RLock()
-	scriptContent, exists := s.scripts[scriptName]
+	generation := s.generation
+	scripts := make([]*compiledScript, 0, len(s.scripts))
+	for _, script := range s.scripts {
+		scripts = append(scripts, script)
+	}
 	s.scriptsMu.RUnlock()
 
-	if !exists {
-		return nil, fmt.Errorf("script not found: %s", scriptName)
+	v := newVM()
+	v.generation = generation
+	v.warm(scripts)
+	return v
+}
+
+// loadNormalizers builds the platform registry from the scripts in normalizers/
+// Each script is run once in a fresh state to read its platform, hosts and federated globals
+// and to check its examples
+func loadNormalizers(scripts map[string]*compiledScript) ([]normalizer, error) {
+	names := make([]string, 0, len(scripts))
+	for scriptName := range scripts {
+		if strings.HasPrefix(scriptName, normalizersDir) {
+			names = append(names, scriptName)
+		}
 	}
+	// Keep dispatch order stable across runs
+	sort.Strings(names)
 
-	// Get VM from pool
-	L := s.vmPool.Get().(*lua.LState)
-	defer s.vmPool.Put(L)
+	normalizers := make([]normalizer, 0, len(names))
+	for _, scriptName := range names {
+		n, err := readNormalizer(scripts[scriptName])
+		if err != nil {
+			return nil, err
+		}
+		normalizers = append(normalizers, n)
+	}
 
-	// Set up context cancellation (basic implementation)
-	// Note: gopher-lua doesn't have built-in context support, so this is a best-effort approach
-	done := make(chan error, 1)
-	var result map[string]interface{}
+	return normalizers, nil
+}
 
-	go func() {
-		defer func() {
-			if r := recover(); r != nil {
-				done <- fmt.Errorf("lua panic: %v", r)
+// readNormalizer reads a normalizer script's registration globals
+func readNormalizer(script *compiledScript) (normalizer, error) {
+	scriptName := script.name
+
+	v := newVM()
+	defer v.close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), maxExecutionTime)
+	defer cancel()
+	v.L.SetContext(withInstructionBudget(ctx, maxInstructions))
+
+	env, err := v.env(script)
+	if err != nil {
+		return normalizer{}, fmt.Errorf("failed to load normalizer %s: %w", scriptName, err)
+	}
+
+	n := normalizer{script: scriptName}
+
+	platform, ok := env.RawGetString("platform").(lua.LString)
+	if !ok || platform == "" {
+		return normalizer{}, fmt.Errorf("normalizer %s must set a platform name", scriptName)
+	}
+	n.platform = string(platform)
+
+	if env.RawGetString("normalize_url").Type() != lua.LTFunction {
+		return normalizer{}, fmt.Errorf("normalizer %s must define normalize_url", scriptName)
+	}
+
+	if hosts, ok := env.RawGetString("hosts").(*lua.LTable); ok {
+		hosts.ForEach(func(_ lua.LValue, value lua.LValue) {
+			if host, ok := value.(lua.LString); ok {
+				n.hosts = append(n.hosts, strings.ToLower(string(host)))
 			}
-		}()
+		})
+	}
 
-		// Load script
-		if err := L.DoString(scriptContent); err != nil {
-			done <- fmt.Errorf("failed to execute script: %w", err)
-			return
-		}
+	n.federated = lua.LVAsBool(env.RawGetString("federated"))
+	if len(n.hosts) == 0 && !n.federated {
+		return normalizer{}, fmt.Errorf("normalizer %s must list hosts or be federated", scriptName)
+	}
 
-		// Prepare arguments
-		argsTable := L.NewTable()
-		for k, v := range args {
-			argsTable.RawSetString(k, s.goToLuaValue(L, v))
-		}
+	if err := checkExamples(v, script, env.RawGetString("examples")); err != nil {
+		return normalizer{}, fmt.Errorf("normalizer %s failed its self-test: %w", scriptName, err)
+	}
 
-		// Call the main function (assuming scriptName without .lua extension)
-		funcName := scriptName[:len(scriptName)-4] // Remove .lua extension
-		if len(scriptName) < 5 || scriptName[len(scriptName)-4:] != ".lua" {
-			funcName = scriptName
-		}
+	return n, nil
+}
 
-		// For url_normalize.lua, the function is normalize_url
-		if scriptName == "url_normalize.lua" {
-			funcName = "normalize_url"
+// loadHooks lists the scripts in hooks/ in name order, checking that each defines on_ingest
+func loadHooks(scripts map[string]*compiledScript) ([]string, error) {
+	hooks := make([]string, 0)
+	for scriptName := range scripts {
+		if strings.HasPrefix(scriptName, hooksDir) {
+			hooks = append(hooks, scriptName)
 		}
+	}
+	sort.Strings(hooks)
 
-		// Get the function from global scope
-		fn := L.GetGlobal(funcName)
-		if fn.Type() == lua.LTNil {
-			done <- fmt.Errorf("function %s not found in script", funcName)
-			return
+	for _, scriptName := range hooks {
+		if err := checkHook(scripts[scriptName]); err != nil {
+			return nil, err
 		}
+	}
+
+	return hooks, nil
+}
+
+// checkHook runs a hook script once in a fresh state to check that it defines on_ingest
+func checkHook(script *compiledScript) error {
+	v := newVM()
+	defer v.close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), maxExecutionTime)
+	defer cancel()
+	v.L.SetContext(withInstructionBudget(ctx, maxInstructions))
+
+	env, err := v.env(script)
+	if err != nil {
+		return fmt.Errorf("failed to load hook %s: %w", script.name, err)
+	}
+	if env.RawGetString(HookFunc).Type() != lua.LTFunction {
+		return fmt.Errorf("hook %s must define %s", script.name, HookFunc)
+	}
+	return nil
+}
+
+// checkExamples runs a normalizer against its examples table
+// Each example is { url = "...", normalizedUrl = "..." } for a URL that must normalize to
+// normalizedUrl, or { url = "...", isValid = false } for a URL that must be rejected.
+// An example with startSeconds also checks the start timestamp read from the URL.
+func checkExamples(v *vm, script *compiledScript, examples lua.LValue) error {
+	if examples.Type() == lua.LTNil {
+		return nil
+	}
+
+	decoded, err := luaValueToGoValue(examples)
+	if err != nil {
+		return fmt.Errorf("invalid examples: %w", err)
+	}
+	list, ok := decoded.([]interface{})
+	if !ok {
+		return fmt.Errorf("examples must be a list")
+	}
 
-		if fn.Type() != lua.LTFunction {
-			done <- fmt.Errorf("%s is not a function", funcName)
-			return
+	for i, item := range list {
+		example, ok := item.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("example %d must be a table", i+1)
+		}
+		exampleURL, _ := example["url"].(string)
+		expectValid, hasValid := example["isValid"].(bool)
+		if !hasValid {
+			expectValid = true
 		}
+		expectURL, _ := example["normalizedUrl"].(string)
+		expectStart, hasStart := example["startSeconds"].(float64)
 
-		// Call function with URL argument
-		urlArg := ""
-		if url, ok := args["url"].(string); ok {
-			urlArg = url
+		ret, err := v.call(script, "normalize_url", []interface{}{exampleURL})
+		if err != nil {
+			return fmt.Errorf("example %s: %w", exampleURL, err)
 		}
+		result := toResultMap(ret)
+		isValid, _ := result["isValid"].(bool)
+		normalizedURL, _ := result["normalizedUrl"].(string)
 
-		L.Push(fn)
-		L.Push(lua.LString(urlArg))
-		if err := L.PCall(1, 1, nil); err != nil {
-			done <- fmt.Errorf("failed to call function: %w", err)
-			return
+		if isValid != expectValid {
+			return fmt.Errorf("example %s: expected isValid=%t, got %t (%v)", exampleURL, expectValid, isValid, result["error"])
+		}
+		if expectValid && expectURL != "" && normalizedURL != expectURL {
+			return fmt.Errorf("example %s: expected %s, got %s", exampleURL, expectURL, normalizedURL)
+		}
+		if startSeconds, _ := result["startSeconds"].(float64); hasStart && startSeconds != expectStart {
+			return fmt.Errorf("example %s: expected startSeconds=%v, got %v", exampleURL, expectStart, startSeconds)
 		}
+	}
 
-		// Get return value
-		ret := L.Get(-1)
-		L.Pop(1)
+	return nil
+}
 
-		// Convert Lua value to Go map
-		result = s.luaValueToGoMap(L, ret)
-		done <- nil
-	}()
+// ExecuteScript executes a script's main function with the given arguments table
+// The main function is named after the script (e.g. "enrich.lua" defines enrich(args))
+// Returns the result as a map[string]interface{} or an error
+func (s *Service) ExecuteScript(ctx context.Context, scriptName string, args map[string]interface{}) (map[string]interface{}, error) {
+	funcName := strings.TrimSuffix(path.Base(scriptName), ".lua")
 
-	select {
-	case <-ctx.Done():
-		return nil, ctx.Err()
-	case err := <-done:
-		if err != nil {
-			return nil, err
-		}
-		return result, nil
+	result, err := s.Call(ctx, scriptName, funcName, args)
+	if err != nil {
+		return nil, err
 	}
+	return toResultMap(result), nil
 }
 
-// goToLuaValue converts a Go value to a Lua value
-func (s *Service) goToLuaValue(L *lua.LState, v interface{}) lua.LValue {
-	switch val := v.(type) {
-	case string:
-		return lua.LString(val)
-	case int:
-		return lua.LNumber(val)
-	case int64:
-		return lua.LNumber(val)
-	case float64:
-		return lua.LNumber(val)
-	case bool:
-		return lua.LBool(val)
-	case nil:
-		return lua.LNil
-	case map[string]interface{}:
-		table := L.NewTable()
-		for k, v := range val {
-			table.RawSetString(k, s.goToLuaValue(L, v))
+// Call runs funcName from a loaded script with the given arguments and returns its first result
+// Arguments are converted with goToLuaValue and the result with luaValueToGoValue, so
+// structured values can be passed in and nested tables and arrays come back as maps and slices.
+// Scripts run in a sandboxed VM and are stopped when ctx is done, when they exceed
+// maxExecutionTime, or when they run out of their instruction budget.
+func (s *Service) Call(ctx context.Context, scriptName, funcName string, args ...interface{}) (interface{}, error) {
+	// Get compiled script
+	s.scriptsMu.RLock()
+	script, exists := s.scripts[scriptName]
+	generation := s.generation
+	s.scriptsMu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("script not found: %s", scriptName)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, maxExecutionTime)
+	defer cancel()
+
+	// Get VM from pool, replacing VMs loaded before the last reload
+	v := s.vmPool.Get().(*vm)
+	if v.generation != generation {
+		v.close()
+		v = s.newWarmVM()
+	}
+	budgetCtx := withInstructionBudget(ctx, maxInstructions)
+	v.L.SetContext(budgetCtx)
+
+	result, err := v.call(script, funcName, args)
+	v.L.RemoveContext()
+
+	if err != nil {
+		// A VM that errored may have been stopped mid-instruction, so it is never reused
+		v.close()
+		if ctxErr := budgetCtx.Err(); ctxErr != nil {
+			return nil, fmt.Errorf("script %s stopped: %w", scriptName, ctxErr)
 		}
-		return table
-	case []interface{}:
-		table := L.NewTable()
-		for i, v := range val {
-			table.RawSetInt(i+1, s.goToLuaValue(L, v))
+		return nil, fmt.Errorf("script %s: %w", scriptName, err)
+	}
+
+	s.vmPool.Put(v)
+	return result, nil
+}
+
+// CallInto runs funcName like Call and decodes its result into out using JSON field tags
+func (s *Service) CallInto(ctx context.Context, scriptName, funcName string, out interface{}, args ...interface{}) error {
+	result, err := s.Call(ctx, scriptName, funcName, args...)
+	if err != nil {
+		return err
+	}
+
+	encoded, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("failed to encode result of %s: %w", funcName, err)
+	}
+	if err := json.Unmarshal(encoded, out); err != nil {
+		return fmt.Errorf("failed to decode result of %s: %w", funcName, err)
+	}
+	return nil
+}
+
+// SetInstances sets the hosts a federated platform's script handles, such as the PeerTube
+// instances to accept videos from. Only these exact hosts match, not their subdomains, and they
+// are kept when the scripts are reloaded.
+func (s *Service) SetInstances(platform string, hosts []string) {
+	instances := make([]string, 0, len(hosts))
+	for _, host := range hosts {
+		if host = strings.ToLower(strings.TrimSpace(host)); host != "" {
+			instances = append(instances, host)
 		}
-		return table
-	default:
-		return lua.LString(fmt.Sprintf("%v", val))
 	}
+
+	s.scriptsMu.Lock()
+	defer s.scriptsMu.Unlock()
+	if s.instances == nil {
+		s.instances = make(map[string][]string)
+	}
+	s.instances[platform] = instances
 }
 
-// luaValueToGoMap converts a Lua value to a Go map
-func (s *Service) luaValueToGoMap(L *lua.LState, lv lua.LValue) map[string]interface{} {
-	if lv.Type() != lua.LTTable {
-		// If not a table, try to convert to a simple value
-		return map[string]interface{}{
-			"value": s.luaValueToGoValue(lv),
+// NormalizeURL normalizes a video URL with the script registered for its host
+// Federated scripts are only used for their platform's configured instances
+func (s *Service) NormalizeURL(ctx context.Context, rawURL string) (map[string]interface{}, error) {
+	host := urlHost(rawURL)
+
+	s.scriptsMu.RLock()
+	scriptName := ""
+	for _, n := range s.normalizers {
+		if n.matches(host) || (n.federated && host != "" && slices.Contains(s.instances[n.platform], host)) {
+			scriptName = n.script
+			break
 		}
 	}
+	s.scriptsMu.RUnlock()
 
-	table := lv.(*lua.LTable)
-	result := make(map[string]interface{})
+	if scriptName != "" {
+		return s.normalize(ctx, scriptName, rawURL)
+	}
 
-	table.ForEach(func(key lua.LValue, value lua.LValue) {
-		keyStr := key.String()
-		result[keyStr] = s.luaValueToGoValue(value)
-	})
+	return map[string]interface{}{
+		"isValid": false,
+		"error":   "Invalid URL: Unsupported platform",
+	}, nil
+}
 
-	return result
+// normalize runs a normalizer script's normalize_url function
+func (s *Service) normalize(ctx context.Context, scriptName, rawURL string) (map[string]interface{}, error) {
+	result, err := s.Call(ctx, scriptName, "normalize_url", rawURL)
+	if err != nil {
+		return nil, err
+	}
+	return toResultMap(result), nil
 }
 
-// luaValueToGoValue converts a Lua value to a Go value
-func (s *Service) luaValueToGoValue(lv lua.LValue) interface{} {
-	switch lv.Type() {
-	case lua.LTNil:
-		return nil
-	case lua.LTBool:
-		return bool(lv.(lua.LBool))
-	case lua.LTString:
-		return string(lv.(lua.LString))
-	case lua.LTNumber:
-		return float64(lv.(lua.LNumber))
-	case lua.LTTable:
-		table := lv.(*lua.LTable)
-		result := make(map[string]interface{})
-		table.ForEach(func(key lua.LValue, value lua.LValue) {
-			keyStr := key.String()
-			result[keyStr] = s.luaValueToGoValue(value)
-		})
-		return result
-	default:
-		return lv.String()
+// matches reports whether host is one of the normalizer's hosts or a subdomain of one
+func (n normalizer) matches(host string) bool {
+	for _, h := range n.hosts {
+		if host == h || strings.HasSuffix(host, "."+h) {
+			return true
+		}
 	}
+	return false
 }
 
-// NormalizeURL is a convenience method specifically for URL normalization
-func (s *Service) NormalizeURL(ctx context.Context, url string) (map[string]interface{}, error) {
-	args := map[string]interface{}{
-		"url": url,
+// urlHost returns the lowercased host of a URL, allowing the scheme to be omitted
+func urlHost(rawURL string) string {
+	rawURL = strings.TrimSpace(rawURL)
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "https://" + rawURL
 	}
-	return s.ExecuteScript(ctx, "url_normalize.lua", args)
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
 }
 
-// Close cleans up resources (currently just clears scripts)
+// Close stops the scripts directory watcher and clears scripts and the normalizer registry
 func (s *Service) Close() error {
+	if s.stopWatch != nil {
+		s.stopWatch()
+	}
+
 	s.scriptsMu.Lock()
 	defer s.scriptsMu.Unlock()
-	s.scripts = make(map[string]string)
+	s.scripts = make(map[string]*compiledScript)
+	s.sources = nil
+	s.origins = nil
+	s.normalizers = nil
+	s.hooks = nil
+	s.generation++
 	return nil
 }
 
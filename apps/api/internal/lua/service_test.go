@@ -0,0 +1,80 @@
+package lua
+
+import (
+	"context"
+	"testing"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+const benchmarkURL = "https://www.youtube.com/watch?v=dQw4w9WgXcQ&list=PLx0sYbCqOb8TBPRdmBHs5Iftvv9TPboYG&index=3"
+
+// BenchmarkNormalizeURL measures normalization with precompiled scripts and warmed VMs
+func BenchmarkNormalizeURL(b *testing.B) {
+	s, err := NewService()
+	if err != nil {
+		b.Fatal(err)
+	}
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		result, err := s.NormalizeURL(ctx, benchmarkURL)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if isValid, _ := result["isValid"].(bool); !isValid {
+			b.Fatalf("expected a valid result, got %v", result)
+		}
+	}
+}
+
+// BenchmarkNormalizeURLParallel measures normalization throughput across goroutines
+func BenchmarkNormalizeURLParallel(b *testing.B) {
+	s, err := NewService()
+	if err != nil {
+		b.Fatal(err)
+	}
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, err := s.NormalizeURL(ctx, benchmarkURL); err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+}
+
+// BenchmarkNormalizeURLRecompiled is the baseline: the script is parsed, compiled and
+// executed on every call, as ExecuteScript did before scripts were precompiled
+func BenchmarkNormalizeURLRecompiled(b *testing.B) {
+	source, err := scriptsFS.ReadFile("scripts/normalizers/youtube.lua")
+	if err != nil {
+		b.Fatal(err)
+	}
+	L := newSandboxedState()
+	defer L.Close()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := L.DoString(string(source)); err != nil {
+			b.Fatal(err)
+		}
+		L.Push(L.GetGlobal("normalize_url"))
+		L.Push(lua.LString(benchmarkURL))
+		if err := L.PCall(1, 1, nil); err != nil {
+			b.Fatal(err)
+		}
+		ret := L.Get(-1)
+		L.Pop(1)
+		if _, err := luaValueToGoValue(ret); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
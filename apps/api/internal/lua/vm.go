@@ -0,0 +1,145 @@
+package lua
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	lua "github.com/yuin/gopher-lua"
+	"github.com/yuin/gopher-lua/parse"
+)
+
+// compiledScript is a script parsed and compiled once, when scripts are loaded
+type compiledScript struct {
+	name  string
+	proto *lua.FunctionProto
+}
+
+// compileScript parses and compiles a script's source
+func compileScript(name, content string) (*compiledScript, error) {
+	chunk, err := parse.Parse(strings.NewReader(content), name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse script %s: %w", name, err)
+	}
+	rewriteConcat(chunk)
+
+	proto, err := lua.Compile(chunk, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile script %s: %w", name, err)
+	}
+
+	return &compiledScript{name: name, proto: proto}, nil
+}
+
+// vm is a sandboxed VM that keeps scripts loaded between calls
+// Each script runs in its own environment table, so scripts that define the same globals
+// (every normalizer defines normalize_url) don't overwrite each other. The environment
+// starts as a copy of the sandbox's globals and standard libraries, see newScriptEnv.
+type vm struct {
+	L    *lua.LState
+	envs map[*compiledScript]*lua.LTable
+	// generation is the Service generation whose scripts this VM was warmed with
+	generation uint64
+}
+
+func newVM() *vm {
+	return &vm{
+		L:    newSandboxedState(),
+		envs: make(map[*compiledScript]*lua.LTable),
+	}
+}
+
+// warm loads scripts into the VM ahead of their first call
+// Warming stops at the first script that fails; it reports its error when called
+func (v *vm) warm(scripts []*compiledScript) {
+	ctx, cancel := context.WithTimeout(context.Background(), maxExecutionTime)
+	defer cancel()
+
+	for _, script := range scripts {
+		v.L.SetContext(withInstructionBudget(ctx, maxInstructions))
+		_, err := v.env(script)
+		v.L.RemoveContext()
+		if err != nil {
+			return
+		}
+	}
+}
+
+// env returns the script's environment, running the script's top-level chunk on first use
+// The caller must have set a context on the VM
+func (v *vm) env(script *compiledScript) (env *lua.LTable, err error) {
+	if env, ok := v.envs[script]; ok {
+		return env, nil
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("lua panic: %v", r)
+		}
+	}()
+
+	L := v.L
+	env = newScriptEnv(L)
+
+	fn := L.NewFunctionFromProto(script.proto)
+	fn.Env = env
+
+	L.Push(fn)
+	if err := L.PCall(0, 0, nil); err != nil {
+		return nil, fmt.Errorf("failed to execute script: %w", err)
+	}
+
+	v.envs[script] = env
+	return env, nil
+}
+
+// call runs one of a script's functions and returns its first result
+// The caller must have set a context on the VM
+func (v *vm) call(script *compiledScript, funcName string, args []interface{}) (result interface{}, err error) {
+	env, err := v.env(script)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("lua panic: %v", r)
+		}
+	}()
+
+	L := v.L
+
+	// Get the function from the script's environment
+	fn := env.RawGetString(funcName)
+	if fn.Type() == lua.LTNil {
+		return nil, fmt.Errorf("function %s not found in script", funcName)
+	}
+
+	if fn.Type() != lua.LTFunction {
+		return nil, fmt.Errorf("%s is not a function", funcName)
+	}
+
+	L.Push(fn)
+	for i, arg := range args {
+		value, err := goToLuaValue(L, arg)
+		if err != nil {
+			L.Pop(i + 1)
+			return nil, fmt.Errorf("invalid argument %d to %s: %w", i+1, funcName, err)
+		}
+		L.Push(value)
+	}
+
+	if err := L.PCall(len(args), 1, nil); err != nil {
+		return nil, fmt.Errorf("failed to call function: %w", err)
+	}
+
+	// Get return value
+	ret := L.Get(-1)
+	L.Pop(1)
+
+	return luaValueToGoValue(ret)
+}
+
+func (v *vm) close() {
+	v.L.Close()
+}
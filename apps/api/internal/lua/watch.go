@@ -0,0 +1,98 @@
+package lua
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/ekkolyth/ekko-playlist/api/internal/logging"
+)
+
+// WatchDir overlays the .lua files in dir on the embedded scripts and reloads them when they change
+// Files replace the embedded script with the same relative path (e.g. normalizers/youtube.lua)
+// and new files add scripts; deleting a file falls back to the embedded version.
+// The directory is polled every interval. A reload only takes effect once every script compiles
+// and passes its self-test; otherwise the error is logged and the previous scripts stay loaded.
+func (s *Service) WatchDir(dir string, interval time.Duration) error {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read scripts directory: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("scripts directory %s is not a directory", dir)
+	}
+
+	fingerprint, err := dirFingerprint(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read scripts directory: %w", err)
+	}
+	s.reloadDir(dir)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.stopWatch = cancel
+	go s.watch(ctx, dir, interval, fingerprint)
+
+	return nil
+}
+
+func (s *Service) watch(ctx context.Context, dir string, interval time.Duration, fingerprint string) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		current, err := dirFingerprint(dir)
+		if err != nil {
+			logging.Info("Lua: Failed to check scripts directory %s: %s", dir, err.Error())
+			continue
+		}
+		if current == fingerprint {
+			continue
+		}
+		fingerprint = current
+		s.reloadDir(dir)
+	}
+}
+
+// reloadDir loads the scripts in dir over the embedded scripts, keeping the previous scripts on failure
+func (s *Service) reloadDir(dir string) {
+	sources, err := readSources(os.DirFS(dir))
+	if err == nil {
+		err = s.setLayer(layerDirectory, sources)
+	}
+	if err != nil {
+		logging.Info("Lua: Reload from %s failed, keeping previous scripts: %s", dir, err.Error())
+		return
+	}
+	logging.Info("Lua: Loaded %d script overrides from %s", len(sources), dir)
+}
+
+// dirFingerprint summarizes the name, size and modification time of every .lua file in dir
+func dirFingerprint(dir string) (string, error) {
+	var b strings.Builder
+	err := fs.WalkDir(os.DirFS(dir), ".", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || path.Ext(name) != ".lua" {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&b, "%s:%d:%d\n", name, info.Size(), info.ModTime().UnixNano())
+		return nil
+	})
+	return b.String(), err
+}
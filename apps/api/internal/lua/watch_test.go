@@ -0,0 +1,120 @@
+package lua
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// waitForResult calls funcName from scriptName until it returns want or the wait times out
+func waitForResult(t *testing.T, s *Service, scriptName, funcName, want string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		result, err := s.Call(context.Background(), scriptName, funcName)
+		if err == nil && result == want {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%s: result = %v, err = %v, want %q", scriptName, result, err, want)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestWatchDirReload(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "greet.lua")
+	if err := os.WriteFile(file, []byte(`function greet() return "hello" end`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := NewService()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if err := s.WatchDir(dir, 10*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	waitForResult(t, s, "greet.lua", "greet", "hello")
+
+	// A changed file replaces the loaded script
+	if err := os.WriteFile(file, []byte(`function greet() return "hello again" end`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	waitForResult(t, s, "greet.lua", "greet", "hello again")
+
+	// A file that doesn't compile leaves the previous scripts loaded
+	if err := os.WriteFile(file, []byte(`function greet( return end`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "other.lua"), []byte(`function other() return "other" end`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	waitForResult(t, s, "greet.lua", "greet", "hello again")
+	if _, err := s.Call(context.Background(), "other.lua", "other"); err == nil {
+		t.Fatal("other.lua was loaded alongside a script that doesn't compile")
+	}
+
+	// Deleting a file unloads its script
+	if err := os.Remove(file); err != nil {
+		t.Fatal(err)
+	}
+	waitForResult(t, s, "other.lua", "other", "other")
+	if _, err := s.Call(context.Background(), "greet.lua", "greet"); err == nil ||
+		!strings.Contains(err.Error(), "script not found") {
+		t.Fatalf("err = %v, want the deleted script not found", err)
+	}
+}
+
+func TestWatchDirOverridesEmbeddedScript(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewService()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if err := s.WatchDir(dir, 10*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+
+	embedded, _ := s.Source("normalizers/youtube.lua")
+	override := embedded + "\nfunction marker() return \"directory\" end\n"
+	if err := os.MkdirAll(filepath.Join(dir, "normalizers"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "normalizers", "youtube.lua"), []byte(override), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	waitForResult(t, s, "normalizers/youtube.lua", "marker", "directory")
+	if origin := scriptOrigin(s, "normalizers/youtube.lua"); origin != OriginDirectory {
+		t.Errorf("origin = %q, want %q", origin, OriginDirectory)
+	}
+
+	// Deleting the override falls back to the embedded script
+	if err := os.Remove(filepath.Join(dir, "normalizers", "youtube.lua")); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for scriptOrigin(s, "normalizers/youtube.lua") != OriginEmbedded {
+		if time.Now().After(deadline) {
+			t.Fatal("the embedded script was not restored")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// scriptOrigin returns where a loaded script came from
+func scriptOrigin(s *Service, scriptName string) string {
+	for _, info := range s.Scripts() {
+		if info.Name == scriptName {
+			return info.Origin
+		}
+	}
+	return ""
+}
@@ -0,0 +1,231 @@
+package metadata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/ekkolyth/ekko-playlist/api/internal/db"
+	"github.com/ekkolyth/ekko-playlist/api/internal/logging"
+)
+
+const (
+	// enrichPollInterval is how often the enricher looks for videos waiting for metadata
+	enrichPollInterval = 10 * time.Second
+	// enrichBatchSize is how many videos are claimed at a time
+	enrichBatchSize = 20
+	// enrichLease is how long a claimed video is hidden from other enrichers; a video whose
+	// enricher died is picked up again once it runs out
+	enrichLease = 5 * time.Minute
+	// enrichVideoTimeout bounds the provider lookups for one video
+	enrichVideoTimeout = 15 * time.Second
+	// enrichMaxAttempts is how many failed lookups are retried before a video is marked failed
+	enrichMaxAttempts = 8
+	// enrichBaseBackoff and enrichMaxBackoff bound the delay between retries
+	enrichBaseBackoff = time.Minute
+	enrichMaxBackoff  = 6 * time.Hour
+)
+
+// videoStore records the metadata of videos; *db.Queries implements it
+type videoStore interface {
+	ClaimVideosForEnrichment(ctx context.Context, arg *db.ClaimVideosForEnrichmentParams) ([]*db.ClaimVideosForEnrichmentRow, error)
+	SaveVideoMetadata(ctx context.Context, arg *db.SaveVideoMetadataParams) error
+	LinkChannelExternalID(ctx context.Context, id int64) error
+	MoveVideoToExternalChannel(ctx context.Context, id int64) error
+	FailVideoMetadata(ctx context.Context, arg *db.FailVideoMetadataParams) error
+	RetryVideoMetadata(ctx context.Context, arg *db.RetryVideoMetadataParams) error
+}
+
+// Enricher fills in the metadata of saved videos in the background
+type Enricher struct {
+	store    videoStore
+	provider Provider
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+// NewEnricher creates an enricher that looks videos up with provider
+func NewEnricher(dbService *db.Service, provider Provider) *Enricher {
+	return &Enricher{
+		store:    dbService.Queries,
+		provider: provider,
+	}
+}
+
+// Start starts enriching pending videos
+func (e *Enricher) Start(ctx context.Context) {
+	ctx, e.cancel = context.WithCancel(ctx)
+
+	e.wg.Add(1)
+	go e.run(ctx)
+	logging.Info("Metadata: Started enricher")
+}
+
+// Stop signals the enricher to stop and waits for the current batch to finish
+// Videos of an interrupted batch are retried once their lease runs out.
+func (e *Enricher) Stop(ctx context.Context) error {
+	if e.cancel == nil {
+		return nil
+	}
+	e.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		e.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		logging.Info("Metadata: Enricher stopped")
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("timed out waiting for the enricher: %w", ctx.Err())
+	}
+}
+
+func (e *Enricher) run(ctx context.Context) {
+	defer e.wg.Done()
+
+	ticker := time.NewTicker(enrichPollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Keep claiming until there is nothing left that is due
+		for ctx.Err() == nil {
+			videos, err := e.store.ClaimVideosForEnrichment(ctx, &db.ClaimVideosForEnrichmentParams{
+				Limit:        enrichBatchSize,
+				LeaseSeconds: int32(enrichLease / time.Second),
+			})
+			if err != nil {
+				if ctx.Err() == nil {
+					logging.Info("Metadata: Failed to claim videos: %s", err.Error())
+				}
+				break
+			}
+			if len(videos) == 0 {
+				break
+			}
+
+			for _, video := range videos {
+				if ctx.Err() != nil {
+					return
+				}
+				e.enrich(ctx, video)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// enrich looks a claimed video up and records the result
+// Videos of platforms no provider supports fail at once; retrying them can't help.
+func (e *Enricher) enrich(ctx context.Context, video *db.ClaimVideosForEnrichmentRow) {
+	var metadata *Metadata
+	var err error
+	if e.provider.Supports(video.Platform) {
+		fetchCtx, cancel := context.WithTimeout(ctx, enrichVideoTimeout)
+		metadata, err = e.provider.Fetch(fetchCtx, Video{
+			Platform: video.Platform,
+			VideoID:  video.VideoID,
+			URL:      video.NormalizedUrl,
+		})
+		cancel()
+		// A lookup cut short by shutdown is retried when the lease runs out
+		if ctx.Err() != nil {
+			return
+		}
+	} else {
+		err = fmt.Errorf("no provider supports %s videos: %w", video.Platform, ErrUnsupported)
+	}
+
+	saveCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	switch {
+	case err == nil:
+		err = e.save(saveCtx, video.ID, metadata)
+	case errors.Is(err, ErrNotFound), errors.Is(err, ErrUnsupported):
+		reason := err.Error()
+		logging.Info("Metadata: No metadata for video %d (%s %s): %s", video.ID, video.Platform, video.VideoID, reason)
+		err = e.store.FailVideoMetadata(saveCtx, &db.FailVideoMetadataParams{
+			ID:            video.ID,
+			MetadataError: &reason,
+		})
+	case video.MetadataAttempts+1 >= enrichMaxAttempts:
+		reason := err.Error()
+		logging.Info("Metadata: Giving up on video %d after %d attempts: %s", video.ID, video.MetadataAttempts+1, reason)
+		err = e.store.FailVideoMetadata(saveCtx, &db.FailVideoMetadataParams{
+			ID:            video.ID,
+			MetadataError: &reason,
+		})
+	default:
+		reason := err.Error()
+		err = e.store.RetryVideoMetadata(saveCtx, &db.RetryVideoMetadataParams{
+			ID:            video.ID,
+			MetadataError: &reason,
+			DelaySeconds:  int32(backoff(int(video.MetadataAttempts)) / time.Second),
+		})
+	}
+	if err != nil {
+		logging.Info("Metadata: Failed to record metadata for video %d: %s", video.ID, err.Error())
+	}
+}
+
+// save records a video's metadata
+// A channel ID is also given to the video's channel if no other channel of the user has it;
+// otherwise the video moves to the channel that does, which merges channels that were saved
+// under different names one video at a time.
+func (e *Enricher) save(ctx context.Context, id int64, metadata *Metadata) error {
+	q := e.store
+	if err := q.SaveVideoMetadata(ctx, saveParams(id, metadata)); err != nil {
+		return err
+	}
+	if metadata.ChannelID == "" {
+		return nil
+	}
+	if err := q.LinkChannelExternalID(ctx, id); err != nil {
+		return fmt.Errorf("failed to link channel: %w", err)
+	}
+	if err := q.MoveVideoToExternalChannel(ctx, id); err != nil {
+		return fmt.Errorf("failed to move video to its channel: %w", err)
+	}
+	return nil
+}
+
+// backoff returns how long to wait before retrying a video that has already failed attempts times
+func backoff(attempts int) time.Duration {
+	delay := enrichBaseBackoff
+	for i := 0; i < attempts && delay < enrichMaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, enrichMaxBackoff)
+}
+
+// saveParams converts metadata to SaveVideoMetadata parameters, leaving unknown fields untouched
+func saveParams(id int64, metadata *Metadata) *db.SaveVideoMetadataParams {
+	params := &db.SaveVideoMetadataParams{ID: id}
+	if metadata.ThumbnailURL != "" {
+		params.ThumbnailUrl = &metadata.ThumbnailURL
+	}
+	if metadata.DurationSeconds > 0 {
+		duration := int32(min(metadata.DurationSeconds, 1<<31-1))
+		params.DurationSeconds = &duration
+	}
+	if !metadata.PublishedAt.IsZero() {
+		params.PublishedAt = pgtype.Timestamptz{Time: metadata.PublishedAt, Valid: true}
+	}
+	if metadata.ChannelID != "" {
+		params.ChannelExternalID = &metadata.ChannelID
+	}
+	return params
+}
@@ -0,0 +1,97 @@
+package metadata
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ekkolyth/ekko-playlist/api/internal/db"
+)
+
+// recordingStore is a videoStore remembering how each video's lookup was recorded
+type recordingStore struct {
+	saved   []int64
+	failed  map[int64]string
+	retried map[int64]string
+}
+
+func newRecordingStore() *recordingStore {
+	return &recordingStore{failed: make(map[int64]string), retried: make(map[int64]string)}
+}
+
+func (s *recordingStore) ClaimVideosForEnrichment(ctx context.Context, arg *db.ClaimVideosForEnrichmentParams) ([]*db.ClaimVideosForEnrichmentRow, error) {
+	return nil, nil
+}
+
+func (s *recordingStore) SaveVideoMetadata(ctx context.Context, arg *db.SaveVideoMetadataParams) error {
+	s.saved = append(s.saved, arg.ID)
+	return nil
+}
+
+func (s *recordingStore) LinkChannelExternalID(ctx context.Context, id int64) error {
+	return nil
+}
+
+func (s *recordingStore) MoveVideoToExternalChannel(ctx context.Context, id int64) error {
+	return nil
+}
+
+func (s *recordingStore) FailVideoMetadata(ctx context.Context, arg *db.FailVideoMetadataParams) error {
+	s.failed[arg.ID] = *arg.MetadataError
+	return nil
+}
+
+func (s *recordingStore) RetryVideoMetadata(ctx context.Context, arg *db.RetryVideoMetadataParams) error {
+	s.retried[arg.ID] = *arg.MetadataError
+	return nil
+}
+
+func TestEnricherOutcomes(t *testing.T) {
+	youtube := &Fake{Platform: "youtube", Videos: map[string]*Metadata{
+		"aaaaaaaaaaa": {ThumbnailURL: "https://example.com/a.jpg"},
+	}}
+	store := newRecordingStore()
+	e := &Enricher{store: store, provider: Chain{youtube}}
+
+	e.enrich(context.Background(), &db.ClaimVideosForEnrichmentRow{ID: 1, Platform: "youtube", VideoID: "aaaaaaaaaaa"})
+	e.enrich(context.Background(), &db.ClaimVideosForEnrichmentRow{ID: 2, Platform: "youtube", VideoID: "bbbbbbbbbbb"})
+	e.enrich(context.Background(), &db.ClaimVideosForEnrichmentRow{ID: 3, Platform: "twitch", VideoID: "123"})
+	e.enrich(context.Background(), &db.ClaimVideosForEnrichmentRow{ID: 4, Platform: "peertube", VideoID: "abc"})
+
+	if len(store.saved) != 1 || store.saved[0] != 1 {
+		t.Errorf("saved = %v, want [1]", store.saved)
+	}
+	if _, ok := store.failed[2]; !ok {
+		t.Errorf("a removed video was not failed")
+	}
+	// Unsupported platforms fail on the first attempt without a lookup
+	for _, id := range []int64{3, 4} {
+		if reason, ok := store.failed[id]; !ok || !strings.Contains(reason, "no provider supports") {
+			t.Errorf("video %d failed = %t with %q, want an unsupported platform failure", id, ok, reason)
+		}
+	}
+	if len(store.retried) != 0 {
+		t.Errorf("retried = %v, want none", store.retried)
+	}
+	for _, call := range youtube.Calls() {
+		if call.Platform != "youtube" {
+			t.Errorf("provider was asked for a %s video", call.Platform)
+		}
+	}
+}
+
+func TestEnricherRetriesErrors(t *testing.T) {
+	store := newRecordingStore()
+	e := &Enricher{store: store, provider: &Fake{Platform: "youtube", Err: errors.New("quota exceeded")}}
+
+	e.enrich(context.Background(), &db.ClaimVideosForEnrichmentRow{ID: 1, Platform: "youtube", VideoID: "aaaaaaaaaaa"})
+	e.enrich(context.Background(), &db.ClaimVideosForEnrichmentRow{ID: 2, Platform: "youtube", VideoID: "aaaaaaaaaaa", MetadataAttempts: enrichMaxAttempts - 1})
+
+	if _, ok := store.retried[1]; !ok {
+		t.Errorf("a failed lookup was not retried")
+	}
+	if _, ok := store.failed[2]; !ok {
+		t.Errorf("a video out of attempts was not failed")
+	}
+}
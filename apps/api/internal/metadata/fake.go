@@ -0,0 +1,48 @@
+package metadata
+
+import (
+	"context"
+	"sync"
+)
+
+// Fake is an in-memory Provider for tests
+// It answers with Videos[videoID], ErrNotFound for unknown IDs, or Err if it is set.
+type Fake struct {
+	Platform string
+	Videos   map[string]*Metadata
+	Err      error
+
+	mu    sync.Mutex
+	calls []Video
+}
+
+func (f *Fake) Name() string {
+	return "fake"
+}
+
+func (f *Fake) Supports(platform string) bool {
+	return f.Platform == "" || platform == f.Platform
+}
+
+func (f *Fake) Fetch(ctx context.Context, video Video) (*Metadata, error) {
+	f.mu.Lock()
+	f.calls = append(f.calls, video)
+	f.mu.Unlock()
+
+	if f.Err != nil {
+		return nil, f.Err
+	}
+	metadata, ok := f.Videos[video.VideoID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	copied := *metadata
+	return &copied, nil
+}
+
+// Calls returns the videos Fetch was called with, in order
+func (f *Fake) Calls() []Video {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Video(nil), f.calls...)
+}
@@ -0,0 +1,98 @@
+package metadata
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// oEmbedEndpoints are the oEmbed endpoints of the platforms that have one
+var oEmbedEndpoints = map[string]string{
+	"youtube":     "https://www.youtube.com/oembed",
+	"vimeo":       "https://vimeo.com/api/oembed.json",
+	"dailymotion": "https://www.dailymotion.com/services/oembed",
+	"soundcloud":  "https://soundcloud.com/oembed",
+}
+
+// maxResponseSize caps a provider response
+const maxResponseSize = 1 << 20
+
+// OEmbedProvider reads thumbnails from the platforms' oEmbed endpoints, along with the
+// duration and upload date where the platform includes them (Vimeo)
+// It needs no API key but knows less than the platform APIs.
+type OEmbedProvider struct {
+	client    *http.Client
+	endpoints map[string]string
+}
+
+// NewOEmbedProvider creates an oEmbed provider for every platform with a known endpoint
+func NewOEmbedProvider() *OEmbedProvider {
+	return &OEmbedProvider{
+		client:    &http.Client{Timeout: 10 * time.Second},
+		endpoints: oEmbedEndpoints,
+	}
+}
+
+func (p *OEmbedProvider) Name() string {
+	return "oembed"
+}
+
+func (p *OEmbedProvider) Supports(platform string) bool {
+	_, ok := p.endpoints[platform]
+	return ok
+}
+
+// oEmbedResponse is the part of an oEmbed response that is used
+type oEmbedResponse struct {
+	ThumbnailURL string `json:"thumbnail_url"`
+	Duration     int    `json:"duration"`
+	UploadDate   string `json:"upload_date"`
+}
+
+func (p *OEmbedProvider) Fetch(ctx context.Context, video Video) (*Metadata, error) {
+	endpoint, ok := p.endpoints[video.Platform]
+	if !ok {
+		return nil, fmt.Errorf("no oEmbed endpoint for %s", video.Platform)
+	}
+
+	query := url.Values{"url": {video.URL}, "format": {"json"}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound, http.StatusUnauthorized, http.StatusForbidden:
+		// YouTube answers 401 for private videos and 404 for removed ones
+		return nil, ErrNotFound
+	default:
+		return nil, fmt.Errorf("oEmbed returned %s", resp.Status)
+	}
+
+	var body oEmbedResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(&body); err != nil {
+		return nil, fmt.Errorf("invalid oEmbed response: %w", err)
+	}
+
+	metadata := &Metadata{
+		ThumbnailURL:    body.ThumbnailURL,
+		DurationSeconds: body.Duration,
+	}
+	if body.UploadDate != "" {
+		if t, err := time.Parse(time.DateTime, body.UploadDate); err == nil {
+			metadata.PublishedAt = t.UTC()
+		}
+	}
+	return metadata, nil
+}
@@ -0,0 +1,115 @@
+package metadata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrNotFound is returned by providers for videos that no longer exist or are private
+var ErrNotFound = errors.New("video not found")
+
+// ErrUnsupported is returned for videos of a platform no provider supports
+var ErrUnsupported = errors.New("platform not supported")
+
+// Video identifies a saved video to a provider
+type Video struct {
+	Platform string
+	VideoID  string
+	URL      string
+}
+
+// Metadata is what a provider knows about a video; zero fields are unknown
+type Metadata struct {
+	ThumbnailURL    string
+	DurationSeconds int
+	PublishedAt     time.Time
+	ChannelID       string
+}
+
+// complete reports whether every field is known
+func (m *Metadata) complete() bool {
+	return m.ThumbnailURL != "" && m.DurationSeconds > 0 && !m.PublishedAt.IsZero() && m.ChannelID != ""
+}
+
+// merge fills m's unknown fields from other
+func (m *Metadata) merge(other *Metadata) {
+	if m.ThumbnailURL == "" {
+		m.ThumbnailURL = other.ThumbnailURL
+	}
+	if m.DurationSeconds <= 0 {
+		m.DurationSeconds = other.DurationSeconds
+	}
+	if m.PublishedAt.IsZero() {
+		m.PublishedAt = other.PublishedAt
+	}
+	if m.ChannelID == "" {
+		m.ChannelID = other.ChannelID
+	}
+}
+
+// Provider looks up video metadata from an external source
+type Provider interface {
+	// Name identifies the provider in logs
+	Name() string
+	// Supports reports whether the provider knows videos of the platform
+	Supports(platform string) bool
+	// Fetch returns the video's metadata, or ErrNotFound if the video is gone
+	Fetch(ctx context.Context, video Video) (*Metadata, error)
+}
+
+// Chain asks each provider that supports a video in turn, filling in the fields the earlier
+// providers didn't know, until every field is known
+type Chain []Provider
+
+func (c Chain) Name() string {
+	return "chain"
+}
+
+func (c Chain) Supports(platform string) bool {
+	for _, provider := range c {
+		if provider.Supports(platform) {
+			return true
+		}
+	}
+	return false
+}
+
+// Fetch returns the merged metadata of every provider that answered
+// A provider reporting ErrNotFound ends the chain; other provider errors are skipped and only
+// returned if no provider answered.
+func (c Chain) Fetch(ctx context.Context, video Video) (*Metadata, error) {
+	var result *Metadata
+	var errs []error
+	for _, provider := range c {
+		if !provider.Supports(video.Platform) {
+			continue
+		}
+
+		metadata, err := provider.Fetch(ctx, video)
+		if errors.Is(err, ErrNotFound) {
+			return nil, err
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", provider.Name(), err))
+			continue
+		}
+
+		if result == nil {
+			result = &Metadata{}
+		}
+		result.merge(metadata)
+		if result.complete() {
+			break
+		}
+	}
+
+	if result == nil {
+		if len(errs) == 0 {
+			return nil, fmt.Errorf("no provider supports %s videos: %w", video.Platform, ErrUnsupported)
+		}
+		return nil, errors.Join(errs...)
+	}
+	return result, nil
+}
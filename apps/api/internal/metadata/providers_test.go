@@ -0,0 +1,206 @@
+package metadata
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func TestParseISODuration(t *testing.T) {
+	cases := map[string]int{
+		"PT3M33S":   213,
+		"PT1H2M3S":  3723,
+		"PT10H":     36000,
+		"PT45S":     45,
+		"P1DT1S":    86401,
+		"P0D":       0,
+		"":          0,
+		"1:02:03":   0,
+		"PT1.5S":    0,
+		"PT2M":      120,
+		"P1DT2H3M4": 0,
+	}
+	for value, want := range cases {
+		if got := parseISODuration(value); got != want {
+			t.Errorf("parseISODuration(%q) = %d, want %d", value, got, want)
+		}
+	}
+}
+
+func TestYouTubeProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if r.URL.Path != "/youtube/v3/videos" || query.Get("key") != "test-key" || query.Get("part") != "snippet,contentDetails" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "bad request"})
+			return
+		}
+		if query.Get("id") != "dQw4w9WgXcQ" {
+			writeJSON(w, http.StatusOK, map[string]interface{}{"items": []interface{}{}})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"items": []interface{}{map[string]interface{}{
+				"snippet": map[string]interface{}{
+					"publishedAt": "2009-10-25T06:57:33Z",
+					"channelId":   "UCuAXFkgsw1L7xaCfnd5JJOw",
+					"thumbnails": map[string]interface{}{
+						"default": map[string]string{"url": "https://i.ytimg.com/vi/dQw4w9WgXcQ/default.jpg"},
+						"high":    map[string]string{"url": "https://i.ytimg.com/vi/dQw4w9WgXcQ/hqdefault.jpg"},
+					},
+				},
+				"contentDetails": map[string]string{"duration": "PT3M33S"},
+			}},
+		})
+	}))
+	defer server.Close()
+
+	p := NewYouTubeProvider("test-key")
+	p.apiURL = server.URL + "/youtube/v3"
+
+	metadata, err := p.Fetch(context.Background(), Video{Platform: "youtube", VideoID: "dQw4w9WgXcQ"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Metadata{
+		ThumbnailURL:    "https://i.ytimg.com/vi/dQw4w9WgXcQ/hqdefault.jpg",
+		DurationSeconds: 213,
+		PublishedAt:     time.Date(2009, 10, 25, 6, 57, 33, 0, time.UTC),
+		ChannelID:       "UCuAXFkgsw1L7xaCfnd5JJOw",
+	}
+	if !reflect.DeepEqual(metadata, want) {
+		t.Errorf("Fetch = %+v, want %+v", metadata, want)
+	}
+
+	if _, err := p.Fetch(context.Background(), Video{Platform: "youtube", VideoID: "aaaaaaaaaaa"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Fetch(removed) error = %v, want ErrNotFound", err)
+	}
+
+	p.apiKey = "wrong-key"
+	if _, err := p.Fetch(context.Background(), Video{Platform: "youtube", VideoID: "dQw4w9WgXcQ"}); err == nil || errors.Is(err, ErrNotFound) {
+		t.Errorf("Fetch(bad key) error = %v, want a fetch error", err)
+	}
+}
+
+func TestOEmbedProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("url") {
+		case "https://vimeo.com/76979871":
+			writeJSON(w, http.StatusOK, map[string]interface{}{
+				"thumbnail_url": "https://i.vimeocdn.com/video/452001751-640.jpg",
+				"duration":      62,
+				"upload_date":   "2013-10-15 14:08:29",
+			})
+		case "https://vimeo.com/1":
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	p := NewOEmbedProvider()
+	p.endpoints = map[string]string{"vimeo": server.URL}
+
+	if p.Supports("youtube") || !p.Supports("vimeo") {
+		t.Errorf("Supports doesn't follow the configured endpoints")
+	}
+
+	metadata, err := p.Fetch(context.Background(), Video{Platform: "vimeo", VideoID: "76979871", URL: "https://vimeo.com/76979871"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Metadata{
+		ThumbnailURL:    "https://i.vimeocdn.com/video/452001751-640.jpg",
+		DurationSeconds: 62,
+		PublishedAt:     time.Date(2013, 10, 15, 14, 8, 29, 0, time.UTC),
+	}
+	if !reflect.DeepEqual(metadata, want) {
+		t.Errorf("Fetch = %+v, want %+v", metadata, want)
+	}
+
+	if _, err := p.Fetch(context.Background(), Video{Platform: "vimeo", VideoID: "1", URL: "https://vimeo.com/1"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Fetch(removed) error = %v, want ErrNotFound", err)
+	}
+	if _, err := p.Fetch(context.Background(), Video{Platform: "vimeo", VideoID: "2", URL: "https://vimeo.com/2"}); err == nil || errors.Is(err, ErrNotFound) {
+		t.Errorf("Fetch(server error) error = %v, want a fetch error", err)
+	}
+}
+
+func TestChain(t *testing.T) {
+	published := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	api := &Fake{Platform: "youtube", Videos: map[string]*Metadata{
+		"aaaaaaaaaaa": {DurationSeconds: 60, PublishedAt: published, ChannelID: "UC1"},
+	}}
+	oembed := &Fake{Videos: map[string]*Metadata{
+		"aaaaaaaaaaa": {ThumbnailURL: "https://example.com/a.jpg", DurationSeconds: 30},
+		"bbbbbbbbbbb": {ThumbnailURL: "https://example.com/b.jpg"},
+	}}
+	chain := Chain{api, oembed}
+
+	// Fields the first provider doesn't know come from the next one
+	metadata, err := chain.Fetch(context.Background(), Video{Platform: "youtube", VideoID: "aaaaaaaaaaa"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Metadata{ThumbnailURL: "https://example.com/a.jpg", DurationSeconds: 60, PublishedAt: published, ChannelID: "UC1"}
+	if !reflect.DeepEqual(metadata, want) {
+		t.Errorf("Fetch = %+v, want %+v", metadata, want)
+	}
+
+	// A video the first provider reports gone is never looked up again
+	if _, err := chain.Fetch(context.Background(), Video{Platform: "youtube", VideoID: "bbbbbbbbbbb"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Fetch(removed) error = %v, want ErrNotFound", err)
+	}
+	if calls := len(oembed.Calls()); calls != 1 {
+		t.Errorf("second provider called %d times, want 1", calls)
+	}
+
+	// Providers that don't support the platform are skipped
+	metadata, err = chain.Fetch(context.Background(), Video{Platform: "vimeo", VideoID: "bbbbbbbbbbb"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metadata.ThumbnailURL != "https://example.com/b.jpg" {
+		t.Errorf("Fetch(vimeo) = %+v, want the second provider's thumbnail", metadata)
+	}
+
+	// Errors are only returned when no provider answers
+	api.Err = errors.New("quota exceeded")
+	if _, err := chain.Fetch(context.Background(), Video{Platform: "youtube", VideoID: "aaaaaaaaaaa"}); err != nil {
+		t.Errorf("Fetch with one failing provider error = %v, want nil", err)
+	}
+	oembed.Err = errors.New("unavailable")
+	if _, err := chain.Fetch(context.Background(), Video{Platform: "youtube", VideoID: "aaaaaaaaaaa"}); err == nil || errors.Is(err, ErrNotFound) {
+		t.Errorf("Fetch with every provider failing error = %v, want a fetch error", err)
+	}
+	if _, err := (Chain{}).Fetch(context.Background(), Video{Platform: "youtube"}); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Fetch on an empty chain error = %v, want ErrUnsupported", err)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	cases := map[int]time.Duration{
+		0:  time.Minute,
+		1:  2 * time.Minute,
+		3:  8 * time.Minute,
+		8:  256 * time.Minute,
+		9:  6 * time.Hour,
+		50: 6 * time.Hour,
+	}
+	for attempts, want := range cases {
+		if got := backoff(attempts); got != want {
+			t.Errorf("backoff(%d) = %s, want %s", attempts, got, want)
+		}
+	}
+}
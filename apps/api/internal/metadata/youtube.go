@@ -0,0 +1,138 @@
+package metadata
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// youtubeAPIURL is the YouTube Data API v3 base URL
+const youtubeAPIURL = "https://www.googleapis.com/youtube/v3"
+
+// isoDurationPattern matches the ISO 8601 durations the Data API uses (P1DT2H3M4S)
+var isoDurationPattern = regexp.MustCompile(`^P(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+// YouTubeProvider reads YouTube video metadata from the Data API
+type YouTubeProvider struct {
+	apiKey string
+	client *http.Client
+	apiURL string
+}
+
+// NewYouTubeProvider creates a Data API provider using apiKey
+func NewYouTubeProvider(apiKey string) *YouTubeProvider {
+	return &YouTubeProvider{
+		apiKey: apiKey,
+		client: &http.Client{Timeout: 10 * time.Second},
+		apiURL: youtubeAPIURL,
+	}
+}
+
+func (p *YouTubeProvider) Name() string {
+	return "youtube"
+}
+
+func (p *YouTubeProvider) Supports(platform string) bool {
+	return platform == "youtube"
+}
+
+// youtubeThumbnail is one size of a video's thumbnail
+type youtubeThumbnail struct {
+	URL string `json:"url"`
+}
+
+// youtubeVideosResponse is the part of a videos.list response that is used
+type youtubeVideosResponse struct {
+	Items []struct {
+		Snippet struct {
+			PublishedAt time.Time `json:"publishedAt"`
+			ChannelID   string    `json:"channelId"`
+			Thumbnails  struct {
+				Maxres   *youtubeThumbnail `json:"maxres"`
+				Standard *youtubeThumbnail `json:"standard"`
+				High     *youtubeThumbnail `json:"high"`
+				Medium   *youtubeThumbnail `json:"medium"`
+				Default  *youtubeThumbnail `json:"default"`
+			} `json:"thumbnails"`
+		} `json:"snippet"`
+		ContentDetails struct {
+			Duration string `json:"duration"`
+		} `json:"contentDetails"`
+	} `json:"items"`
+}
+
+func (p *YouTubeProvider) Fetch(ctx context.Context, video Video) (*Metadata, error) {
+	query := url.Values{
+		"part": {"snippet,contentDetails"},
+		"id":   {video.VideoID},
+		"key":  {p.apiKey},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.apiURL+"/videos?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("YouTube API returned %s", resp.Status)
+	}
+
+	var body youtubeVideosResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(&body); err != nil {
+		return nil, fmt.Errorf("invalid YouTube API response: %w", err)
+	}
+	// Deleted and private videos are simply left out of the response
+	if len(body.Items) == 0 {
+		return nil, ErrNotFound
+	}
+
+	item := body.Items[0]
+	metadata := &Metadata{
+		PublishedAt:     item.Snippet.PublishedAt,
+		ChannelID:       item.Snippet.ChannelID,
+		DurationSeconds: parseISODuration(item.ContentDetails.Duration),
+	}
+
+	thumbnails := item.Snippet.Thumbnails
+	for _, thumbnail := range []*youtubeThumbnail{thumbnails.Maxres, thumbnails.Standard, thumbnails.High, thumbnails.Medium, thumbnails.Default} {
+		if thumbnail != nil && thumbnail.URL != "" {
+			metadata.ThumbnailURL = thumbnail.URL
+			break
+		}
+	}
+
+	return metadata, nil
+}
+
+// parseISODuration converts an ISO 8601 duration like PT1H2M3S to seconds
+// It returns 0 for anything else, including the P0D of live streams
+func parseISODuration(value string) int {
+	match := isoDurationPattern.FindStringSubmatch(value)
+	if match == nil {
+		return 0
+	}
+
+	seconds := 0
+	for i, unit := range []int{86400, 3600, 60, 1} {
+		if match[i+1] == "" {
+			continue
+		}
+		n, err := strconv.Atoi(match[i+1])
+		if err != nil {
+			return 0
+		}
+		seconds += n * unit
+	}
+	return seconds
+}
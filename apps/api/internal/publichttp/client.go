@@ -0,0 +1,45 @@
+// Package publichttp provides HTTP clients for fetching URLs that come from users
+package publichttp
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// ErrNotPublic is returned when a request would connect to an address that isn't public
+var ErrNotPublic = errors.New("not a public address")
+
+// NewClient creates an HTTP client that only connects to public addresses
+// URLs that come from users mustn't be able to point the API at hosts on its own network.
+// Addresses are checked when connecting, so redirects and DNS answers are covered too.
+func NewClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 5 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if !IsPublicIP(net.ParseIP(host)) {
+				return fmt.Errorf("%w: %s", ErrNotPublic, host)
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+	}
+}
+
+// IsPublicIP reports whether ip is a global unicast address outside the private ranges
+func IsPublicIP(ip net.IP) bool {
+	return ip != nil && ip.IsGlobalUnicast() && !ip.IsPrivate()
+}
@@ -0,0 +1,323 @@
+package scripts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/ekkolyth/ekko-playlist/api/internal/db"
+	"github.com/ekkolyth/ekko-playlist/api/internal/logging"
+	"github.com/ekkolyth/ekko-playlist/api/internal/lua"
+)
+
+// ErrInvalidName is returned for script names that lua.ValidScriptName rejects
+var ErrInvalidName = errors.New("invalid script name")
+
+// ErrNotFound is returned when a script version does not exist
+var ErrNotFound = errors.New("script version not found")
+
+// ValidationError is returned when a script fails to compile or self-test
+type ValidationError struct {
+	Err error
+}
+
+func (e *ValidationError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// DryRunResult compares the current and candidate normalization of one URL
+type DryRunResult struct {
+	URL       string                 `json:"url"`
+	Current   map[string]interface{} `json:"current"`
+	Candidate map[string]interface{} `json:"candidate"`
+	Changed   bool                   `json:"changed"`
+}
+
+// Service stores versioned Lua scripts in the database and keeps the Lua service in sync
+// with the active versions
+type Service struct {
+	dbService  *db.Service
+	luaService *lua.Service
+
+	// mu serializes changes to the active scripts; fingerprint identifies the applied set
+	mu          sync.Mutex
+	fingerprint string
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewService creates a new scripts service
+func NewService(dbService *db.Service, luaService *lua.Service) *Service {
+	return &Service{
+		dbService:  dbService,
+		luaService: luaService,
+	}
+}
+
+// Load applies the active database scripts to the Lua service
+func (s *Service) Load(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	active, err := s.dbService.Queries.ListActiveLuaScripts(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list active scripts: %w", err)
+	}
+	return s.apply(active)
+}
+
+// Watch periodically reloads the active scripts, picking up activations made by other API instances
+func (s *Service) Watch(interval time.Duration) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			loadCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+			if err := s.Load(loadCtx); err != nil && ctx.Err() == nil {
+				logging.Info("Scripts: Failed to reload database scripts: %s", err.Error())
+			}
+			cancel()
+		}
+	}()
+}
+
+// Stop stops watching for script changes
+func (s *Service) Stop() {
+	if s.cancel == nil {
+		return
+	}
+	s.cancel()
+	s.wg.Wait()
+}
+
+// Versions lists a script's stored versions, newest first
+func (s *Service) Versions(ctx context.Context, name string) ([]*db.LuaScript, error) {
+	if !lua.ValidScriptName(name) {
+		return nil, ErrInvalidName
+	}
+	return s.dbService.Queries.ListLuaScriptVersions(ctx, name)
+}
+
+// ActiveVersions maps script names to their active database version
+func (s *Service) ActiveVersions(ctx context.Context) (map[string]int32, error) {
+	active, err := s.dbService.Queries.ListActiveLuaScripts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make(map[string]int32, len(active))
+	for _, script := range active {
+		versions[script.Name] = script.Version
+	}
+	return versions, nil
+}
+
+// CreateVersion validates source and stores it as the script's next version, optionally activating it
+func (s *Service) CreateVersion(ctx context.Context, name, source, userID string, activate bool) (*db.LuaScript, error) {
+	if !lua.ValidScriptName(name) {
+		return nil, ErrInvalidName
+	}
+
+	preview, err := s.luaService.WithScript(name, source)
+	if err != nil {
+		return nil, &ValidationError{Err: err}
+	}
+	preview.Close()
+
+	var createdBy pgtype.UUID
+	if err := createdBy.Scan(userID); err != nil {
+		return nil, fmt.Errorf("invalid user ID: %w", err)
+	}
+
+	script, err := s.dbService.Queries.CreateLuaScriptVersion(ctx, &db.CreateLuaScriptVersionParams{
+		Name:      name,
+		Source:    source,
+		CreatedBy: createdBy,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to save script: %w", err)
+	}
+	logging.Info("Scripts: Saved %s version %d", name, script.Version)
+
+	if !activate {
+		return script, nil
+	}
+	return s.Activate(ctx, name, script.Version)
+}
+
+// Activate makes a stored version the script's active version
+// The change is only committed if the resulting set of scripts loads successfully
+func (s *Service) Activate(ctx context.Context, name string, version int32) (*db.LuaScript, error) {
+	if !lua.ValidScriptName(name) {
+		return nil, ErrInvalidName
+	}
+
+	var activated *db.LuaScript
+	err := s.change(ctx, func(q *db.Queries) error {
+		if err := q.DeactivateLuaScript(ctx, name); err != nil {
+			return err
+		}
+
+		script, err := q.ActivateLuaScriptVersion(ctx, &db.ActivateLuaScriptVersionParams{
+			Name:    name,
+			Version: version,
+		})
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrNotFound
+			}
+			return err
+		}
+		activated = script
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logging.Info("Scripts: Activated %s version %d", name, version)
+	return activated, nil
+}
+
+// Deactivate reverts a script to its built-in version
+func (s *Service) Deactivate(ctx context.Context, name string) error {
+	if !lua.ValidScriptName(name) {
+		return ErrInvalidName
+	}
+
+	err := s.change(ctx, func(q *db.Queries) error {
+		return q.DeactivateLuaScript(ctx, name)
+	})
+	if err != nil {
+		return err
+	}
+
+	logging.Info("Scripts: Deactivated %s", name)
+	return nil
+}
+
+// DryRun normalizes urls with the current scripts and with source in place of the named script
+func (s *Service) DryRun(ctx context.Context, name, source string, urls []string) ([]DryRunResult, error) {
+	if !lua.ValidScriptName(name) {
+		return nil, ErrInvalidName
+	}
+
+	preview, err := s.luaService.WithScript(name, source)
+	if err != nil {
+		return nil, &ValidationError{Err: err}
+	}
+	defer preview.Close()
+
+	results := make([]DryRunResult, 0, len(urls))
+	for _, url := range urls {
+		current := normalize(ctx, s.luaService, url)
+		candidate := normalize(ctx, preview, url)
+		results = append(results, DryRunResult{
+			URL:       url,
+			Current:   current,
+			Candidate: candidate,
+			Changed:   !reflect.DeepEqual(current, candidate),
+		})
+	}
+
+	return results, nil
+}
+
+// change runs fn in a transaction and applies the resulting active scripts before committing
+// If the scripts fail to load the transaction is rolled back and a ValidationError returned
+func (s *Service) change(ctx context.Context, fn func(q *db.Queries) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	applied := false
+	err := s.dbService.DB.WithTx(ctx, func(q *db.Queries) error {
+		if err := fn(q); err != nil {
+			return err
+		}
+
+		active, err := q.ListActiveLuaScripts(ctx)
+		if err != nil {
+			return err
+		}
+		if err := s.apply(active); err != nil {
+			return &ValidationError{Err: err}
+		}
+		applied = true
+		return nil
+	})
+
+	if err != nil && applied {
+		// The scripts were applied but the transaction failed; go back to what is stored
+		reloadCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+		defer cancel()
+		if active, listErr := s.dbService.Queries.ListActiveLuaScripts(reloadCtx); listErr == nil {
+			if applyErr := s.apply(active); applyErr != nil {
+				logging.Info("Scripts: Failed to restore database scripts: %s", applyErr.Error())
+			}
+		}
+	}
+	return err
+}
+
+// apply loads the given active scripts into the Lua service unless they are already loaded
+// The caller must hold mu
+func (s *Service) apply(active []*db.LuaScript) error {
+	parts := make([]string, 0, len(active))
+	sources := make(map[string]string, len(active))
+	for _, script := range active {
+		parts = append(parts, fmt.Sprintf("%s@%d", script.Name, script.Version))
+		sources[script.Name] = script.Source
+	}
+	sort.Strings(parts)
+	fingerprint := strings.Join(parts, ",")
+
+	if fingerprint == s.fingerprint {
+		return nil
+	}
+
+	if err := s.luaService.SetDatabaseScripts(sources); err != nil {
+		return err
+	}
+	s.fingerprint = fingerprint
+	if len(active) > 0 {
+		logging.Info("Scripts: Loaded %d database scripts (%s)", len(active), fingerprint)
+	}
+	return nil
+}
+
+func normalize(ctx context.Context, luaService *lua.Service, url string) map[string]interface{} {
+	result, err := luaService.NormalizeURL(ctx, url)
+	if err != nil {
+		return map[string]interface{}{
+			"isValid": false,
+			"error":   err.Error(),
+		}
+	}
+	return result
+}
@@ -0,0 +1,259 @@
+package thumbnails
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ekkolyth/ekko-playlist/api/internal/api/upload"
+	"github.com/ekkolyth/ekko-playlist/api/internal/db"
+	"github.com/ekkolyth/ekko-playlist/api/internal/logging"
+	"github.com/ekkolyth/ekko-playlist/api/internal/publichttp"
+)
+
+const (
+	// cachePollInterval is how often the cacher looks for thumbnails to fetch
+	cachePollInterval = 10 * time.Second
+	// cacheBatchSize is how many videos are claimed at a time
+	cacheBatchSize = 20
+	// cacheLease is how long a claimed video is hidden from other cachers; a video whose cacher
+	// died is picked up again once it runs out
+	cacheLease = 5 * time.Minute
+	// cacheFetchTimeout bounds the download of one thumbnail
+	cacheFetchTimeout = 15 * time.Second
+	// cacheMaxAttempts is how many failed downloads are retried before a thumbnail is given up on
+	cacheMaxAttempts = 5
+	// cacheBaseBackoff and cacheMaxBackoff bound the delay between retries
+	cacheBaseBackoff = time.Minute
+	cacheMaxBackoff  = 6 * time.Hour
+	// sweepInterval is how often thumbnails of deleted videos are removed
+	sweepInterval = 6 * time.Hour
+	// sweepGracePeriod is how old an unused thumbnail has to be before it is removed
+	sweepGracePeriod = time.Hour
+)
+
+// errGone is returned for thumbnails that can't be downloaded and won't be later either
+var errGone = errors.New("thumbnail is gone")
+
+// Cacher downloads the thumbnails of saved videos into the store in the background, and
+// removes the thumbnails no video refers to any more
+type Cacher struct {
+	dbService *db.Service
+	store     *Store
+	client    *http.Client
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+}
+
+// NewCacher creates a cacher that saves thumbnails to store
+func NewCacher(dbService *db.Service, store *Store) *Cacher {
+	return &Cacher{
+		dbService: dbService,
+		store:     store,
+		client:    publichttp.NewClient(cacheFetchTimeout),
+	}
+}
+
+// Start starts caching thumbnails
+func (c *Cacher) Start(ctx context.Context) {
+	ctx, c.cancel = context.WithCancel(ctx)
+
+	c.wg.Add(1)
+	go c.run(ctx)
+	logging.Info("Thumbnails: Started cacher")
+}
+
+// Stop signals the cacher to stop and waits for the current download to finish
+// Videos of an interrupted batch are retried once their lease runs out.
+func (c *Cacher) Stop(ctx context.Context) error {
+	if c.cancel == nil {
+		return nil
+	}
+	c.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		c.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		logging.Info("Thumbnails: Cacher stopped")
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("timed out waiting for the thumbnail cacher: %w", ctx.Err())
+	}
+}
+
+func (c *Cacher) run(ctx context.Context) {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(cachePollInterval)
+	defer ticker.Stop()
+	sweepTicker := time.NewTicker(sweepInterval)
+	defer sweepTicker.Stop()
+
+	c.sweep(ctx)
+	for {
+		// Keep claiming until there is nothing left that is due
+		for ctx.Err() == nil {
+			videos, err := c.dbService.Queries.ClaimVideosForThumbnailCache(ctx, &db.ClaimVideosForThumbnailCacheParams{
+				Limit:        cacheBatchSize,
+				LeaseSeconds: int32(cacheLease / time.Second),
+			})
+			if err != nil {
+				if ctx.Err() == nil {
+					logging.Info("Thumbnails: Failed to claim videos: %s", err.Error())
+				}
+				break
+			}
+			if len(videos) == 0 {
+				break
+			}
+
+			// Videos with the same thumbnail are saved together
+			cached := make(map[string]bool, len(videos))
+			for _, video := range videos {
+				if ctx.Err() != nil {
+					return
+				}
+				if video.ThumbnailUrl == nil || cached[*video.ThumbnailUrl] {
+					continue
+				}
+				cached[*video.ThumbnailUrl] = true
+				c.cache(ctx, video)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-sweepTicker.C:
+			c.sweep(ctx)
+		case <-ticker.C:
+		}
+	}
+}
+
+// cache downloads a claimed video's thumbnail and records the result on every video with the
+// same thumbnail URL
+func (c *Cacher) cache(ctx context.Context, video *db.ClaimVideosForThumbnailCacheRow) {
+	fetchCtx, cancel := context.WithTimeout(ctx, cacheFetchTimeout)
+	hash, err := c.fetch(fetchCtx, *video.ThumbnailUrl)
+	cancel()
+	// A download cut short by shutdown is retried when the lease runs out
+	if ctx.Err() != nil {
+		return
+	}
+
+	saveCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	switch {
+	case err == nil:
+		err = c.dbService.Queries.SaveVideoThumbnail(saveCtx, &db.SaveVideoThumbnailParams{
+			ThumbnailUrl:  video.ThumbnailUrl,
+			ThumbnailHash: &hash,
+		})
+	case errors.Is(err, errGone), errors.Is(err, ErrInvalidImage), video.ThumbnailCacheAttempts+1 >= cacheMaxAttempts:
+		// Recording no hash stops the URL from being fetched again; videos keep linking to it
+		logging.Info("Thumbnails: Giving up on %s for video %d: %s", *video.ThumbnailUrl, video.ID, err.Error())
+		err = c.dbService.Queries.SaveVideoThumbnail(saveCtx, &db.SaveVideoThumbnailParams{
+			ThumbnailUrl: video.ThumbnailUrl,
+		})
+	default:
+		logging.Info("Thumbnails: Failed to fetch %s for video %d: %s", *video.ThumbnailUrl, video.ID, err.Error())
+		err = c.dbService.Queries.RetryVideoThumbnail(saveCtx, &db.RetryVideoThumbnailParams{
+			ID:           video.ID,
+			DelaySeconds: int32(backoff(int(video.ThumbnailCacheAttempts)) / time.Second),
+		})
+	}
+	if err != nil {
+		logging.Info("Thumbnails: Failed to record thumbnail for video %d: %s", video.ID, err.Error())
+	}
+}
+
+// fetch downloads a thumbnail and saves it to the store, returning its content hash
+func (c *Cacher) fetch(ctx context.Context, thumbnailURL string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, thumbnailURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", errGone, err.Error())
+	}
+	if req.URL.Scheme != "https" && req.URL.Scheme != "http" {
+		return "", fmt.Errorf("%w: unsupported scheme %q", errGone, req.URL.Scheme)
+	}
+
+	resp, err := c.client.Do(req)
+	if errors.Is(err, publichttp.ErrNotPublic) {
+		return "", fmt.Errorf("%w: %s", errGone, err.Error())
+	}
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound, http.StatusGone, http.StatusForbidden, http.StatusUnauthorized:
+		return "", fmt.Errorf("%w: %s", errGone, resp.Status)
+	default:
+		return "", fmt.Errorf("thumbnail host returned %s", resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, upload.MaxFileSize+1))
+	if err != nil {
+		return "", err
+	}
+	if len(data) > upload.MaxFileSize {
+		return "", fmt.Errorf("%w: larger than %d bytes", ErrInvalidImage, upload.MaxFileSize)
+	}
+	if contentType := http.DetectContentType(data); !strings.HasPrefix(contentType, "image/") {
+		return "", fmt.Errorf("%w: got %s", ErrInvalidImage, contentType)
+	}
+
+	return c.store.Save(data)
+}
+
+// sweep removes the thumbnails no video refers to any more
+func (c *Cacher) sweep(ctx context.Context) {
+	deleted, err := c.store.Sweep(ctx, time.Now().Add(-sweepGracePeriod), c.referenced)
+	if err != nil {
+		if ctx.Err() == nil {
+			logging.Info("Thumbnails: Failed to remove unused thumbnails: %s", err.Error())
+		}
+		return
+	}
+	if deleted > 0 {
+		logging.Info("Thumbnails: Removed %d unused thumbnail files", deleted)
+	}
+}
+
+// referenced returns which of the hashes a video still refers to
+func (c *Cacher) referenced(ctx context.Context, hashes []string) (map[string]bool, error) {
+	rows, err := c.dbService.Queries.ListReferencedThumbnailHashes(ctx, hashes)
+	if err != nil {
+		return nil, err
+	}
+	inUse := make(map[string]bool, len(rows))
+	for _, hash := range rows {
+		if hash != nil {
+			inUse[*hash] = true
+		}
+	}
+	return inUse, nil
+}
+
+// backoff returns how long to wait before retrying a thumbnail that has already failed attempts times
+func backoff(attempts int) time.Duration {
+	delay := cacheBaseBackoff
+	for i := 0; i < attempts && delay < cacheMaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, cacheMaxBackoff)
+}
@@ -0,0 +1,25 @@
+package thumbnails
+
+import (
+	"image"
+	"image/color"
+	_ "image/gif"
+	_ "image/png"
+	"math"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// resize scales img down to width, keeping its aspect ratio, onto a white background
+// Narrower images keep their size; JPEG has no transparency, so transparent pixels become white.
+func resize(img image.Image, width int) image.Image {
+	bounds := img.Bounds()
+	width = min(width, bounds.Dx())
+	height := max(1, int(math.Round(float64(bounds.Dy())*float64(width)/float64(bounds.Dx()))))
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, draw.Over, nil)
+	return dst
+}
@@ -0,0 +1,234 @@
+package thumbnails
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ekkolyth/ekko-playlist/api/internal/api/upload"
+)
+
+// Size is a standard thumbnail size; images are scaled down to Width, keeping their aspect ratio
+type Size struct {
+	Name  string
+	Width int
+}
+
+// Sizes are the sizes every thumbnail is cached in
+var Sizes = []Size{
+	{Name: "small", Width: 320},
+	{Name: "medium", Width: 640},
+}
+
+const (
+	// maxImagePixels caps the dimensions of a thumbnail that is decoded
+	maxImagePixels = 4096 * 4096
+	// jpegQuality is the quality cached thumbnails are encoded with
+	jpegQuality = 85
+	// tempPrefix starts the names of files that are still being written
+	tempPrefix = ".tmp-"
+	// sweepBatchSize is how many hashes are looked up at a time while sweeping
+	sweepBatchSize = 1000
+)
+
+// filenamePattern matches the names of cached thumbnails: the content hash and the size
+var filenamePattern = regexp.MustCompile(`^([0-9a-f]{64})-([a-z]+)\.jpg$`)
+
+// ErrInvalidImage is returned for downloads that can't be decoded as an image
+var ErrInvalidImage = errors.New("invalid image")
+
+// Dir returns the directory thumbnails are cached in, inside the upload directory
+func Dir() string {
+	return filepath.Join(upload.GetUploadDir(), "thumbnails")
+}
+
+// Filename returns the file name of a thumbnail's size
+func Filename(hash string, size Size) string {
+	return hash + "-" + size.Name + ".jpg"
+}
+
+// URLs returns where a cached thumbnail's sizes are served, keyed by size name
+func URLs(hash string) map[string]string {
+	urls := make(map[string]string, len(Sizes))
+	for _, size := range Sizes {
+		urls[size.Name] = "/api/uploads/thumbnails/" + Filename(hash, size)
+	}
+	return urls
+}
+
+// ParseFilename returns the content hash of a cached thumbnail's file name
+func ParseFilename(filename string) (string, bool) {
+	match := filenamePattern.FindStringSubmatch(filename)
+	if match == nil {
+		return "", false
+	}
+	for _, size := range Sizes {
+		if size.Name == match[2] {
+			return match[1], true
+		}
+	}
+	return "", false
+}
+
+// Store keeps cached thumbnails in a directory, named by the hash of the downloaded image so
+// videos with the same thumbnail share the files
+type Store struct {
+	dir string
+}
+
+// NewStore creates a store in dir, which is created when the first thumbnail is saved
+func NewStore(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// Path returns the path of a cached thumbnail file
+func (s *Store) Path(filename string) string {
+	return filepath.Join(s.dir, filename)
+}
+
+// Save resizes an image to every size and stores it, returning its content hash
+// Images that are already stored aren't decoded again.
+func (s *Store) Save(data []byte) (string, error) {
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	if s.has(hash) {
+		return hash, nil
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrInvalidImage, err.Error())
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > maxImagePixels {
+		return "", fmt.Errorf("%w: %dx%d is too large", ErrInvalidImage, config.Width, config.Height)
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrInvalidImage, err.Error())
+	}
+
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create thumbnail directory: %w", err)
+	}
+	for _, size := range Sizes {
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, resize(img, size.Width), &jpeg.Options{Quality: jpegQuality}); err != nil {
+			return "", fmt.Errorf("failed to encode %s thumbnail: %w", size.Name, err)
+		}
+		if err := s.write(Filename(hash, size), buf.Bytes()); err != nil {
+			return "", err
+		}
+	}
+	return hash, nil
+}
+
+// has reports whether every size of the thumbnail is stored
+func (s *Store) has(hash string) bool {
+	for _, size := range Sizes {
+		if _, err := os.Stat(s.Path(Filename(hash, size))); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// write writes a file through a temporary file, so a thumbnail is never served half written
+func (s *Store) write(filename string, data []byte) error {
+	file, err := os.CreateTemp(s.dir, tempPrefix+"*")
+	if err != nil {
+		return fmt.Errorf("failed to save thumbnail: %w", err)
+	}
+	defer os.Remove(file.Name())
+
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to save thumbnail: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to save thumbnail: %w", err)
+	}
+	if err := os.Chmod(file.Name(), 0644); err != nil {
+		return fmt.Errorf("failed to save thumbnail: %w", err)
+	}
+	if err := os.Rename(file.Name(), s.Path(filename)); err != nil {
+		return fmt.Errorf("failed to save thumbnail: %w", err)
+	}
+	return nil
+}
+
+// Sweep deletes the thumbnails that no video refers to any more, along with abandoned temporary
+// files, and returns how many files it deleted
+// referenced reports which of the given hashes are still in use. Files modified after
+// olderThan are kept, so a thumbnail that was just saved isn't deleted before its video refers to it.
+func (s *Store) Sweep(ctx context.Context, olderThan time.Time, referenced func(ctx context.Context, hashes []string) (map[string]bool, error)) (int, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	filesByHash := make(map[string][]string)
+	var abandoned []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || info.ModTime().After(olderThan) {
+			continue
+		}
+		if strings.HasPrefix(entry.Name(), tempPrefix) {
+			abandoned = append(abandoned, entry.Name())
+			continue
+		}
+		if hash, ok := ParseFilename(entry.Name()); ok {
+			filesByHash[hash] = append(filesByHash[hash], entry.Name())
+		}
+	}
+
+	hashes := make([]string, 0, len(filesByHash))
+	for hash := range filesByHash {
+		hashes = append(hashes, hash)
+	}
+	inUse := make(map[string]bool)
+	for start := 0; start < len(hashes); start += sweepBatchSize {
+		batch, err := referenced(ctx, hashes[start:min(start+sweepBatchSize, len(hashes))])
+		if err != nil {
+			return 0, err
+		}
+		for hash := range batch {
+			inUse[hash] = true
+		}
+	}
+
+	deleted := 0
+	remove := func(filename string) {
+		if err := os.Remove(s.Path(filename)); err == nil || errors.Is(err, os.ErrNotExist) {
+			deleted++
+		}
+	}
+	for _, filename := range abandoned {
+		remove(filename)
+	}
+	for hash, filenames := range filesByHash {
+		if inUse[hash] {
+			continue
+		}
+		for _, filename := range filenames {
+			remove(filename)
+		}
+	}
+	return deleted, nil
+}
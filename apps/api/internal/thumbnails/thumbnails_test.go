@@ -0,0 +1,209 @@
+package thumbnails
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ekkolyth/ekko-playlist/api/internal/publichttp"
+)
+
+// testPNG encodes a solid image of the given size
+func testPNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 200, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// imageSize decodes a stored thumbnail's dimensions
+func imageSize(t *testing.T, path string) (int, int) {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	config, err := jpeg.DecodeConfig(file)
+	if err != nil {
+		t.Fatalf("%s is not a JPEG: %v", path, err)
+	}
+	return config.Width, config.Height
+}
+
+func TestParseFilename(t *testing.T) {
+	hash := strings.Repeat("ab", 32)
+	cases := map[string]bool{
+		hash + "-small.jpg":         true,
+		hash + "-medium.jpg":        true,
+		hash + "-large.jpg":         false,
+		hash + "-small.png":         false,
+		hash[:63] + "-small.jpg":    false,
+		"../" + hash + "-small.jpg": false,
+		"user-1-abcdef12.jpg":       false,
+	}
+	for filename, want := range cases {
+		got, ok := ParseFilename(filename)
+		if ok != want || (ok && got != hash) {
+			t.Errorf("ParseFilename(%q) = %q, %v, want ok %v", filename, got, ok, want)
+		}
+	}
+}
+
+func TestStoreSave(t *testing.T) {
+	store := NewStore(filepath.Join(t.TempDir(), "thumbnails"))
+
+	hash, err := store.Save(testPNG(t, 1280, 720))
+	if err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	want := map[string][2]int{"small": {320, 180}, "medium": {640, 360}}
+	for _, size := range Sizes {
+		width, height := imageSize(t, store.Path(Filename(hash, size)))
+		if [2]int{width, height} != want[size.Name] {
+			t.Errorf("%s is %dx%d, want %v", size.Name, width, height, want[size.Name])
+		}
+	}
+
+	again, err := store.Save(testPNG(t, 1280, 720))
+	if err != nil || again != hash {
+		t.Errorf("Save of the same image = %q, %v, want %q", again, err, hash)
+	}
+
+	// Images narrower than a size aren't scaled up
+	small, err := store.Save(testPNG(t, 120, 90))
+	if err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if width, height := imageSize(t, store.Path(Filename(small, Sizes[1]))); width != 120 || height != 90 {
+		t.Errorf("medium of a 120x90 image is %dx%d, want 120x90", width, height)
+	}
+
+	if _, err := store.Save([]byte("<html>not an image</html>")); !errors.Is(err, ErrInvalidImage) {
+		t.Errorf("Save of HTML = %v, want ErrInvalidImage", err)
+	}
+}
+
+func TestStoreSweep(t *testing.T) {
+	dir := t.TempDir()
+	store := NewStore(dir)
+	kept := strings.Repeat("a", 64)
+	unused := strings.Repeat("b", 64)
+	recent := strings.Repeat("c", 64)
+
+	old := time.Now().Add(-2 * time.Hour)
+	for _, name := range []string{
+		Filename(kept, Sizes[0]), Filename(kept, Sizes[1]),
+		Filename(unused, Sizes[0]), Filename(unused, Sizes[1]),
+		tempPrefix + "123", "user-1-abcdef12.jpg",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(filepath.Join(dir, name), old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, Filename(recent, Sizes[0])), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var asked []string
+	deleted, err := store.Sweep(context.Background(), time.Now().Add(-time.Hour), func(ctx context.Context, hashes []string) (map[string]bool, error) {
+		asked = append(asked, hashes...)
+		return map[string]bool{kept: true}, nil
+	})
+	if err != nil {
+		t.Fatalf("Sweep failed: %v", err)
+	}
+	if deleted != 3 {
+		t.Errorf("Sweep deleted %d files, want 3", deleted)
+	}
+	if len(asked) != 2 {
+		t.Errorf("Sweep asked about %v, want the two old hashes", asked)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var remaining []string
+	for _, entry := range entries {
+		remaining = append(remaining, entry.Name())
+	}
+	want := []string{Filename(kept, Sizes[1]), Filename(kept, Sizes[0]), Filename(recent, Sizes[0]), "user-1-abcdef12.jpg"}
+	if strings.Join(remaining, ",") != strings.Join(want, ",") {
+		t.Errorf("remaining files = %v, want %v", remaining, want)
+	}
+
+	// A missing directory has nothing to sweep
+	if _, err := NewStore(filepath.Join(dir, "missing")).Sweep(context.Background(), time.Now(), nil); err != nil {
+		t.Errorf("Sweep of a missing directory failed: %v", err)
+	}
+}
+
+func TestFetch(t *testing.T) {
+	thumbnail := testPNG(t, 640, 360)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/hqdefault.png":
+			w.Write(thumbnail)
+		case "/page.html":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte("<!DOCTYPE html><html></html>"))
+		case "/busy.png":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	cacher := &Cacher{store: NewStore(t.TempDir()), client: server.Client()}
+
+	hash, err := cacher.fetch(context.Background(), server.URL+"/hqdefault.png")
+	if err != nil {
+		t.Fatalf("fetch failed: %v", err)
+	}
+	if _, err := os.Stat(cacher.store.Path(Filename(hash, Sizes[0]))); err != nil {
+		t.Errorf("small thumbnail wasn't stored: %v", err)
+	}
+
+	if _, err := cacher.fetch(context.Background(), server.URL+"/missing.png"); !errors.Is(err, errGone) {
+		t.Errorf("fetch of a missing thumbnail = %v, want errGone", err)
+	}
+	if _, err := cacher.fetch(context.Background(), server.URL+"/page.html"); !errors.Is(err, ErrInvalidImage) {
+		t.Errorf("fetch of a page = %v, want ErrInvalidImage", err)
+	}
+	if _, err := cacher.fetch(context.Background(), server.URL+"/busy.png"); err == nil || errors.Is(err, errGone) {
+		t.Errorf("fetch from a busy host = %v, want a retryable error", err)
+	}
+	if _, err := cacher.fetch(context.Background(), "file:///etc/passwd"); !errors.Is(err, errGone) {
+		t.Errorf("fetch of a file URL = %v, want errGone", err)
+	}
+
+	// The default client refuses to connect to the API's own network
+	cacher.client = publichttp.NewClient(cacheFetchTimeout)
+	if _, err := cacher.fetch(context.Background(), server.URL+"/hqdefault.png"); !errors.Is(err, errGone) {
+		t.Errorf("fetch from a loopback address = %v, want errGone", err)
+	}
+}
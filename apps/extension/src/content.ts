@@ -1,5 +1,14 @@
 // Inline types and YouTube URL parser to avoid CommonJS module issues
-interface VideoInfo {
+// Optional details read off a video's page; the API validates them and fills in what a saved video is missing
+interface VideoDetails {
+    durationSeconds?: number;
+    thumbnailUrl?: string;
+    viewCount?: number;
+    uploadDate?: string;
+    channelHandle?: string;
+}
+
+interface VideoInfo extends VideoDetails {
     channel: string;
     url: string;
     title: string;
@@ -23,6 +32,7 @@ interface CurrentVideoInfoResponse {
     type: "CURRENT_VIDEO_INFO";
     title: string;
     channel: string;
+    details?: VideoDetails;
     error?: string;
 }
 
@@ -602,6 +612,54 @@ async function extractVideoInfo(): Promise<VideoInfo[]> {
     return videos;
 }
 
+// Reads the details of the video playing on a watch page
+// The page's microdata is only used when it describes the current video, since YouTube
+// navigates between videos without reloading it.
+function extractVideoDetails(): VideoDetails {
+    const details: VideoDetails = {};
+    const currentVideoId = new URLSearchParams(window.location.search).get("v");
+    const meta = (selector: string): string | undefined =>
+        document.querySelector<HTMLMetaElement>(selector)?.content?.trim() || undefined;
+
+    // Skip the duration while an ad is playing, it would be the ad's
+    const player = document.querySelector("video") as HTMLVideoElement | null;
+    if (
+        player &&
+        Number.isFinite(player.duration) &&
+        player.duration > 0 &&
+        !document.querySelector(".ad-showing")
+    ) {
+        details.durationSeconds = player.duration;
+    }
+
+    if (currentVideoId && meta('meta[itemprop="identifier"]') === currentVideoId) {
+        details.thumbnailUrl = meta('meta[property="og:image"]');
+        details.uploadDate =
+            meta('meta[itemprop="uploadDate"]') || meta('meta[itemprop="datePublished"]');
+        const views = Number(
+            meta('meta[itemprop="interactionCount"]') ||
+                meta('meta[itemprop="userInteractionCount"]'),
+        );
+        if (Number.isFinite(views) && views > 0) {
+            details.viewCount = views;
+        }
+    }
+
+    const ownerLink = document.querySelector(
+        'ytd-video-owner-renderer a[href*="/@"]',
+    ) as HTMLAnchorElement | null;
+    const handleMatch = ownerLink?.getAttribute("href")?.match(/\/(@[^/?#]+)/);
+    if (handleMatch) {
+        try {
+            details.channelHandle = decodeURIComponent(handleMatch[1]);
+        } catch {
+            // Malformed escape, leave the handle out
+        }
+    }
+
+    return details;
+}
+
 function getCurrentVideoInfo(): CurrentVideoInfoResponse {
     try {
         // Try to get video title from the page
@@ -662,6 +720,7 @@ function getCurrentVideoInfo(): CurrentVideoInfoResponse {
             type: "CURRENT_VIDEO_INFO",
             title: videoTitle,
             channel: channelName,
+            details: extractVideoDetails(),
         };
     } catch (error) {
         const errorMessage =
@@ -7,7 +7,16 @@ interface AuthTokenResponse {
     sessionId?: string;
     error?: string;
 }
-interface VideoInfo {
+// Optional details read off a video's page; the API validates them and fills in what a saved video is missing
+interface VideoDetails {
+    durationSeconds?: number;
+    thumbnailUrl?: string;
+    viewCount?: number;
+    uploadDate?: string;
+    channelHandle?: string;
+}
+
+interface VideoInfo extends VideoDetails {
     channel: string;
     url: string;
     title: string;
@@ -31,23 +40,35 @@ interface CurrentVideoInfoResponse {
     type: "CURRENT_VIDEO_INFO";
     title: string;
     channel: string;
+    details?: VideoDetails;
     error?: string;
 }
 
-interface ProcessPlaylistResponse {
-    processed: Array<{
+// Outcome of ingesting a single video
+type IngestStatus = "created" | "duplicate" | "invalid" | "error";
+
+interface ProcessJobResponse {
+    id: string;
+    status: "queued" | "running" | "completed" | "failed";
+    results: Array<{
         channel: string;
         originalUrl: string;
         normalizedUrl: string;
         title: string;
         isValid: boolean;
+        status?: IngestStatus;
+        id?: number;
         error?: string;
     }>;
     total: number;
-    valid: number;
-    invalid: number;
+    processed: number;
+    totals: Record<IngestStatus, number>;
+    error?: string;
 }
 
+// How often to poll a queued playlist job for progress
+const JOB_POLL_INTERVAL_MS = 1000;
+
 interface ProcessVideoResponse {
     processed: {
         channel: string;
@@ -55,6 +76,8 @@ interface ProcessVideoResponse {
         normalizedUrl: string;
         title: string;
         isValid: boolean;
+        status?: IngestStatus;
+        id?: number;
         error?: string;
     };
 }
@@ -433,7 +456,7 @@ async function handleSaveConfig(): Promise<void> {
 
 async function sendPlaylistToAPI(
     videos: VideoInfo[],
-): Promise<ProcessPlaylistResponse> {
+): Promise<ProcessJobResponse> {
     // Get authentication token and server URL
     const token = await getStoredToken();
     const apiUrl = await getApiBaseUrl();
@@ -472,8 +495,38 @@ async function sendPlaylistToAPI(
             );
         }
 
-        const result = await response.json();
-        return result;
+        // The playlist is processed in the background; poll the job until it finishes
+        let job: ProcessJobResponse = await response.json();
+        while (job.status === "queued" || job.status === "running") {
+            setStatus(
+                `Processing videos... ${job.processed}/${job.total}`,
+                "info",
+            );
+            await new Promise((resolve) =>
+                setTimeout(resolve, JOB_POLL_INTERVAL_MS),
+            );
+
+            const jobResponse = await fetch(
+                `${apiUrl}/api/process/jobs/${job.id}`,
+                {
+                    headers: {
+                        Authorization: `Bearer ${token}`,
+                    },
+                },
+            );
+            if (!jobResponse.ok) {
+                throw new Error(
+                    `Failed to check processing status (${jobResponse.status})`,
+                );
+            }
+            job = await jobResponse.json();
+        }
+
+        if (job.status === "failed") {
+            throw new Error(job.error || "Processing failed");
+        }
+
+        return job;
     } catch (error) {
         if (error instanceof TypeError && error.message.includes("fetch")) {
             const apiUrl = await getApiBaseUrl();
@@ -627,7 +680,7 @@ async function scanPlaylist(): Promise<void> {
         const result = await sendPlaylistToAPI(scanResponse.videos);
 
         setStatus(
-            `Processed ${result.total} videos: ${result.valid} valid, ${result.invalid} invalid`,
+            `Processed ${result.total} videos: ${result.totals.created} added, ${result.totals.duplicate} already saved, ${result.totals.invalid} invalid, ${result.totals.error} failed`,
             "success",
         );
 
@@ -721,6 +774,7 @@ async function saveCurrentUrl(): Promise<void> {
                 response.channel
             ) {
                 videoInfo = {
+                    ...response.details,
                     channel: response.channel,
                     url: parsedUrl.normalizedUrl,
                     title: response.title,
@@ -752,7 +806,9 @@ async function saveCurrentUrl(): Promise<void> {
 
         const result = await sendVideoToAPI(videoInfo);
 
-        if (result.processed.isValid) {
+        if (result.processed.status === "duplicate") {
+            setStatus("Video is already in your library", "success");
+        } else if (result.processed.isValid && result.processed.status !== "error") {
             setStatus("Video processed and saved successfully!", "success");
         } else {
             const errorMsg =
@@ -1,4 +1,13 @@
-export interface VideoInfo {
+// Optional details read off a video's page; the API validates them and fills in what a saved video is missing
+export interface VideoDetails {
+  durationSeconds?: number;
+  thumbnailUrl?: string;
+  viewCount?: number;
+  uploadDate?: string;
+  channelHandle?: string;
+}
+
+export interface VideoInfo extends VideoDetails {
   channel: string;
   url: string;
   title: string;
@@ -22,6 +31,7 @@ export interface CurrentVideoInfoResponse {
   type: 'CURRENT_VIDEO_INFO';
   title: string;
   channel: string;
+  details?: VideoDetails;
   error?: string;
 }
 
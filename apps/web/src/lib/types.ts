@@ -9,6 +9,10 @@ export interface Playlist {
 
 export interface PlaylistDetail extends Omit<Playlist, "videoCount"> {
   videos: Video[];
+  // Cursor of the next page of videos, null on the last page
+  nextCursor: string | null;
+  // Only on the first page
+  total?: number;
 }
 
 export interface Video {
@@ -18,12 +22,39 @@ export interface Video {
   originalUrl: string;
   title: string;
   channel: string;
+  channelId: number | null;
+  platform: string;
+  startSeconds: number;
   userId: string;
   createdAt: string;
+  thumbnailUrl: string | null;
+  durationSeconds: number | null;
+  publishedAt: string | null;
+  viewCount: number | null;
+  channelHandle: string | null;
+  channelExternalId: string | null;
+  metadataStatus: "pending" | "enriched" | "failed";
+  cachedThumbnails: { small: string; medium: string } | null;
+  availability: "unknown" | "available" | "unavailable";
+  availabilityReason: string | null;
+  lastCheckedAt: string | null;
+  match?: SearchMatch;
+  position?: number;
+}
+
+export interface SearchMatch {
+  rank: number;
+  // HTML-escaped, with the matching words in <mark> tags
+  title: string;
+  channel: string;
 }
 
 export interface VideosResponse {
   videos: Video[];
+  // Cursor of the next page, null on the last page
+  nextCursor: string | null;
+  // Only on the first page
+  total?: number;
 }
 
 export interface ListPlaylistsResponse {
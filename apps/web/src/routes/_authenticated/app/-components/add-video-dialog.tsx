@@ -67,39 +67,51 @@ interface AddVideoDialogProps {
   availableChannels: string[];
 }
 
-interface ProcessPlaylistResponse {
-  processed: Array<{
+interface ProcessVideoResponse {
+  processed: {
     channel: string;
     originalUrl: string;
     normalizedUrl: string;
     title: string;
+    startSeconds?: number;
     isValid: boolean;
+    status?: "created" | "duplicate" | "invalid" | "error";
+    id?: number;
     error?: string;
-  }>;
+  };
+}
+
+// Returned with 202 when the URL was a YouTube playlist, whose videos are imported in the background
+interface ProcessJobResponse {
+  id: string;
   total: number;
-  valid: number;
-  invalid: number;
+  playlist?: string;
+  // Set when the playlist may have more videos than were read
+  warning?: string;
 }
 
+type AddVideoResult =
+  | ({ kind: "video" } & ProcessVideoResponse)
+  | ({ kind: "playlist" } & ProcessJobResponse);
+
 async function addVideo(data: {
   url: string;
   channel: string;
   title: string;
-}): Promise<ProcessPlaylistResponse> {
-  const response = await fetch("/api/process/playlist", {
+}): Promise<AddVideoResult> {
+  const response = await fetch("/api/process/video", {
     method: "POST",
     headers: {
       "Content-Type": "application/json",
     },
     credentials: "include",
     body: JSON.stringify({
-      videos: [
-        {
-          url: data.url,
-          channel: data.channel,
-          title: data.title,
-        },
-      ],
+      video: {
+        url: data.url,
+        channel: data.channel,
+        title: data.title,
+      },
+      createPlaylist: true,
     }),
   });
 
@@ -110,7 +122,10 @@ async function addVideo(data: {
     throw new Error(error.message || "Failed to add video");
   }
 
-  return response.json();
+  if (response.status === 202) {
+    return { kind: "playlist", ...(await response.json()) };
+  }
+  return { kind: "video", ...(await response.json()) };
 }
 
 export function AddVideoDialog({ availableChannels }: AddVideoDialogProps) {
@@ -122,14 +137,32 @@ export function AddVideoDialog({ availableChannels }: AddVideoDialogProps) {
   const mutation = useMutation({
     mutationFn: addVideo,
     onSuccess: (data) => {
-      if (data.valid > 0) {
+      if (data.kind === "playlist") {
+        toast.success(
+          data.playlist
+            ? `Importing ${data.total} videos into "${data.playlist}"`
+            : `Importing ${data.total} videos`,
+        );
+        if (data.warning) {
+          toast.warning(data.warning);
+        }
+        queryClient.invalidateQueries({ queryKey: ["playlists"] });
+        setOpen(false);
+        form.reset();
+        setChannelSearch("");
+      } else if (data.processed.status === "duplicate") {
+        toast.info("Video is already in your library");
+        setOpen(false);
+        form.reset();
+        setChannelSearch("");
+      } else if (data.processed.isValid && data.processed.status !== "error") {
         toast.success("Video added successfully!");
         queryClient.invalidateQueries({ queryKey: ["videos"] });
         setOpen(false);
         form.reset();
         setChannelSearch("");
-      } else if (data.processed[0]?.error) {
-        toast.error(`Failed to add video: ${data.processed[0].error}`);
+      } else if (data.processed.error) {
+        toast.error(`Failed to add video: ${data.processed.error}`);
       } else {
         toast.error("Failed to add video. Please check the URL.");
       }